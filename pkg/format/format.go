@@ -14,12 +14,7 @@ import (
 )
 
 func Format(in io.Reader, out io.Writer) error {
-	a, err := parser.Parse("", in, reporter.NewHandler(reporter.NewReporter(
-		func(err reporter.ErrorWithPos) error {
-			return err
-		},
-		func(err reporter.ErrorWithPos) {},
-	)), 0)
+	a, err := Parse("", in)
 	if err != nil {
 		return err
 	}
@@ -27,6 +22,16 @@ func Format(in io.Reader, out io.Writer) error {
 	return formatter.Run()
 }
 
+// Parse parses a source file to be formatted, stopping at the first error.
+func Parse(filename string, in io.Reader) (*ast.FileNode, error) {
+	return parser.Parse(filename, in, reporter.NewHandler(reporter.NewReporter(
+		func(err reporter.ErrorWithPos) error {
+			return err
+		},
+		func(err reporter.ErrorWithPos) {},
+	)), 0)
+}
+
 func File(filename string, out io.Writer) error {
 	f, err := os.Open(filename)
 	if err != nil {
@@ -560,12 +560,21 @@ func (p *Printer) printFile(fdp protoreflect.FileDescriptor, reg *protoregistry.
 	path[0] = internal.File_packageTag
 	sourceInfo.PutIfAbsent(append(path, 0), sourceInfo.Get(path))
 
-	path[0] = internal.File_syntaxTag
-	si := sourceInfo.Get(path)
-	p.printElement(false, si, w, 0, func(w *writer) {
-		syn := fdp.Syntax()
-		_, _ = fmt.Fprintf(w, "syntax = %q;", syn)
-	})
+	if fdp.Syntax() == protoreflect.Editions {
+		path[0] = internal.File_editionTag
+		si := sourceInfo.Get(path)
+		p.printElement(false, si, w, 0, func(w *writer) {
+			edition, _ := FileEdition(fdp)
+			_, _ = fmt.Fprintf(w, "edition = %q;", strings.TrimPrefix(edition.String(), "EDITION_"))
+		})
+	} else {
+		path[0] = internal.File_syntaxTag
+		si := sourceInfo.Get(path)
+		p.printElement(false, si, w, 0, func(w *writer) {
+			syn := fdp.Syntax()
+			_, _ = fmt.Fprintf(w, "syntax = %q;", syn)
+		})
+	}
 	p.newLine(w, CompactTopLevelDeclarations)
 
 	skip := map[interface{}]bool{}
@@ -1150,7 +1159,24 @@ func (p *Printer) printField(fld protoreflect.FieldDescriptor, reg *protoregistr
 	})
 }
 
+// FileEdition returns the edition of a file which uses editions syntax, or
+// false if the file uses proto2 or proto3 syntax.
+func FileEdition(fd protoreflect.FileDescriptor) (descriptorpb.Edition, bool) {
+	if fd == nil || fd.Syntax() != protoreflect.Editions {
+		return 0, false
+	}
+	if ef, ok := fd.(interface{ Edition() int32 }); ok {
+		return descriptorpb.Edition(ef.Edition()), true
+	}
+	return protodesc.ToFileDescriptorProto(fd).GetEdition(), true
+}
+
 func shouldEmitLabel(fld protoreflect.FieldDescriptor) bool {
+	if fld.Syntax() == protoreflect.Editions {
+		// field presence is controlled by features in editions; only the
+		// repeated label is allowed
+		return fld.Cardinality() == protoreflect.Repeated && !fld.IsMap()
+	}
 	return fld.HasOptionalKeyword() ||
 		(!fld.IsMap() && fld.ContainingOneof() == nil &&
 			(fld.Cardinality() != protoreflect.Optional || fld.Syntax() != protoreflect.Proto3))
@@ -0,0 +1,299 @@
+package lsp
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// BazelWorkspace describes the proto_library rules of a Bazel workspace, and
+// the external repositories fetched into its output base.
+type BazelWorkspace struct {
+	Root        string // absolute path of the workspace root
+	ExternalDir string // absolute path of bazel-<workspace>/external, if present
+	Rules       []BazelProtoLibrary
+
+	importPaths map[string]string // filename -> import path
+	filenames   map[string]string // import path -> filename
+}
+
+// BazelProtoLibrary is a proto_library rule declared in a BUILD file.
+type BazelProtoLibrary struct {
+	Package           string   // workspace-relative package path, e.g. "foo/bar"
+	Name              string   // name of the rule
+	Srcs              []string // absolute paths of the rule's sources
+	StripImportPrefix string
+	ImportPrefix      string
+}
+
+var (
+	bazelWorkspaceFiles = []string{"MODULE.bazel", "WORKSPACE.bazel", "WORKSPACE"}
+	bazelBuildFiles     = []string{"BUILD.bazel", "BUILD"}
+)
+
+// LoadBazelWorkspace reads the proto_library rules of the Bazel workspace
+// rooted at dir. If dir is not the root of a Bazel workspace, or the
+// workspace contains neither proto_library rules nor external repositories,
+// a nil workspace is returned.
+func LoadBazelWorkspace(dir string) (*BazelWorkspace, error) {
+	return loadBazelWorkspace(dir, newWorkspaceFiles(dir))
+}
+
+func loadBazelWorkspace(dir string, files *workspaceFiles) (*BazelWorkspace, error) {
+	if !slices.ContainsFunc(bazelWorkspaceFiles, func(name string) bool {
+		return isRegularFile(filepath.Join(dir, name))
+	}) {
+		return nil, nil
+	}
+	w := &BazelWorkspace{
+		Root:        dir,
+		ExternalDir: findBazelExternalDir(dir),
+		importPaths: map[string]string{},
+		filenames:   map[string]string{},
+	}
+	buildFiles, err := files.BuildFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, filename := range buildFiles {
+		// BUILD.bazel takes precedence over BUILD if both are present
+		if filepath.Base(filename) == "BUILD" && isRegularFile(filepath.Join(filepath.Dir(filename), "BUILD.bazel")) {
+			continue
+		}
+		if err := w.loadBuildFile(filename); err != nil {
+			slog.With("path", filename, "error", err).Warn("failed to load BUILD file")
+		}
+	}
+	if len(w.Rules) == 0 && w.ExternalDir == "" {
+		return nil, nil
+	}
+	return w, nil
+}
+
+func skipBazelSearchDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "bazel-") || strings.HasPrefix(name, "_bazel_") || name == "node_modules"
+}
+
+// findBazelExternalDir returns the directory containing the external
+// repositories of the workspace, found through the bazel-<workspace>
+// convenience symlink created by Bazel in the workspace root.
+func findBazelExternalDir(dir string) string {
+	candidates := []string{filepath.Join(dir, "bazel-"+filepath.Base(dir))}
+	matches, _ := filepath.Glob(filepath.Join(dir, "bazel-*"))
+	for _, match := range matches {
+		switch filepath.Base(match) {
+		case "bazel-bin", "bazel-out", "bazel-testlogs":
+			continue
+		}
+		candidates = append(candidates, match)
+	}
+	for _, candidate := range candidates {
+		external := filepath.Join(candidate, "external")
+		if info, err := os.Stat(external); err == nil && info.IsDir() {
+			return external
+		}
+	}
+	return ""
+}
+
+func (w *BazelWorkspace) loadBuildFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	pkgDir := filepath.Dir(filename)
+	rel, err := filepath.Rel(w.Root, pkgDir)
+	if err != nil {
+		return err
+	}
+	pkg := filepath.ToSlash(rel)
+	if pkg == "." {
+		pkg = ""
+	}
+	calls, err := parseStarlarkCalls(data, "proto_library", func(include, exclude []string) []string {
+		return bazelGlob(pkgDir, include, exclude)
+	})
+	if err != nil {
+		return err
+	}
+	for _, call := range calls {
+		rule := BazelProtoLibrary{
+			Package:           pkg,
+			Name:              call.stringAttr("name"),
+			StripImportPrefix: call.stringAttrDefault("strip_import_prefix", "/"),
+			ImportPrefix:      call.stringAttr("import_prefix"),
+		}
+		for _, src := range call.listAttr("srcs") {
+			src, ok := bazelSourceLabel(pkg, src)
+			if !ok {
+				continue
+			}
+			importPath, ok := bazelImportPath(pkg, src, rule.StripImportPrefix, rule.ImportPrefix)
+			if !ok {
+				slog.With("rule", "//"+pkg+":"+rule.Name, "src", src).Warn("source is not under strip_import_prefix")
+				continue
+			}
+			srcFilename := filepath.Join(pkgDir, filepath.FromSlash(src))
+			rule.Srcs = append(rule.Srcs, srcFilename)
+			w.importPaths[srcFilename] = importPath
+			if _, ok := w.filenames[importPath]; !ok {
+				w.filenames[importPath] = srcFilename
+			}
+		}
+		w.Rules = append(w.Rules, rule)
+	}
+	return nil
+}
+
+// bazelSourceLabel returns the package-relative path of a .proto file given
+// in the srcs of a rule in the given package. Labels referring to files in
+// other packages or to other rules are not supported.
+func bazelSourceLabel(pkg, label string) (string, bool) {
+	if rest, ok := strings.CutPrefix(label, "//"); ok {
+		labelPkg, name, ok := strings.Cut(rest, ":")
+		if !ok || labelPkg != pkg {
+			return "", false
+		}
+		label = name
+	} else if strings.HasPrefix(label, "@") {
+		return "", false
+	}
+	label = strings.TrimPrefix(label, ":")
+	if !strings.HasSuffix(label, ".proto") || !filepath.IsLocal(filepath.FromSlash(label)) {
+		return "", false
+	}
+	return label, true
+}
+
+// bazelImportPath returns the import path of a source of a proto_library rule,
+// given its package-relative path and the rule's strip_import_prefix and
+// import_prefix attributes. A strip_import_prefix starting with a slash is
+// relative to the workspace root; otherwise, it is relative to the package.
+// The default strip_import_prefix of "/" leaves the workspace-relative path
+// unchanged.
+func bazelImportPath(pkg, src, stripImportPrefix, importPrefix string) (string, bool) {
+	importPath := path.Join(pkg, src)
+	var prefix string
+	if strings.HasPrefix(stripImportPrefix, "/") {
+		prefix = strings.Trim(stripImportPrefix, "/")
+	} else {
+		prefix = path.Join(pkg, stripImportPrefix)
+	}
+	if prefix != "" {
+		rel, ok := strings.CutPrefix(importPath, prefix+"/")
+		if !ok {
+			return "", false
+		}
+		importPath = rel
+	}
+	if importPrefix != "" {
+		importPath = path.Join(strings.Trim(importPrefix, "/"), importPath)
+	}
+	return importPath, true
+}
+
+// bazelGlob returns the package-relative paths of the files in pkgDir matching
+// any of the include patterns and none of the exclude patterns. As in Bazel,
+// files in subpackages are not matched.
+func bazelGlob(pkgDir string, include, exclude []string) []string {
+	var matches []string
+	filepath.WalkDir(pkgDir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if filename == pkgDir {
+				return nil
+			}
+			if skipBazelSearchDir(d.Name()) || slices.ContainsFunc(bazelBuildFiles, func(name string) bool {
+				return isRegularFile(filepath.Join(filename, name))
+			}) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(pkgDir, filename)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		matchesAny := func(patterns []string) bool {
+			return slices.ContainsFunc(patterns, func(pattern string) bool {
+				return matchBazelGlob(pattern, rel)
+			})
+		}
+		if matchesAny(include) && !matchesAny(exclude) {
+			matches = append(matches, rel)
+		}
+		return nil
+	})
+	return matches
+}
+
+// matchBazelGlob reports whether name matches the glob pattern, where "**"
+// matches any number of path segments.
+func matchBazelGlob(pattern, name string) bool {
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchGlobSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// ImportPath returns the import path of the given file, if it is a source of
+// a proto_library rule.
+func (w *BazelWorkspace) ImportPath(filename string) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	importPath, ok := w.importPaths[filename]
+	return importPath, ok
+}
+
+// FindFile returns the filename of the given import path, which is either a
+// source of a proto_library rule in the workspace, or a file at the same
+// path relative to the root of one of the external repositories. The second
+// return value reports whether the file was found in an external repository.
+func (w *BazelWorkspace) FindFile(importPath string) (filename string, isExternal bool, ok bool) {
+	if w == nil || !filepath.IsLocal(filepath.FromSlash(importPath)) {
+		return "", false, false
+	}
+	if filename, ok := w.filenames[importPath]; ok {
+		return filename, false, true
+	}
+	if w.ExternalDir == "" {
+		return "", false, false
+	}
+	repos, err := os.ReadDir(w.ExternalDir)
+	if err != nil {
+		return "", false, false
+	}
+	for _, repo := range repos {
+		candidate := filepath.Join(w.ExternalDir, repo.Name(), filepath.FromSlash(importPath))
+		if isRegularFile(candidate) {
+			return candidate, true, true
+		}
+	}
+	return "", false, false
+}
@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBazelWorkspace(t *testing.T) {
+	t.Run("proto_library", func(t *testing.T) {
+		dir := t.TempDir()
+		outputBase := t.TempDir()
+		writeFiles(t, outputBase, map[string]string{
+			"external/com_google_googleapis/google/api/annotations.proto": `syntax = "proto3";`,
+		})
+		require.NoError(t, os.Symlink(outputBase, filepath.Join(dir, "bazel-"+filepath.Base(dir))))
+		writeFiles(t, dir, map[string]string{
+			"MODULE.bazel": `module(name = "example")`,
+			"api/BUILD.bazel": `
+load("@rules_proto//proto:defs.bzl", "proto_library")
+
+proto_library(
+    name = "foo_proto",
+    srcs = ["foo/v1/foo.proto"],
+    strip_import_prefix = "/api",
+    visibility = ["//visibility:public"],
+)
+
+proto_library(
+    name = "bar_proto",
+    srcs = [":bar.proto"],
+    import_prefix = "example.com/bar",
+    deps = [":foo_proto"],
+)
+`,
+			"api/BUILD":            `proto_library(name = "ignored", srcs = ["ignored.proto"])`,
+			"api/foo/v1/foo.proto": `syntax = "proto3";`,
+			"api/bar.proto":        `syntax = "proto3";`,
+			"api/ignored.proto":    `syntax = "proto3";`,
+			"lib/BUILD": `
+# sources are listed in a variable
+SRCS = [
+    "a.proto",
+] + glob(["sub/**/*.proto"], exclude = ["sub/skip.proto"])
+
+def _macro(name):
+    native.proto_library(name = name, srcs = ["macro.proto"])
+
+proto_library(
+    name = 'lib_proto',
+    srcs = SRCS + select({"//conditions:default": []}),
+)
+
+proto_library(
+    name = "lib2_proto",
+    srcs = SRCS,
+    strip_import_prefix = "sub",
+    import_prefix = "x",
+)
+`,
+			"lib/a.proto":               `syntax = "proto3";`,
+			"lib/sub/b.proto":           `syntax = "proto3";`,
+			"lib/sub/deep/c.proto":      `syntax = "proto3";`,
+			"lib/sub/skip.proto":        `syntax = "proto3";`,
+			"lib/sub/pkg/BUILD":         ``,
+			"lib/sub/pkg/d.proto":       `syntax = "proto3";`,
+			"unrelated/unrelated.proto": `syntax = "proto3";`,
+		})
+
+		w, err := LoadBazelWorkspace(dir)
+		require.NoError(t, err)
+		require.NotNil(t, w)
+		require.Equal(t, filepath.Join(dir, "bazel-"+filepath.Base(dir), "external"), w.ExternalDir)
+		require.Len(t, w.Rules, 4)
+		require.Equal(t, BazelProtoLibrary{
+			Package:           "api",
+			Name:              "foo_proto",
+			Srcs:              []string{filepath.Join(dir, "api/foo/v1/foo.proto")},
+			StripImportPrefix: "/api",
+		}, w.Rules[0])
+
+		for filename, want := range map[string]string{
+			"api/foo/v1/foo.proto": "foo/v1/foo.proto",
+			"api/bar.proto":        "example.com/bar/api/bar.proto",
+			"lib/sub/b.proto":      "x/b.proto",
+			"lib/sub/deep/c.proto": "x/deep/c.proto",
+		} {
+			path, ok := w.ImportPath(filepath.Join(dir, filename))
+			require.True(t, ok, filename)
+			require.Equal(t, want, path, filename)
+
+			found, isExternal, ok := w.FindFile(want)
+			require.True(t, ok, want)
+			require.False(t, isExternal)
+			require.Equal(t, filepath.Join(dir, filename), found)
+		}
+		// a.proto is not under the stripped prefix in lib2_proto, so it keeps
+		// the import path from lib_proto
+		path, ok := w.ImportPath(filepath.Join(dir, "lib/a.proto"))
+		require.True(t, ok)
+		require.Equal(t, "lib/a.proto", path)
+
+		for _, filename := range []string{
+			"api/ignored.proto",
+			"lib/sub/skip.proto",
+			"lib/sub/pkg/d.proto",
+			"lib/macro.proto",
+			"unrelated/unrelated.proto",
+		} {
+			_, ok := w.ImportPath(filepath.Join(dir, filename))
+			require.False(t, ok, filename)
+		}
+
+		found, isExternal, ok := w.FindFile("google/api/annotations.proto")
+		require.True(t, ok)
+		require.True(t, isExternal)
+		require.Equal(t, filepath.Join(w.ExternalDir, "com_google_googleapis/google/api/annotations.proto"), found)
+
+		_, _, ok = w.FindFile("../api/bar.proto")
+		require.False(t, ok)
+	})
+
+	t.Run("not a workspace", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"BUILD": `proto_library(name = "foo_proto", srcs = ["foo.proto"])`,
+		})
+		w, err := LoadBazelWorkspace(dir)
+		require.NoError(t, err)
+		require.Nil(t, w)
+		_, ok := w.ImportPath(filepath.Join(dir, "foo.proto"))
+		require.False(t, ok)
+	})
+}
+
+func TestBazelImportPath(t *testing.T) {
+	for _, tc := range []struct {
+		pkg, src, strip, prefix string
+		want                    string
+		ok                      bool
+	}{
+		{pkg: "a/b", src: "c.proto", strip: "/", want: "a/b/c.proto", ok: true},
+		{pkg: "", src: "c.proto", strip: "/", want: "c.proto", ok: true},
+		{pkg: "a/b", src: "c.proto", strip: "", want: "c.proto", ok: true},
+		{pkg: "a/b", src: "c.proto", strip: "/a", want: "b/c.proto", ok: true},
+		{pkg: "a/b", src: "x/c.proto", strip: "x", want: "c.proto", ok: true},
+		{pkg: "a/b", src: "c.proto", strip: "/", prefix: "p/q", want: "p/q/a/b/c.proto", ok: true},
+		{pkg: "a/b", src: "x/c.proto", strip: "/a/b/x", prefix: "/p/", want: "p/c.proto", ok: true},
+		{pkg: "a/b", src: "c.proto", strip: "/z", ok: false},
+	} {
+		got, ok := bazelImportPath(tc.pkg, tc.src, tc.strip, tc.prefix)
+		require.Equal(t, tc.ok, ok, tc)
+		require.Equal(t, tc.want, got, tc)
+	}
+}
+
+func TestParseStarlarkCalls(t *testing.T) {
+	calls, err := parseStarlarkCalls([]byte(`
+X = "x"
+X += 'y'
+L = ["a", r"b\n", """c"""]
+other_rule(name = "skipped", srcs = ["skip"])
+rule(
+    "positional",
+    name = X + "z",  # comment
+    srcs = L + ["d"],
+    bad = [x for x in L],
+    num = 1,
+    nested = {"k": ["v"]},
+)
+rule(name = "\"quoted\"")
+`), "rule", nil)
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	require.Equal(t, "xyz", calls[0].stringAttr("name"))
+	require.Equal(t, []string{"a", `b\n`, "c", "d"}, calls[0].listAttr("srcs"))
+	require.Nil(t, calls[0].listAttr("bad"))
+	require.Empty(t, calls[0].stringAttr("num"))
+	require.Nil(t, calls[0].listAttr("nested"))
+	require.Equal(t, []starlarkValue{{valid: true, str: "positional"}}, calls[0].args)
+	require.Equal(t, `"quoted"`, calls[1].stringAttr("name"))
+
+	_, err = parseStarlarkCalls([]byte(`rule(name = "unterminated)`), "rule", nil)
+	require.Error(t, err)
+}
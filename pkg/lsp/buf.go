@@ -0,0 +1,347 @@
+package lsp
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BufWorkspace describes the modules and dependencies of a workspace using
+// the buf layout, as configured by buf.work.yaml and buf.yaml files.
+type BufWorkspace struct {
+	Modules []BufModule
+	Deps    []BufDependency
+}
+
+// BufModule is a directory containing proto sources. Import paths of files
+// within the module are relative to its root.
+type BufModule struct {
+	Root     string   // absolute path of the module root
+	Excludes []string // absolute paths of directories excluded from the module
+}
+
+// BufDependency is a module listed in the deps of a buf.yaml file.
+type BufDependency struct {
+	Name   string // e.g. buf.build/bufbuild/protovalidate
+	Commit string // commit from buf.lock, if known
+	Dir    string // directory containing the dependency's sources in the module cache
+}
+
+type bufWorkYaml struct {
+	Version     string   `yaml:"version"`
+	Directories []string `yaml:"directories"`
+}
+
+type bufYaml struct {
+	Version string   `yaml:"version"`
+	Deps    []string `yaml:"deps"`
+	// v1 and v1beta1
+	Build struct {
+		Roots    []string `yaml:"roots"`
+		Excludes []string `yaml:"excludes"`
+	} `yaml:"build"`
+	// v2
+	Modules []struct {
+		Path     string   `yaml:"path"`
+		Excludes []string `yaml:"excludes"`
+	} `yaml:"modules"`
+}
+
+type bufLockYaml struct {
+	Version string `yaml:"version"`
+	Deps    []struct {
+		// v2
+		Name string `yaml:"name"`
+		// v1
+		Remote     string `yaml:"remote"`
+		Owner      string `yaml:"owner"`
+		Repository string `yaml:"repository"`
+
+		Commit string `yaml:"commit"`
+	} `yaml:"deps"`
+}
+
+// LoadBufWorkspace reads the buf configuration for the workspace rooted at
+// dir. A buf.work.yaml or buf.yaml in dir takes precedence; otherwise, any
+// buf.yaml files found in subdirectories are used. If no configuration is
+// found, a nil workspace is returned.
+func LoadBufWorkspace(dir string) (*BufWorkspace, error) {
+	return loadBufWorkspace(dir, newWorkspaceFiles(dir))
+}
+
+func loadBufWorkspace(dir string, files *workspaceFiles) (*BufWorkspace, error) {
+	w := &BufWorkspace{}
+	if data, err := os.ReadFile(filepath.Join(dir, "buf.work.yaml")); err == nil {
+		var work bufWorkYaml
+		if err := yaml.Unmarshal(data, &work); err != nil {
+			return nil, err
+		}
+		for _, moduleDir := range work.Directories {
+			if err := w.loadBufYaml(filepath.Join(dir, filepath.FromSlash(moduleDir)), true); err != nil {
+				return nil, err
+			}
+		}
+	} else if _, err := os.Stat(filepath.Join(dir, "buf.yaml")); err == nil {
+		if err := w.loadBufYaml(dir, false); err != nil {
+			return nil, err
+		}
+	} else {
+		moduleDirs, err := files.BufYamlDirs()
+		if err != nil {
+			return nil, err
+		}
+		for _, moduleDir := range moduleDirs {
+			if err := w.loadBufYaml(moduleDir, false); err != nil {
+				slog.With("path", filepath.Join(moduleDir, "buf.yaml"), "error", err).Warn("failed to load buf.yaml")
+			}
+		}
+	}
+	if len(w.Modules) == 0 {
+		return nil, nil
+	}
+	// sort modules so that nested module roots are matched first
+	slices.SortStableFunc(w.Modules, func(a, b BufModule) int {
+		return len(b.Root) - len(a.Root)
+	})
+	return w, nil
+}
+
+func skipBufSearchDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || strings.HasPrefix(name, "_bazel_")
+}
+
+// loadBufYaml reads the buf.yaml (and buf.lock) in dir. If implicit is true,
+// dir is treated as a module root even if it does not contain a buf.yaml.
+func (w *BufWorkspace) loadBufYaml(dir string, implicit bool) error {
+	data, err := os.ReadFile(filepath.Join(dir, "buf.yaml"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && implicit {
+			w.Modules = append(w.Modules, BufModule{Root: dir})
+			return nil
+		}
+		return err
+	}
+	var config bufYaml
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	switch config.Version {
+	case "v2":
+		if len(config.Modules) == 0 {
+			w.Modules = append(w.Modules, BufModule{Root: dir})
+		}
+		for _, m := range config.Modules {
+			// excludes are relative to the buf.yaml in v2
+			w.Modules = append(w.Modules, BufModule{
+				Root:     filepath.Join(dir, filepath.FromSlash(m.Path)),
+				Excludes: joinAll(dir, m.Excludes),
+			})
+		}
+	default: // v1, v1beta1
+		if len(config.Build.Roots) == 0 {
+			w.Modules = append(w.Modules, BufModule{
+				Root:     dir,
+				Excludes: joinAll(dir, config.Build.Excludes),
+			})
+		}
+		for _, root := range config.Build.Roots {
+			// excludes are relative to the roots in v1beta1
+			root = filepath.Join(dir, filepath.FromSlash(root))
+			w.Modules = append(w.Modules, BufModule{
+				Root:     root,
+				Excludes: joinAll(root, config.Build.Excludes),
+			})
+		}
+	}
+
+	commits := readBufLock(filepath.Join(dir, "buf.lock"))
+	for _, dep := range config.Deps {
+		name, _, _ := strings.Cut(dep, ":")
+		if slices.ContainsFunc(w.Deps, func(d BufDependency) bool { return d.Name == name }) {
+			continue
+		}
+		d := BufDependency{
+			Name:   name,
+			Commit: commits[name],
+		}
+		d.Dir = findBufModuleCacheDir(bufCacheDir(), d)
+		w.Deps = append(w.Deps, d)
+	}
+	return nil
+}
+
+func joinAll(dir string, paths []string) []string {
+	joined := make([]string, len(paths))
+	for i, p := range paths {
+		joined[i] = filepath.Join(dir, filepath.FromSlash(p))
+	}
+	return joined
+}
+
+// readBufLock returns the locked commit of each dependency in the buf.lock
+// file, keyed by module name.
+func readBufLock(filename string) map[string]string {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	var lock bufLockYaml
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		slog.With("path", filename, "error", err).Warn("failed to read buf.lock")
+		return nil
+	}
+	commits := map[string]string{}
+	for _, dep := range lock.Deps {
+		name := dep.Name
+		if name == "" {
+			name = path.Join(dep.Remote, dep.Owner, dep.Repository)
+		}
+		commits[name] = dep.Commit
+	}
+	return commits
+}
+
+// bufCacheDir returns the buf cache directory, which is $BUF_CACHE_DIR if set,
+// or the "buf" directory within the user cache directory.
+func bufCacheDir() string {
+	if dir, ok := os.LookupEnv("BUF_CACHE_DIR"); ok {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "buf")
+}
+
+// findBufModuleCacheDir returns the directory containing the sources of the
+// dependency in the local buf module cache, or an empty string if it has not
+// been downloaded. The following cache layouts are supported:
+//
+//	v3/modules/<digest type>/<remote>/<owner>/<repository>/<commit>/files/
+//	v1/module/data/<remote>/<owner>/<repository>/<commit>/
+//
+// If the dependency's commit is not known, the most recently modified commit
+// directory is used.
+func findBufModuleCacheDir(cacheDir string, dep BufDependency) string {
+	if cacheDir == "" {
+		return ""
+	}
+	name := filepath.FromSlash(dep.Name)
+	commit := dep.Commit
+	if commit == "" {
+		commit = "*"
+	}
+	patterns := []string{
+		filepath.Join(cacheDir, "v3", "modules", "*", name, commit, "files"),
+		filepath.Join(cacheDir, "v1", "module", "data", name, commit),
+	}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		var newest string
+		var newestInfo fs.FileInfo
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || !info.IsDir() {
+				continue
+			}
+			if newestInfo == nil || info.ModTime().After(newestInfo.ModTime()) {
+				newest, newestInfo = match, info
+			}
+		}
+		if newest != "" {
+			return newest
+		}
+	}
+	return ""
+}
+
+// ModuleRelativePath returns the import path of the given file relative to
+// the root of the module containing it. If the file is not in any module, or
+// is excluded from its module, false is returned.
+func (w *BufWorkspace) ModuleRelativePath(filename string) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	for _, m := range w.Modules {
+		if !isWithinDir(filename, m.Root) {
+			continue
+		}
+		if m.isExcluded(filename) {
+			return "", false
+		}
+		rel, err := filepath.Rel(m.Root, filename)
+		if err != nil {
+			return "", false
+		}
+		return filepath.ToSlash(rel), true
+	}
+	return "", false
+}
+
+// IsExcluded reports whether the given file is within a directory excluded
+// from the module containing it.
+func (w *BufWorkspace) IsExcluded(filename string) bool {
+	if w == nil {
+		return false
+	}
+	for _, m := range w.Modules {
+		if isWithinDir(filename, m.Root) {
+			return m.isExcluded(filename)
+		}
+	}
+	return false
+}
+
+func (m BufModule) isExcluded(filename string) bool {
+	for _, exclude := range m.Excludes {
+		if isWithinDir(filename, exclude) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindFile returns the filename of the given import path within one of the
+// workspace modules or dependencies, and whether it was found in a dependency.
+func (w *BufWorkspace) FindFile(importPath string) (filename string, isDep bool, ok bool) {
+	if w == nil || !filepath.IsLocal(filepath.FromSlash(importPath)) {
+		return "", false, false
+	}
+	for _, m := range w.Modules {
+		candidate := filepath.Join(m.Root, filepath.FromSlash(importPath))
+		if m.isExcluded(candidate) {
+			continue
+		}
+		if isRegularFile(candidate) {
+			return candidate, false, true
+		}
+	}
+	for _, dep := range w.Deps {
+		if dep.Dir == "" {
+			continue
+		}
+		candidate := filepath.Join(dep.Dir, filepath.FromSlash(importPath))
+		if isRegularFile(candidate) {
+			return candidate, true, true
+		}
+	}
+	return "", false, false
+}
+
+func isWithinDir(filename, dir string) bool {
+	rel, err := filepath.Rel(dir, filename)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func isRegularFile(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && info.Mode().IsRegular()
+}
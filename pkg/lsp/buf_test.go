@@ -0,0 +1,161 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, contents := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0o755))
+		require.NoError(t, os.WriteFile(filename, []byte(contents), 0o644))
+	}
+}
+
+func TestLoadBufWorkspace(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("BUF_CACHE_DIR", cacheDir)
+	writeFiles(t, cacheDir, map[string]string{
+		"v3/modules/b5/buf.build/bufbuild/protovalidate/0123456789abcdef/files/buf/validate/validate.proto": `syntax = "proto3";`,
+		"v1/module/data/buf.build/googleapis/googleapis/fedcba9876543210/google/api/annotations.proto":      `syntax = "proto3";`,
+	})
+
+	t.Run("v2", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"buf.yaml": `
+version: v2
+modules:
+  - path: proto
+    excludes:
+      - proto/legacy
+deps:
+  - buf.build/bufbuild/protovalidate
+`,
+			"buf.lock": `
+version: v2
+deps:
+  - name: buf.build/bufbuild/protovalidate
+    commit: 0123456789abcdef
+    digest: b5:0000
+`,
+			"proto/foo/v1/foo.proto":    `syntax = "proto3";`,
+			"proto/legacy/legacy.proto": `syntax = "proto3";`,
+		})
+		w, err := LoadBufWorkspace(dir)
+		require.NoError(t, err)
+		require.NotNil(t, w)
+		require.Equal(t, []BufModule{{
+			Root:     filepath.Join(dir, "proto"),
+			Excludes: []string{filepath.Join(dir, "proto", "legacy")},
+		}}, w.Modules)
+		require.Equal(t, []BufDependency{{
+			Name:   "buf.build/bufbuild/protovalidate",
+			Commit: "0123456789abcdef",
+			Dir:    filepath.Join(cacheDir, "v3/modules/b5/buf.build/bufbuild/protovalidate/0123456789abcdef/files"),
+		}}, w.Deps)
+
+		path, ok := w.ModuleRelativePath(filepath.Join(dir, "proto/foo/v1/foo.proto"))
+		require.True(t, ok)
+		require.Equal(t, "foo/v1/foo.proto", path)
+
+		_, ok = w.ModuleRelativePath(filepath.Join(dir, "proto/legacy/legacy.proto"))
+		require.False(t, ok)
+		require.True(t, w.IsExcluded(filepath.Join(dir, "proto/legacy/legacy.proto")))
+
+		filename, isDep, ok := w.FindFile("foo/v1/foo.proto")
+		require.True(t, ok)
+		require.False(t, isDep)
+		require.Equal(t, filepath.Join(dir, "proto/foo/v1/foo.proto"), filename)
+
+		_, _, ok = w.FindFile("legacy/legacy.proto")
+		require.False(t, ok)
+
+		filename, isDep, ok = w.FindFile("buf/validate/validate.proto")
+		require.True(t, ok)
+		require.True(t, isDep)
+		require.Equal(t, filepath.Join(w.Deps[0].Dir, "buf/validate/validate.proto"), filename)
+	})
+
+	t.Run("v1 workspace", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"buf.work.yaml": `
+version: v1
+directories:
+  - api
+  - vendor/protos
+`,
+			"api/buf.yaml": `
+version: v1
+deps:
+  - buf.build/googleapis/googleapis
+build:
+  excludes:
+    - internal
+`,
+			"api/foo/foo.proto":              `syntax = "proto3";`,
+			"api/internal/internal.proto":    `syntax = "proto3";`,
+			"vendor/protos/bar/bar.proto":    `syntax = "proto3";`,
+			"unrelated/unrelated.proto":      `syntax = "proto3";`,
+			"vendor/protos/nested/buf.yaml":  `version: v1`,
+			"vendor/protos/nested/baz.proto": `syntax = "proto3";`,
+		})
+		w, err := LoadBufWorkspace(dir)
+		require.NoError(t, err)
+		require.NotNil(t, w)
+		require.Len(t, w.Modules, 2)
+		require.Equal(t, []BufDependency{{
+			Name: "buf.build/googleapis/googleapis",
+			Dir:  filepath.Join(cacheDir, "v1/module/data/buf.build/googleapis/googleapis/fedcba9876543210"),
+		}}, w.Deps)
+
+		for filename, want := range map[string]string{
+			"api/foo/foo.proto":              "foo/foo.proto",
+			"vendor/protos/bar/bar.proto":    "bar/bar.proto",
+			"vendor/protos/nested/baz.proto": "nested/baz.proto",
+		} {
+			path, ok := w.ModuleRelativePath(filepath.Join(dir, filename))
+			require.True(t, ok, filename)
+			require.Equal(t, want, path)
+		}
+		for _, filename := range []string{
+			"api/internal/internal.proto",
+			"unrelated/unrelated.proto",
+		} {
+			_, ok := w.ModuleRelativePath(filepath.Join(dir, filename))
+			require.False(t, ok, filename)
+		}
+
+		_, isDep, ok := w.FindFile("google/api/annotations.proto")
+		require.True(t, ok)
+		require.True(t, isDep)
+	})
+
+	t.Run("nested buf.yaml", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"proto/buf.yaml":         `version: v1`,
+			"proto/foo/foo.proto":    `syntax = "proto3";`,
+			"node_modules/buf.yaml":  `version: v1`,
+			".git/buf.yaml":          `version: v1`,
+			"other/other/other.yaml": ``,
+		})
+		w, err := LoadBufWorkspace(dir)
+		require.NoError(t, err)
+		require.Equal(t, []BufModule{{Root: filepath.Join(dir, "proto"), Excludes: []string{}}}, w.Modules)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		w, err := LoadBufWorkspace(t.TempDir())
+		require.NoError(t, err)
+		require.Nil(t, w)
+		_, ok := w.ModuleRelativePath("/foo/bar.proto")
+		require.False(t, ok)
+	})
+}
@@ -40,15 +40,19 @@ type Cache struct {
 	partialResultsMu       sync.Mutex
 	unlinkedResults        map[protocompile.ResolvedPath]parser.Result
 	partiallyLinkedResults map[protocompile.ResolvedPath]linker.Result
+	compiledPaths          map[protocompile.ResolvedPath]struct{} // paths compiled since the last call to compileLocked
 
 	inflightTasksInvalidate gsync.Map[protocompile.ResolvedPath, time.Time]
 	inflightTasksCompile    gsync.Map[protocompile.ResolvedPath, time.Time]
 	pragmas                 gsync.Map[protocompile.ResolvedPath, *pragmaMap]
+	semanticTokens          semanticTokensStore
 
 	documentVersions *documentVersionQueue
 }
 
-type CacheOptions struct{}
+type CacheOptions struct {
+	settings Settings
+}
 
 type CacheOption func(*CacheOptions)
 
@@ -58,6 +62,15 @@ func (o *CacheOptions) apply(opts ...CacheOption) {
 	}
 }
 
+// WithSettings sets the initial settings for the cache. Settings which affect
+// how files are resolved, such as include paths, must be provided here or set
+// with DidChangeConfiguration before any files are loaded.
+func WithSettings(settings Settings) CacheOption {
+	return func(o *CacheOptions) {
+		o.settings = settings
+	}
+}
+
 func NewCache(workspace protocol.WorkspaceFolder, opts ...CacheOption) *Cache {
 	options := CacheOptions{}
 	options.apply(opts...)
@@ -87,9 +100,10 @@ func NewCache(workspace protocol.WorkspaceFolder, opts ...CacheOption) *Cache {
 		diagHandler:            diagHandler,
 		unlinkedResults:        make(map[protocompile.ResolvedPath]parser.Result),
 		partiallyLinkedResults: make(map[protocompile.ResolvedPath]linker.Result),
+		compiledPaths:          make(map[protocompile.ResolvedPath]struct{}),
 		documentVersions:       newDocumentVersionQueue(),
 	}
-	cache.DidChangeConfiguration(context.TODO(), Settings{}) // load default settings
+	cache.DidChangeConfiguration(context.TODO(), options.settings)
 
 	compiler.Hooks = protocompile.CompilerHooks{
 		PreInvalidate:  cache.preInvalidateHook,
@@ -325,6 +339,7 @@ func (c *Cache) FindDefinitionForTypeDescriptor(desc protoreflect.Descriptor) (p
 func (c *Cache) DidChangeConfiguration(ctx context.Context, settings Settings) error {
 	slog.Info("Configuration updated", "settings", settings)
 	c.settings.Store(&settings)
+	c.resolver.SetIncludePaths(settings.IncludePaths)
 	return nil
 }
 
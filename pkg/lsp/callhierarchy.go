@@ -0,0 +1,203 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goast "go/ast"
+	"path/filepath"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// The call hierarchy is rooted at RPC methods. Outgoing calls of a method are
+// its request and response message types, and incoming calls are the Go call
+// sites of the method on the generated client.
+//
+// As in gopls, the range of each call hierarchy item is the range of the
+// declaration's name, not the entire declaration.
+
+func (c *Cache) PrepareCallHierarchy(params protocol.TextDocumentPositionParams) ([]protocol.CallHierarchyItem, error) {
+	desc, _, err := c.FindTypeDescriptorAtLocation(params)
+	if err != nil {
+		return nil, err
+	}
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, nil
+	}
+	c.resultsMu.RLock()
+	defer c.resultsMu.RUnlock()
+	item, err := c.toCallHierarchyItemLocked(method)
+	if err != nil {
+		return nil, err
+	}
+	return []protocol.CallHierarchyItem{item}, nil
+}
+
+func (c *Cache) FindOutgoingCalls(ctx context.Context, item protocol.CallHierarchyItem) ([]protocol.CallHierarchyOutgoingCall, error) {
+	c.resultsMu.RLock()
+	defer c.resultsMu.RUnlock()
+	method, err := c.findCallHierarchyMethodLocked(item)
+	if err != nil || method == nil {
+		return nil, err
+	}
+	linkRes, err := c.findResultOrPartialResultByPathLocked(method.ParentFile().Path())
+	if err != nil {
+		return nil, err
+	}
+	rpc := linkRes.MethodNode(method.(protoutil.DescriptorProtoWrapper).AsProto().(*descriptorpb.MethodDescriptorProto))
+	if rpc == nil {
+		return nil, fmt.Errorf("no source information for %s", method.FullName())
+	}
+	fileNode := linkRes.AST()
+
+	var calls []protocol.CallHierarchyOutgoingCall
+	for _, typ := range []struct {
+		desc protoreflect.MessageDescriptor
+		node *ast.RPCTypeNode
+	}{
+		{method.Input(), rpc.Input},
+		{method.Output(), rpc.Output},
+	} {
+		if typ.desc == nil || typ.desc.IsPlaceholder() || typ.node == nil || typ.node.MessageType == nil {
+			continue
+		}
+		fromRange := toRange(fileNode.NodeInfo(typ.node.MessageType))
+		if len(calls) > 0 && calls[0].To.Data == string(typ.desc.FullName()) {
+			// the request and response types are the same
+			calls[0].FromRanges = append(calls[0].FromRanges, fromRange)
+			continue
+		}
+		to, err := c.toTypeHierarchyItemLocked(typ.desc)
+		if err != nil {
+			continue
+		}
+		to.Range = to.SelectionRange
+		calls = append(calls, protocol.CallHierarchyOutgoingCall{
+			To:         protocol.CallHierarchyItem(to),
+			FromRanges: []protocol.Range{fromRange},
+		})
+	}
+	return calls, nil
+}
+
+func (c *Cache) FindIncomingCalls(ctx context.Context, item protocol.CallHierarchyItem) ([]protocol.CallHierarchyIncomingCall, error) {
+	c.resultsMu.RLock()
+	method, err := c.findCallHierarchyMethodLocked(item)
+	c.resultsMu.RUnlock()
+	if err != nil || method == nil {
+		return nil, err
+	}
+	svc, ok := method.Parent().(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, nil
+	}
+	parentUri, err := c.resolver.PathToURI(method.ParentFile().Path())
+	if err != nil {
+		return nil, err
+	}
+
+	// type-checking the go module can take a while, so it is done without
+	// holding the lock; descriptors are immutable once linked.
+	sites, err := c.resolver.FindGoMethodCallSites(parentUri, method.ParentFile(), GoIdent(svc)+"Client", GoIdent(method))
+	if err != nil {
+		if errors.Is(err, ErrNoModule) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	type caller struct {
+		filename string
+		fn       *goast.FuncDecl
+	}
+	var calls []protocol.CallHierarchyIncomingCall
+	indexes := map[caller]int{}
+	for _, site := range sites {
+		key := caller{site.File.Filename, site.Func}
+		fromRange := nodeLocation(site.File, site.Sel.Sel).Range
+		if i, ok := indexes[key]; ok {
+			calls[i].FromRanges = append(calls[i].FromRanges, fromRange)
+			continue
+		}
+		indexes[key] = len(calls)
+		calls = append(calls, protocol.CallHierarchyIncomingCall{
+			From:       goCallerItem(site),
+			FromRanges: []protocol.Range{fromRange},
+		})
+	}
+	return calls, nil
+}
+
+// findCallHierarchyMethodLocked returns the method referenced by a call
+// hierarchy item, or nil if the item does not refer to a method (for example,
+// if it is a Go function from the list of incoming calls).
+func (c *Cache) findCallHierarchyMethodLocked(item protocol.CallHierarchyItem) (protoreflect.MethodDescriptor, error) {
+	name, ok := item.Data.(string)
+	if !ok {
+		return nil, nil
+	}
+	desc, err := c.results.AsResolver().FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, err
+	}
+	method, _ := desc.(protoreflect.MethodDescriptor)
+	return method, nil
+}
+
+func (c *Cache) toCallHierarchyItemLocked(method protoreflect.MethodDescriptor) (protocol.CallHierarchyItem, error) {
+	linkRes, err := c.findResultOrPartialResultByPathLocked(method.ParentFile().Path())
+	if err != nil {
+		return protocol.CallHierarchyItem{}, err
+	}
+	uri, err := c.resolver.PathToURI(method.ParentFile().Path())
+	if err != nil {
+		return protocol.CallHierarchyItem{}, err
+	}
+	rpc := linkRes.MethodNode(method.(protoutil.DescriptorProtoWrapper).AsProto().(*descriptorpb.MethodDescriptorProto))
+	if rpc == nil {
+		return protocol.CallHierarchyItem{}, fmt.Errorf("no source information for %s", method.FullName())
+	}
+	fileNode := linkRes.AST()
+	return protocol.CallHierarchyItem{
+		Name:           string(method.Name()),
+		Kind:           symbolKind(method),
+		Detail:         string(method.Parent().FullName()),
+		URI:            uri,
+		Range:          toRange(fileNode.NodeInfo(rpc.Name)),
+		SelectionRange: toRange(fileNode.NodeInfo(rpc.Name)),
+		Data:           string(method.FullName()),
+	}, nil
+}
+
+func goCallerItem(site GoCallSite) protocol.CallHierarchyItem {
+	filename := site.File.Filename
+	detail := fmt.Sprintf("%s • %s", site.File.Name.Name, filepath.Base(filename))
+	if site.Func == nil {
+		return protocol.CallHierarchyItem{
+			Name:           filepath.Base(filename),
+			Kind:           protocol.File,
+			Detail:         detail,
+			URI:            protocol.URIFromPath(filename),
+			Range:          nodeLocation(site.File, site.File.Name).Range,
+			SelectionRange: nodeLocation(site.File, site.File.Name).Range,
+		}
+	}
+	kind := protocol.Function
+	if site.Func.Recv != nil {
+		kind = protocol.Method
+	}
+	return protocol.CallHierarchyItem{
+		Name:           site.Func.Name.Name,
+		Kind:           kind,
+		Detail:         detail,
+		URI:            protocol.URIFromPath(filename),
+		Range:          nodeLocation(site.File, site.Func.Name).Range,
+		SelectionRange: nodeLocation(site.File, site.Func.Name).Range,
+	}
+}
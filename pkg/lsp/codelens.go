@@ -2,6 +2,9 @@ package lsp
 
 import (
 	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
 
 	"github.com/kralicky/protocompile/linker"
 	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
@@ -24,6 +27,23 @@ func (c *Cache) ComputeCodeLens(uri protocol.DocumentURI) ([]protocol.CodeLens,
 		return nil, nil
 	}
 
+	// if the workspace has a generation config, it determines which generators
+	// are run; an empty list disables code generation.
+	var suffix string
+	config, err := c.GenerateConfig()
+	if err != nil {
+		slog.With("error", err).Warn("failed to load code generation config")
+	} else if config != nil {
+		if len(config.Generators) == 0 {
+			return nil, nil
+		}
+		names := make([]string, len(config.Generators))
+		for i, g := range config.Generators {
+			names[i] = g.DisplayName()
+		}
+		suffix = fmt.Sprintf(" (%s)", strings.Join(names, ", "))
+	}
+
 	var codeLenses []protocol.CodeLens
 	req, _ := json.Marshal(GenerateCodeRequest{
 		URIs: []protocol.DocumentURI{uri},
@@ -32,7 +52,7 @@ func (c *Cache) ComputeCodeLens(uri protocol.DocumentURI) ([]protocol.CodeLens,
 	codeLenses = append(codeLenses,
 		protocol.CodeLens{
 			Command: &protocol.Command{
-				Title:     "Generate File",
+				Title:     "Generate File" + suffix,
 				Command:   "protols.generate",
 				Arguments: []json.RawMessage{json.RawMessage(req)},
 			},
@@ -55,7 +75,7 @@ func (c *Cache) ComputeCodeLens(uri protocol.DocumentURI) ([]protocol.CodeLens,
 		codeLenses = append(codeLenses,
 			protocol.CodeLens{
 				Command: &protocol.Command{
-					Title:     "Generate Package",
+					Title:     "Generate Package" + suffix,
 					Command:   "protols.generate",
 					Arguments: []json.RawMessage{json.RawMessage(req)},
 				},
@@ -69,7 +89,7 @@ func (c *Cache) ComputeCodeLens(uri protocol.DocumentURI) ([]protocol.CodeLens,
 	codeLenses = append(codeLenses,
 		protocol.CodeLens{
 			Command: &protocol.Command{
-				Title:     "Generate Workspace",
+				Title:     "Generate Workspace" + suffix,
 				Command:   "protols.generateWorkspace",
 				Arguments: []json.RawMessage{json.RawMessage(req)},
 			},
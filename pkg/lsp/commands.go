@@ -6,7 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"runtime"
+	"slices"
 
 	"github.com/kralicky/protols/pkg/format"
 	"github.com/kralicky/tools-lite/gopls/pkg/file"
@@ -108,59 +110,11 @@ func (s *Server) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCom
 		return format.DumpAST(parseRes.AST(), parseRes), nil
 	case "protols/reindexWorkspaces":
 		s.cachesMu.Lock()
-		allWorkspaces := []protocol.WorkspaceFolder{}
-		openOverlays := map[protocol.WorkspaceFolder][]file.Modification{}
-		for _, c := range s.caches {
-			allWorkspaces = append(allWorkspaces, c.workspace)
-
-			for _, overlay := range c.resolver.Overlays() {
-				df, err := c.resolver.OpenFileFromDisk(ctx, overlay.URI())
-				if err != nil {
-					slog.Error("failed to open file from disk", "uri", overlay.URI(), "err", err)
-					continue
-				}
-				dfContent, err := df.Content()
-				if err != nil {
-					slog.Error("failed to read file from disk", "uri", overlay.URI(), "err", err)
-					continue
-				}
-				openOverlays[c.workspace] = append(openOverlays[c.workspace], file.Modification{
-					URI:        overlay.URI(),
-					Action:     file.Open,
-					OnDisk:     false,
-					Version:    df.Version(),
-					Text:       dfContent,
-					LanguageID: "protobuf",
-				})
-				if !overlay.SameContentsOnDisk() {
-					// generate an additional change event for the overlay
-					editorContent, _ := overlay.Content() // always returns nil error
-					openOverlays[c.workspace] = append(openOverlays[c.workspace],
-						file.Modification{
-							URI:     overlay.URI(),
-							Action:  file.Change,
-							OnDisk:  false,
-							Version: max(overlay.Version(), 2),
-							Text:    editorContent,
-						},
-					)
-				}
-			}
-		}
 		slog.Info("reindexing workspaces")
-		for path := range s.caches {
-			s.cacheDestroyLocked(path, errors.New("reindexing workspaces"))
+		for _, c := range slices.Collect(maps.Values(s.caches)) {
+			s.reindexCacheLocked(ctx, c, *c.settings.Load())
 		}
-		clear(s.caches)
 		runtime.GC()
-		for _, folder := range allWorkspaces {
-			path := protocol.DocumentURI(folder.URI).Path()
-			c := NewCache(folder)
-			s.cacheInitLocked(c, path)
-			if changes, ok := openOverlays[folder]; ok {
-				c.DidModifyFiles(ctx, changes)
-			}
-		}
 		s.cachesMu.Unlock()
 		return nil, nil
 	case "protols/refreshModules":
@@ -222,3 +176,52 @@ func (s *Server) ExecuteCommand(ctx context.Context, params *protocol.ExecuteCom
 		return nil, fmt.Errorf("unknown command %q", params.Command)
 	}
 }
+
+// reindexCacheLocked replaces the given cache with a new cache for the same
+// workspace using the given settings. Documents open in the editor are
+// re-opened in the new cache, along with any unsaved changes.
+//
+// requires s.cachesMu held for writing
+func (s *Server) reindexCacheLocked(ctx context.Context, c *Cache, settings Settings) {
+	var openOverlays []file.Modification
+	for _, overlay := range c.resolver.Overlays() {
+		df, err := c.resolver.OpenFileFromDisk(ctx, overlay.URI())
+		if err != nil {
+			slog.Error("failed to open file from disk", "uri", overlay.URI(), "err", err)
+			continue
+		}
+		dfContent, err := df.Content()
+		if err != nil {
+			slog.Error("failed to read file from disk", "uri", overlay.URI(), "err", err)
+			continue
+		}
+		openOverlays = append(openOverlays, file.Modification{
+			URI:        overlay.URI(),
+			Action:     file.Open,
+			OnDisk:     false,
+			Version:    df.Version(),
+			Text:       dfContent,
+			LanguageID: "protobuf",
+		})
+		if !overlay.SameContentsOnDisk() {
+			// generate an additional change event for the overlay
+			editorContent, _ := overlay.Content() // always returns nil error
+			openOverlays = append(openOverlays,
+				file.Modification{
+					URI:     overlay.URI(),
+					Action:  file.Change,
+					OnDisk:  false,
+					Version: max(overlay.Version(), 2),
+					Text:    editorContent,
+				},
+			)
+		}
+	}
+	path := protocol.DocumentURI(c.workspace.URI).Path()
+	s.cacheDestroyLocked(path, errors.New("reindexing workspace"))
+	newCache := NewCache(c.workspace, WithSettings(settings))
+	s.cacheInitLocked(newCache, path)
+	if len(openOverlays) > 0 {
+		newCache.DidModifyFiles(ctx, openOverlays)
+	}
+}
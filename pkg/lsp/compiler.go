@@ -57,6 +57,9 @@ func (c *Cache) postCompile(path protocompile.ResolvedPath) {
 	} else {
 		slog.Debug(fmt.Sprintf("compiled %s\n", path))
 	}
+	c.partialResultsMu.Lock()
+	defer c.partialResultsMu.Unlock()
+	c.compiledPaths[path] = struct{}{}
 }
 
 func (c *Cache) Compile(protos []string, after ...func()) {
@@ -148,8 +151,23 @@ func (c *Cache) compileLocked(protos ...string) {
 		}
 		c.pragmas.Store(path, &pragmaMap{m: pragmas})
 	}
+	var compiled []linker.Result
+	for _, r := range res.Files {
+		if _, ok := c.compiledPaths[protocompile.ResolvedPath(r.Path())]; ok {
+			compiled = append(compiled, r.(linker.Result))
+		}
+	}
+	for path, partial := range res.PartialLinkResults {
+		if _, ok := c.compiledPaths[path]; ok {
+			compiled = append(compiled, partial)
+		}
+	}
+	clear(c.compiledPaths)
 	c.partialResultsMu.Unlock()
 
+	c.reportShadowedImports(compiled...)
+	c.runLintRules(compiled...)
+
 	syntheticFiles := c.resolver.CheckIncompleteDescriptors(c.results)
 	if len(syntheticFiles) == 0 {
 		return
@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/kralicky/protocompile"
 	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protocompile/ast/paths"
 	"github.com/kralicky/protocompile/linker"
@@ -919,6 +920,9 @@ func (c *Cache) deepCompleteOptionNames(
 			if !strings.HasPrefix(string(fld.Name()), partialName) {
 				continue
 			}
+			if !isFeatureCompletionAllowed(fld, prev, linkRes) {
+				continue
+			}
 			if (partialNameSuffix == "" && fld.Name() != protoreflect.Name(partialName)) ||
 				(fld.Name() != protoreflect.Name(partialName+partialNameSuffix)) {
 				if _, ok := existingOpts[string(fld.FullName())]; ok && fld.Cardinality() != protoreflect.Repeated {
@@ -1308,29 +1312,40 @@ func newNonMessageRepeatedOptionCompletionItem(fld protoreflect.FieldDescriptor,
 
 func isProto2(f *ast.FileNode) bool {
 	if f.Syntax == nil {
-		return true
+		return f.Edition == nil
 	}
 	return f.Syntax.Syntax.AsString() == "proto2"
 }
 
+func isEditions(f *ast.FileNode) bool {
+	return f.Edition != nil
+}
+
 func messageKeywordCompletions(fileNode *ast.FileNode, partialName, partialNameSuffix string, pos protocol.Position) []protocol.CompletionItem {
 	// add keyword completions for messages
-	possibleKeywords := []string{"option", "optional", "repeated", "enum", "message", "reserved"}
-	if isProto2(fileNode) {
-		possibleKeywords = append(possibleKeywords, "required", "extend", "group")
+	possibleKeywords := []string{"option", "repeated", "enum", "message", "reserved"}
+	switch {
+	case isProto2(fileNode):
+		possibleKeywords = append(possibleKeywords, "optional", "required", "extend", "group")
+	case isEditions(fileNode):
+		possibleKeywords = append(possibleKeywords, "extend")
+	default:
+		possibleKeywords = append(possibleKeywords, "optional")
 	}
 	return completeKeywords(possibleKeywords, partialName, partialNameSuffix, pos)
 }
 
 func fileKeywordCompletions(fileNode *ast.FileNode, partialName, partialNameSuffix string, pos protocol.Position) []protocol.CompletionItem {
 	possibleKeywords := make([]string, 0, 8)
-	// TODO(editions): add edition keyword
 	var completions []protocol.CompletionItem
-	if fileNode.Syntax == nil {
+	if fileNode.Syntax == nil && fileNode.Edition == nil {
 		if strings.HasPrefix("syntax", partialName) {
 			completions = append(completions, syntaxSnippets()...)
 		}
-		possibleKeywords = append(possibleKeywords, "syntax")
+		if strings.HasPrefix("edition", partialName) {
+			completions = append(completions, editionSnippets()...)
+		}
+		possibleKeywords = append(possibleKeywords, "syntax", "edition")
 	}
 	hasPkgNode := false
 	for _, pkg := range fileNode.Decls {
@@ -1362,10 +1377,28 @@ func syntaxSnippets() []protocol.CompletionItem {
 			InsertTextFormat: &snippetMode,
 			InsertText:       "syntax = \"proto2\";\n",
 		},
-		// TODO(editions): add edition keyword
 	}
 }
 
+func editionSnippets() []protocol.CompletionItem {
+	var items []protocol.CompletionItem
+	for _, edition := range []descriptorpb.Edition{
+		descriptorpb.Edition_EDITION_2023,
+		descriptorpb.Edition_EDITION_2024,
+	} {
+		if !protocompile.IsEditionSupported(edition) {
+			continue
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:            fmt.Sprintf("edition: %s", editionName(edition)),
+			Kind:             protocol.SnippetCompletion,
+			InsertTextFormat: &snippetMode,
+			InsertText:       fmt.Sprintf("edition = %q;\n", editionName(edition)),
+		})
+	}
+	return items
+}
+
 // sort by distance from local package
 type entry struct {
 	candidate     protoreflect.Descriptor
@@ -19,32 +19,44 @@ import (
 	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
 )
 
-// func (c *Cache) ComputeDiagnosticReports(uri protocol.DocumentURI, prevResultId string) ([]protocol.Diagnostic, protocol.DocumentDiagnosticReportKind, string, error) {
-// 	c.resultsMu.RLock()
-// 	defer c.resultsMu.RUnlock()
-// 	var maybePrevResultId []string
-// 	if prevResultId != "" {
-// 		maybePrevResultId = append(maybePrevResultId, prevResultId)
-// 	}
-// 	path, err := c.resolver.URIToPath(uri)
-// 	if err != nil {
-// 		slog.With(
-// 			"error", err,
-// 			"uri", string(uri),
-// 		).Error("failed to resolve uri to path")
-// 		return nil, protocol.DiagnosticUnchanged, "", nil
-// 	}
-// 	rawReports, resultId, unchanged := c.diagHandler.GetDiagnosticsForPath(path, maybePrevResultId...)
-// 	if unchanged {
-// 		return nil, protocol.DiagnosticUnchanged, resultId, nil
-// 	}
-// 	protocolReports := c.toProtocolDiagnostics(rawReports)
-// 	if protocolReports == nil {
-// 		protocolReports = []protocol.Diagnostic{}
-// 	}
-
-// 	return protocolReports, protocol.DiagnosticFull, resultId, nil
-// }
+func (c *Cache) ComputeDiagnosticReports(uri protocol.DocumentURI, prevResultId string) ([]protocol.Diagnostic, protocol.DocumentDiagnosticReportKind, string, error) {
+	c.resultsMu.RLock()
+	defer c.resultsMu.RUnlock()
+	var maybePrevResultId []string
+	if prevResultId != "" {
+		maybePrevResultId = append(maybePrevResultId, prevResultId)
+	}
+	path, err := c.resolver.URIToPath(uri)
+	if err != nil {
+		slog.With(
+			"error", err,
+			"uri", string(uri),
+		).Error("failed to resolve uri to path")
+		return nil, protocol.DiagnosticUnchanged, "", nil
+	}
+	rawReports, resultId, unchanged := c.diagHandler.GetDiagnosticsForPath(path, maybePrevResultId...)
+	if unchanged {
+		return nil, protocol.DiagnosticUnchanged, resultId, nil
+	}
+	protocolReports := c.toProtocolDiagnostics(rawReports)
+	if protocolReports == nil {
+		protocolReports = []protocol.Diagnostic{}
+	}
+
+	return protocolReports, protocol.DiagnosticFull, resultId, nil
+}
+
+// ComputeWorkspaceDiagnosticReports returns a full report for every file with
+// known diagnostics.
+func (c *Cache) ComputeWorkspaceDiagnosticReports() []protocol.WorkspaceFullDocumentDiagnosticReport {
+	var reports []protocol.WorkspaceFullDocumentDiagnosticReport
+	c.diagHandler.Range(func(path string, resultId string, diagnostics []*ProtoDiagnostic) {
+		if report, ok := c.toWorkspaceDiagnosticReport(path, resultId, diagnostics); ok {
+			reports = append(reports, report)
+		}
+	})
+	return reports
+}
 
 func (c *Cache) toProtocolDiagnostics(rawReports []*ProtoDiagnostic) []protocol.Diagnostic {
 	reports := make([]protocol.Diagnostic, 0)
@@ -91,6 +103,8 @@ func (c *Cache) toProtocolDiagnostics(rawReports []*ProtoDiagnostic) []protocol.
 		}
 		if rawReport.WerrorCategory != "" {
 			report.Code = rawReport.WerrorCategory
+		} else if rawReport.LintRule != "" {
+			report.Code = rawReport.LintRule
 		}
 		data := DiagnosticData{
 			Metadata:    rawReport.Metadata,
@@ -111,22 +125,43 @@ type workspaceDiagnosticCallbackFunc = func(uri protocol.DocumentURI, reports []
 
 func (c *Cache) StreamWorkspaceDiagnostics(ctx context.Context, ch chan<- protocol.WorkspaceFullDocumentDiagnosticReport) {
 	c.diagHandler.Stream(ctx, func(path string, resultId string, diagnostics []*ProtoDiagnostic) {
-		uri, err := c.resolver.PathToURI(path)
-		if err != nil {
-			return
+		if report, ok := c.toWorkspaceDiagnosticReport(path, resultId, diagnostics); ok {
+			select {
+			case ch <- report:
+			case <-ctx.Done():
+			}
 		}
-		ch <- protocol.WorkspaceFullDocumentDiagnosticReport{
-			URI:     uri,
-			Version: c.documentVersions.Get(uri),
-			FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
-				Kind:     string(protocol.DiagnosticFull),
-				ResultID: resultId,
-				Items:    c.toProtocolDiagnostics(diagnostics),
-			},
+	})
+}
+
+// WatchDiagnostics sends to ch each time the diagnostics for any file change
+// after a compile, until ctx is done. If a value is already pending, the
+// change is merged with it, so the receiver is not notified once per file.
+func (c *Cache) WatchDiagnostics(ctx context.Context, ch chan<- struct{}) {
+	c.diagHandler.Watch(ctx, func(string, string, []*ProtoDiagnostic) {
+		select {
+		case ch <- struct{}{}:
+		default:
 		}
 	})
 }
 
+func (c *Cache) toWorkspaceDiagnosticReport(path string, resultId string, diagnostics []*ProtoDiagnostic) (protocol.WorkspaceFullDocumentDiagnosticReport, bool) {
+	uri, err := c.resolver.PathToURI(path)
+	if err != nil {
+		return protocol.WorkspaceFullDocumentDiagnosticReport{}, false
+	}
+	return protocol.WorkspaceFullDocumentDiagnosticReport{
+		URI:     uri,
+		Version: c.documentVersions.Get(uri),
+		FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
+			Kind:     string(protocol.DiagnosticFull),
+			ResultID: resultId,
+			Items:    c.toProtocolDiagnostics(diagnostics),
+		},
+	}, true
+}
+
 type ProtoDiagnostic struct {
 	Path               string
 	Version            int32
@@ -141,6 +176,10 @@ type ProtoDiagnostic struct {
 	// If this is a warning being treated as an error, WerrorCategory will be set to
 	// a category that can be named in a debug pragma to disable it.
 	WerrorCategory string
+
+	// If this diagnostic was reported by a lint rule, LintRule will be set to
+	// the name of the rule.
+	LintRule string
 }
 
 type RelatedInformation struct {
@@ -171,6 +210,7 @@ type CodeAction struct {
 func NewDiagnosticHandler() *DiagnosticHandler {
 	return &DiagnosticHandler{
 		diagnostics: map[string]*DiagnosticList{},
+		listeners:   map[int]ListenerFunc{},
 	}
 }
 
@@ -247,10 +287,11 @@ type (
 )
 
 type DiagnosticHandler struct {
-	diagnosticsMu sync.RWMutex
-	diagnostics   map[string]*DiagnosticList
-	listenerMu    sync.RWMutex
-	listener      ListenerFunc
+	diagnosticsMu  sync.RWMutex
+	diagnostics    map[string]*DiagnosticList
+	listenerMu     sync.RWMutex
+	listeners      map[int]ListenerFunc
+	nextListenerId int
 }
 
 func tagsForError(errWithPos reporter.ErrorWithPos) []protocol.DiagnosticTag {
@@ -496,6 +537,18 @@ func (dr *DiagnosticHandler) HandleWarning(err reporter.ErrorWithPos) {
 	// dr.listenerMu.RUnlock()
 }
 
+// HandleDiagnostic adds a diagnostic which was not reported by the compiler,
+// such as one reported by a lint rule.
+func (dr *DiagnosticHandler) HandleDiagnostic(d *ProtoDiagnostic) {
+	slog.Debug(fmt.Sprintf("[diagnostic] %s: %s\n", d.Path, d.Error.Error()))
+
+	dr.diagnosticsMu.Lock()
+	dl, _ := dr.getOrCreateDiagnosticListLocked(d.Path)
+	dr.diagnosticsMu.Unlock()
+
+	dl.Add(d)
+}
+
 func (dr *DiagnosticHandler) GetDiagnosticsForPath(path string, prevResultId ...string) ([]*ProtoDiagnostic, string, bool) {
 	dr.diagnosticsMu.RLock()
 	defer dr.diagnosticsMu.RUnlock()
@@ -527,6 +580,7 @@ func (dr *DiagnosticHandler) FullDiagnosticSnapshot() map[string][]*ProtoDiagnos
 				CodeActions:        d.CodeActions,
 				Metadata:           d.Metadata,
 				WerrorCategory:     d.WerrorCategory,
+				LintRule:           d.LintRule,
 			})
 		}
 		res[path] = list
@@ -568,26 +622,59 @@ func (dr *DiagnosticHandler) ClearDiagnosticsForPath(path string) {
 	// dr.listenerMu.RUnlock()
 }
 
+// Stream calls the callback once for each path with known diagnostics, then
+// again each time the diagnostics for a path are flushed, until ctx is done.
+// Multiple streams may be active at the same time.
 func (dr *DiagnosticHandler) Stream(ctx context.Context, callback ListenerFunc) {
-	// dr.diagnosticsMu.RLock()
+	dr.diagnosticsMu.RLock()
+	dr.rangeLocked(callback)
+	id := dr.addListener(callback)
+	dr.diagnosticsMu.RUnlock()
 
-	dr.listenerMu.Lock()
-	dr.listener = callback
-	dr.listenerMu.Unlock()
+	<-ctx.Done()
 
-	// dr.listenerMu.RLock()
-	// for path, dl := range dr.diagnostics {
-	// 	callback(path, dl.resultId, dl.diagnostics)
-	// }
-	// dr.listenerMu.RUnlock()
+	dr.removeListener(id)
+}
 
-	// dr.diagnosticsMu.RUnlock()
+// Watch calls the callback each time the diagnostics for a path are flushed,
+// until ctx is done. Unlike Stream, it is not called for the diagnostics which
+// are already known.
+func (dr *DiagnosticHandler) Watch(ctx context.Context, callback ListenerFunc) {
+	id := dr.addListener(callback)
 
 	<-ctx.Done()
 
+	dr.removeListener(id)
+}
+
+func (dr *DiagnosticHandler) addListener(callback ListenerFunc) int {
+	dr.listenerMu.Lock()
+	defer dr.listenerMu.Unlock()
+	id := dr.nextListenerId
+	dr.nextListenerId++
+	dr.listeners[id] = callback
+	return id
+}
+
+func (dr *DiagnosticHandler) removeListener(id int) {
 	dr.listenerMu.Lock()
-	dr.listener = nil
-	dr.listenerMu.Unlock()
+	defer dr.listenerMu.Unlock()
+	delete(dr.listeners, id)
+}
+
+// Range calls fn with the current diagnostics for each path known to the handler.
+func (dr *DiagnosticHandler) Range(fn ListenerFunc) {
+	dr.diagnosticsMu.RLock()
+	defer dr.diagnosticsMu.RUnlock()
+	dr.rangeLocked(fn)
+}
+
+// requires diagnosticsMu to be held
+func (dr *DiagnosticHandler) rangeLocked(fn ListenerFunc) {
+	for path, dl := range dr.diagnostics {
+		diagnostics, resultId, _ := dl.Get()
+		fn(path, resultId, diagnostics)
+	}
 }
 
 func (dr *DiagnosticHandler) Flush() {
@@ -599,8 +686,8 @@ func (dr *DiagnosticHandler) Flush() {
 		if wasDirty {
 			slog.Debug(fmt.Sprintf("[diagnostic] flushing %d diagnostics for %s\n", len(diagnostics), path))
 			dr.listenerMu.RLock()
-			if dr.listener != nil {
-				dr.listener(path, resultId, diagnostics)
+			for _, listener := range dr.listeners {
+				listener(path, resultId, diagnostics)
 			}
 			dr.listenerMu.RUnlock()
 		}
@@ -0,0 +1,71 @@
+package lsp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/file"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "a.proto")
+	require.NoError(t, os.WriteFile(filename, []byte("syntax = \"proto3\";\npackage a;\nmessage A {}\n"), 0o644))
+
+	cache := NewCache(protocol.WorkspaceFolder{URI: string(protocol.URIFromPath(dir))})
+	cache.LoadFiles([]string{filename})
+
+	ctx, ca := context.WithCancel(context.Background())
+	defer ca()
+	changed := make(chan struct{}, 1)
+	go cache.WatchDiagnostics(ctx, changed)
+	require.Eventually(t, func() bool {
+		cache.diagHandler.listenerMu.RLock()
+		defer cache.diagHandler.listenerMu.RUnlock()
+		return len(cache.diagHandler.listeners) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// diagnostics from the initial load are not reported
+	select {
+	case <-changed:
+		t.Fatal("unexpected notification for existing diagnostics")
+	default:
+	}
+
+	uri := protocol.URIFromPath(filename)
+	cache.DidModifyFiles(ctx, []file.Modification{{
+		Action:     file.Open,
+		URI:        uri,
+		Version:    1,
+		Text:       []byte("syntax = \"proto3\";\npackage a;\nmessage A {}\n"),
+		LanguageID: "protobuf",
+	}})
+	// opening the file may recompile it; only the change below is of interest
+	select {
+	case <-changed:
+	default:
+	}
+	cache.DidModifyFiles(ctx, []file.Modification{{
+		Action:  file.Change,
+		URI:     uri,
+		Version: 2,
+		Text:    []byte("syntax = \"proto3\";\npackage a;\nmessage A {\n"),
+	}})
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a notification after diagnostics changed")
+	}
+
+	ca()
+	require.Eventually(t, func() bool {
+		cache.diagHandler.listenerMu.RLock()
+		defer cache.diagHandler.listenerMu.RUnlock()
+		return len(cache.diagHandler.listeners) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -78,6 +78,7 @@ func (c *Cache) DidModifyFiles(ctx context.Context, modifications []file.Modific
 		}
 		switch m.Action {
 		case file.Close:
+			c.semanticTokens.delete(m.URI)
 		case file.Open, file.Save:
 			fh, err := c.compiler.fs.ReadFile(ctx, m.URI)
 			if err == nil || fh.Version() != m.Version {
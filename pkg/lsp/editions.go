@@ -0,0 +1,179 @@
+package lsp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protols/pkg/format/protoprint"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var featureSetDescriptor = (*descriptorpb.FeatureSet)(nil).ProtoReflect().Descriptor()
+
+// editionName returns the name of the edition as written in source files,
+// e.g. "2023".
+func editionName(edition descriptorpb.Edition) string {
+	return strings.TrimPrefix(edition.String(), "EDITION_")
+}
+
+// featureTargetType returns the option target type corresponding to the kind
+// of the given descriptor.
+func featureTargetType(desc protoreflect.Descriptor) descriptorpb.FieldOptions_OptionTargetType {
+	switch desc.(type) {
+	case protoreflect.FileDescriptor:
+		return descriptorpb.FieldOptions_TARGET_TYPE_FILE
+	case protoreflect.MessageDescriptor:
+		return descriptorpb.FieldOptions_TARGET_TYPE_MESSAGE
+	case protoreflect.FieldDescriptor:
+		return descriptorpb.FieldOptions_TARGET_TYPE_FIELD
+	case protoreflect.OneofDescriptor:
+		return descriptorpb.FieldOptions_TARGET_TYPE_ONEOF
+	case protoreflect.EnumDescriptor:
+		return descriptorpb.FieldOptions_TARGET_TYPE_ENUM
+	case protoreflect.EnumValueDescriptor:
+		return descriptorpb.FieldOptions_TARGET_TYPE_ENUM_ENTRY
+	case protoreflect.ServiceDescriptor:
+		return descriptorpb.FieldOptions_TARGET_TYPE_SERVICE
+	case protoreflect.MethodDescriptor:
+		return descriptorpb.FieldOptions_TARGET_TYPE_METHOD
+	}
+	return descriptorpb.FieldOptions_TARGET_TYPE_UNKNOWN
+}
+
+// isFeatureAvailable reports whether the given field of google.protobuf.FeatureSet
+// can be used in the given edition.
+func isFeatureAvailable(feature protoreflect.FieldDescriptor, edition descriptorpb.Edition) bool {
+	support := feature.Options().(*descriptorpb.FieldOptions).GetFeatureSupport()
+	if support == nil {
+		return true
+	}
+	if introduced := support.GetEditionIntroduced(); introduced != 0 && introduced > edition {
+		return false
+	}
+	if removed := support.GetEditionRemoved(); removed != 0 && removed <= edition {
+		return false
+	}
+	return true
+}
+
+// optionsTargetType returns the option target type corresponding to the given
+// options message, or TARGET_TYPE_UNKNOWN if it is not one of the standard
+// options messages.
+func optionsTargetType(name protoreflect.FullName) descriptorpb.FieldOptions_OptionTargetType {
+	switch name {
+	case "google.protobuf.FileOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_FILE
+	case "google.protobuf.ExtensionRangeOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_EXTENSION_RANGE
+	case "google.protobuf.MessageOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_MESSAGE
+	case "google.protobuf.FieldOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_FIELD
+	case "google.protobuf.OneofOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_ONEOF
+	case "google.protobuf.EnumOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_ENUM
+	case "google.protobuf.EnumValueOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_ENUM_ENTRY
+	case "google.protobuf.ServiceOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_SERVICE
+	case "google.protobuf.MethodOptions":
+		return descriptorpb.FieldOptions_TARGET_TYPE_METHOD
+	}
+	return descriptorpb.FieldOptions_TARGET_TYPE_UNKNOWN
+}
+
+// isFeatureCompletionAllowed reports whether the given field should be offered
+// when completing option names within prev. The features field of the options
+// messages is only offered in files using editions, and individual features
+// are only offered if they are available in the file's edition and can be set
+// on the kind of element the options belong to.
+func isFeatureCompletionAllowed(fld, prev protoreflect.Descriptor, file protoreflect.FileDescriptor) bool {
+	edition, isEditions := protoprint.FileEdition(file)
+	if fd, ok := fld.(protoreflect.FieldDescriptor); ok && fd.Message() != nil && fd.Message().FullName() == featureSetDescriptor.FullName() {
+		return isEditions
+	}
+	if fld.Parent() == nil || fld.Parent().FullName() != featureSetDescriptor.FullName() {
+		return true
+	}
+	// use the linked-in descriptor, which is guaranteed to have interpreted options
+	feature := featureSetDescriptor.Fields().ByName(fld.Name())
+	if feature == nil || !isEditions {
+		return true
+	}
+	if !isFeatureAvailable(feature, edition) {
+		return false
+	}
+	if features, ok := prev.(protoreflect.FieldDescriptor); ok {
+		target := optionsTargetType(features.ContainingMessage().FullName())
+		if target != descriptorpb.FieldOptions_TARGET_TYPE_UNKNOWN &&
+			!slices.Contains(feature.Options().(*descriptorpb.FieldOptions).GetTargets(), target) {
+			return false
+		}
+	}
+	return true
+}
+
+type resolvedFeature struct {
+	Name  protoreflect.Name
+	Value string
+}
+
+// resolvedFeatures returns the name and resolved value of each feature which
+// applies to the given descriptor. Features which cannot be set on the kind of
+// descriptor given are omitted. If the descriptor is not in a file which uses
+// editions, no features are returned.
+func resolvedFeatures(desc protoreflect.Descriptor) (descriptorpb.Edition, []resolvedFeature) {
+	edition, ok := protoprint.FileEdition(desc.ParentFile())
+	if !ok {
+		return 0, nil
+	}
+	target := featureTargetType(desc)
+	var features []resolvedFeature
+	fields := featureSetDescriptor.Fields()
+	for i := range fields.Len() {
+		feature := fields.Get(i)
+		if !isFeatureAvailable(feature, edition) {
+			continue
+		}
+		if !slices.Contains(feature.Options().(*descriptorpb.FieldOptions).GetTargets(), target) {
+			continue
+		}
+		value, err := protoutil.ResolveFeature(desc, feature)
+		if err != nil {
+			continue
+		}
+		features = append(features, resolvedFeature{
+			Name:  feature.Name(),
+			Value: featureValueString(feature, value),
+		})
+	}
+	return edition, features
+}
+
+func featureValueString(feature protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	if feature.Kind() == protoreflect.EnumKind {
+		if ev := feature.Enum().Values().ByNumber(value.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// resolvedFeaturesMarkdown returns a markdown list of the features resolved
+// for the given descriptor, or an empty string if there are none.
+func resolvedFeaturesMarkdown(desc protoreflect.Descriptor) string {
+	edition, features := resolvedFeatures(desc)
+	if len(features) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**Features** (edition %s)\n", editionName(edition))
+	for _, f := range features {
+		fmt.Fprintf(&sb, "- `%s`: `%s`\n", f.Name, f.Value)
+	}
+	return sb.String()
+}
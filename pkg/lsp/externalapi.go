@@ -34,9 +34,6 @@ func (c *Cache) XGetLinkerResults() []linker.Result {
 		if result.IsPlaceholder() {
 			continue
 		}
-		if result.Syntax() == protoreflect.Editions {
-			continue
-		}
 		results = append(results, result.(linker.Result))
 	}
 	return results
@@ -70,6 +67,10 @@ func (c *Cache) XGetURIPathMappings() PathMappings {
 	}
 }
 
+func (c *Cache) XGetWorkdir() string {
+	return c.compiler.workdir
+}
+
 func (c *Cache) XListWorkspaceLocalURIs() []protocol.DocumentURI {
 	c.resultsMu.RLock()
 	defer c.resultsMu.RUnlock()
@@ -0,0 +1,195 @@
+package lsp
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+)
+
+// ComputeFoldingRanges returns folding ranges for declaration bodies, runs of
+// imports, multi-line comments, and multi-line option values in the given
+// document. If lineFoldingOnly is set, the ranges will only contain complete
+// lines, and the line containing the closing brace of a body is left unfolded.
+func (c *Cache) ComputeFoldingRanges(doc protocol.TextDocumentIdentifier, lineFoldingOnly bool) ([]protocol.FoldingRange, error) {
+	mapper, err := c.GetMapper(doc.URI)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.FindParseResultByURI(doc.URI)
+	if err != nil {
+		return nil, err
+	}
+	fileNode := res.AST()
+	if fileNode == nil {
+		return nil, nil
+	}
+	f := foldingRangeBuilder{
+		mapper:          mapper,
+		fileNode:        fileNode,
+		lineFoldingOnly: lineFoldingOnly,
+	}
+	f.addBodies()
+	f.addImports()
+	f.addComments()
+
+	slices.SortStableFunc(f.ranges, func(a, b protocol.FoldingRange) int {
+		return cmp.Or(
+			cmp.Compare(a.StartLine, b.StartLine),
+			cmp.Compare(a.StartCharacter, b.StartCharacter),
+		)
+	})
+	return f.ranges, nil
+}
+
+type foldingRangeBuilder struct {
+	mapper          *protocol.Mapper
+	fileNode        *ast.FileNode
+	lineFoldingOnly bool
+	ranges          []protocol.FoldingRange
+}
+
+// bodyDelimiters returns the open and close runes of a node which has a body
+// that can be folded.
+func bodyDelimiters(node ast.Node) (open, close *ast.RuneNode) {
+	switch node := node.(type) {
+	case *ast.MessageNode:
+		return node.OpenBrace, node.CloseBrace
+	case *ast.EnumNode:
+		return node.OpenBrace, node.CloseBrace
+	case *ast.ServiceNode:
+		return node.OpenBrace, node.CloseBrace
+	case *ast.OneofNode:
+		return node.OpenBrace, node.CloseBrace
+	case *ast.ExtendNode:
+		return node.OpenBrace, node.CloseBrace
+	case *ast.GroupNode:
+		return node.OpenBrace, node.CloseBrace
+	case *ast.RPCNode:
+		return node.OpenBrace, node.CloseBrace
+	case *ast.MessageLiteralNode:
+		return node.Open, node.Close
+	case *ast.ArrayLiteralNode:
+		return node.OpenBracket, node.CloseBracket
+	case *ast.CompactOptionsNode:
+		return node.OpenBracket, node.CloseBracket
+	}
+	return nil, nil
+}
+
+func (f *foldingRangeBuilder) addBodies() {
+	ast.Inspect(f.fileNode, func(node ast.Node) bool {
+		open, close := bodyDelimiters(node)
+		if open == nil || close == nil || open.GetVirtual() || close.GetVirtual() {
+			return true
+		}
+		start := toPosition(f.fileNode.NodeInfo(open).End())
+		end := toPosition(f.fileNode.NodeInfo(close).Start())
+		if f.lineFoldingOnly {
+			// keep the closing brace visible
+			if end.Line == 0 {
+				return true
+			}
+			end = protocol.Position{Line: end.Line - 1}
+		}
+		f.add(start, end, protocol.Region)
+		return true
+	})
+}
+
+func (f *foldingRangeBuilder) addImports() {
+	var first, last *ast.ImportNode
+	flush := func() {
+		if first != nil && first != last {
+			start := f.endOfLine(f.fileNode.NodeInfo(first).Start().Offset)
+			end := toPosition(f.fileNode.NodeInfo(last).End())
+			f.add(start, end, protocol.Imports)
+		}
+		first, last = nil, nil
+	}
+	for _, decl := range f.fileNode.Decls {
+		imp := decl.GetImport()
+		if imp == nil {
+			flush()
+			continue
+		}
+		if first == nil {
+			first = imp
+		}
+		last = imp
+	}
+	flush()
+}
+
+func (f *foldingRangeBuilder) addComments() {
+	var group []ast.Comment
+	flush := func() {
+		if len(group) > 0 {
+			start := f.endOfLine(group[0].Start().Offset)
+			end, err := f.mapper.OffsetPosition(group[len(group)-1].End().Offset + 1)
+			if err == nil {
+				f.add(start, end, protocol.Comment)
+			}
+		}
+		group = nil
+	}
+	items := f.fileNode.Items()
+	for item, ok := items.First(); ok; item, ok = items.Next(item) {
+		_, comment := f.fileNode.GetItem(item)
+		if !comment.IsValid() || comment.IsVirtual() || !f.startsLine(comment.Start().Offset) {
+			continue
+		}
+		if !strings.HasPrefix(comment.RawText(), "//") {
+			// block comments are folded on their own
+			flush()
+			group = append(group, comment)
+			flush()
+			continue
+		}
+		if len(group) > 0 {
+			prev := group[len(group)-1]
+			if !strings.HasPrefix(prev.RawText(), "//") || comment.Start().Line != prev.End().Line+1 {
+				flush()
+			}
+		}
+		group = append(group, comment)
+	}
+	flush()
+}
+
+// startsLine reports whether only whitespace precedes the given offset on its line.
+func (f *foldingRangeBuilder) startsLine(offset int) bool {
+	lineStart := bytes.LastIndexByte(f.mapper.Content[:offset], '\n') + 1
+	return len(bytes.TrimSpace(f.mapper.Content[lineStart:offset])) == 0
+}
+
+// endOfLine returns the position at the end of the line containing offset.
+func (f *foldingRangeBuilder) endOfLine(offset int) protocol.Position {
+	end := bytes.IndexByte(f.mapper.Content[offset:], '\n')
+	if end < 0 {
+		end = len(f.mapper.Content)
+	} else {
+		end += offset
+	}
+	pos, _ := f.mapper.OffsetPosition(end)
+	return pos
+}
+
+func (f *foldingRangeBuilder) add(start, end protocol.Position, kind protocol.FoldingRangeKind) {
+	if end.Line <= start.Line {
+		return
+	}
+	rng := protocol.FoldingRange{
+		StartLine: start.Line,
+		EndLine:   end.Line,
+		Kind:      string(kind),
+	}
+	if !f.lineFoldingOnly {
+		rng.StartCharacter = start.Character
+		rng.EndCharacter = end.Character
+	}
+	f.ranges = append(f.ranges, rng)
+}
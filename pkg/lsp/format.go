@@ -2,43 +2,235 @@ package lsp
 
 import (
 	"bytes"
+	"fmt"
+	"slices"
 
+	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/protols/pkg/format"
 	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
 	"github.com/kralicky/tools-lite/pkg/diff"
 )
 
+// FormatDocument formats the given document. If one or more ranges are given,
+// only the message, enum, service, and extend declarations selected by those
+// ranges are formatted; the rest of the document is left untouched.
 func (c *Cache) FormatDocument(doc protocol.TextDocumentIdentifier, options protocol.FormattingOptions, maybeRange ...protocol.Range) ([]protocol.TextEdit, error) {
+	mapper, fileNode, err := c.findFormattableAST(doc.URI)
+	if err != nil || fileNode == nil {
+		return nil, err
+	}
+	if len(maybeRange) == 0 {
+		// format whole file
+		buf := bytes.NewBuffer(make([]byte, 0, len(mapper.Content)))
+		format := format.NewFormatter(buf, fileNode)
+		if err := format.Run(); err != nil {
+			return nil, err
+		}
+
+		edits := diff.Bytes(mapper.Content, buf.Bytes())
+		return protocol.EditsFromDiffEdits(mapper, edits)
+	}
+
+	var nodes []ast.Node
+	for _, rng := range maybeRange {
+		start, end, err := mapper.RangeOffsets(rng)
+		if err != nil {
+			return nil, err
+		}
+		if decl := findEnclosingFormattableDecl(mapper, fileNode, start, end); decl != nil {
+			nodes = append(nodes, decl)
+			continue
+		}
+		// the range is not contained within a single declaration; format all
+		// top-level declarations it intersects
+		for _, decl := range fileNode.Decls {
+			node := decl.Unwrap()
+			if !isFormattableDecl(node) {
+				continue
+			}
+			info := fileNode.NodeInfo(node)
+			if info.Start().Offset < end && endOffset(mapper, info) > start {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return formatNodes(mapper, fileNode, nodes)
+}
+
+// FormatOnType formats the declaration enclosing a ';' or '}' character that
+// was just typed at the given position.
+func (c *Cache) FormatOnType(doc protocol.TextDocumentIdentifier, pos protocol.Position, ch string) ([]protocol.TextEdit, error) {
+	if ch != ";" && ch != "}" {
+		return nil, nil
+	}
+	mapper, fileNode, err := c.findFormattableAST(doc.URI)
+	if err != nil || fileNode == nil {
+		return nil, err
+	}
+	offset, err := mapper.PositionOffset(pos)
+	if err != nil {
+		return nil, err
+	}
+	// the position is immediately after the typed character
+	offset--
+	if offset < 0 || offset >= len(mapper.Content) || string(mapper.Content[offset]) != ch {
+		return nil, nil
+	}
+	var node ast.Node
+	if decl := findEnclosingFormattableDecl(mapper, fileNode, offset, offset+1); decl != nil {
+		node = decl
+	} else {
+		// top-level statements (imports, options, etc.) are formatted on their own
+		for _, decl := range fileNode.Decls {
+			start, end := nodeOffsetsWithComments(mapper, fileNode, decl.Unwrap())
+			if start <= offset && offset < end {
+				node = decl.Unwrap()
+				break
+			}
+		}
+	}
+	if node == nil {
+		return nil, nil
+	}
+	return formatNodes(mapper, fileNode, []ast.Node{node})
+}
+
+// findFormattableAST returns the mapper and AST for the given document, or a
+// nil AST if the document should not be formatted.
+func (c *Cache) findFormattableAST(uri protocol.DocumentURI) (*protocol.Mapper, *ast.FileNode, error) {
 	// check if the file has any parse errors; if it does, don't try to format
 	// the document as we will end up erasing anything the user has typed
 	// since the last time the document was successfully parsed.
-	if ok, err := c.LatestDocumentContentsWellFormed(doc.URI, true); err != nil {
-		return nil, err
+	if ok, err := c.LatestDocumentContentsWellFormed(uri, true); err != nil {
+		return nil, nil, err
 	} else if !ok {
-		return nil, nil
+		return nil, nil, nil
 	}
-	mapper, err := c.GetMapper(doc.URI)
+	mapper, err := c.GetMapper(uri)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	res, err := c.FindParseResultByURI(doc.URI)
+	res, err := c.FindParseResultByURI(uri)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	resAst := res.AST()
 	if resAst == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 	if _, ok := resAst.Pragma(PragmaNoFormat); ok {
-		return nil, nil
+		return nil, nil, nil
 	}
-	// format whole file
-	buf := bytes.NewBuffer(make([]byte, 0, len(mapper.Content)))
-	format := format.NewFormatter(buf, res.AST())
-	if err := format.Run(); err != nil {
-		return nil, err
+	return mapper, resAst, nil
+}
+
+func isFormattableDecl(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.MessageNode, *ast.EnumNode, *ast.ServiceNode, *ast.ExtendNode:
+		return true
+	}
+	return false
+}
+
+// findEnclosingFormattableDecl returns the narrowest message, enum, service,
+// or extend declaration containing the range [start, end), or nil if there is
+// no such declaration.
+func findEnclosingFormattableDecl(mapper *protocol.Mapper, fileNode *ast.FileNode, start, end int) ast.Node {
+	var narrowest ast.Node
+	ast.Inspect(fileNode, func(node ast.Node) bool {
+		if _, ok := node.(*ast.FileNode); ok {
+			return true
+		}
+		info := fileNode.NodeInfo(node)
+		if info.Start().Offset > start || endOffset(mapper, info) < end {
+			return false
+		}
+		if isFormattableDecl(node) {
+			narrowest = node
+		}
+		return true
+	})
+	return narrowest
+}
+
+// formatNodes replaces the text of each node (including its comments) with its
+// formatted representation, and returns the resulting edits.
+func formatNodes(mapper *protocol.Mapper, fileNode *ast.FileNode, nodes []ast.Node) ([]protocol.TextEdit, error) {
+	type replacement struct {
+		start, end int
+		text       string
 	}
+	var replacements []replacement
+	for _, node := range nodes {
+		start, end := nodeOffsetsWithComments(mapper, fileNode, node)
+		text, err := format.PrintNode(fileNode, node)
+		if err != nil {
+			return nil, err
+		}
+		col := start - bytes.LastIndexByte(mapper.Content[:start], '\n') - 1
+		replacements = append(replacements, replacement{
+			start: start,
+			end:   end,
+			text:  indentTextHanging(text, col),
+		})
+	}
+	slices.SortFunc(replacements, func(a, b replacement) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		// sort wider replacements first so that nested ones can be dropped
+		return b.end - a.end
+	})
+
+	var buf bytes.Buffer
+	buf.Grow(len(mapper.Content))
+	offset := 0
+	for _, r := range replacements {
+		if r.start < offset {
+			// contained within a previous replacement
+			continue
+		}
+		buf.Write(mapper.Content[offset:r.start])
+		buf.WriteString(r.text)
+		offset = r.end
+	}
+	if offset > len(mapper.Content) {
+		return nil, fmt.Errorf("bug: formatted node extends past the end of the document")
+	}
+	buf.Write(mapper.Content[offset:])
 
 	edits := diff.Bytes(mapper.Content, buf.Bytes())
 	return protocol.EditsFromDiffEdits(mapper, edits)
 }
+
+// nodeOffsetsWithComments returns the start and end offsets of the node,
+// extended to include its trailing semicolon (if any) and its leading and
+// trailing comments.
+func nodeOffsetsWithComments(mapper *protocol.Mapper, fileNode *ast.FileNode, node ast.Node) (start, end int) {
+	info := fileNode.NodeInfo(node)
+	start, end = info.Start().Offset, endOffset(mapper, info)
+	if leading := info.LeadingComments(); leading.Len() > 0 {
+		start = min(start, leading.Index(0).Start().Offset)
+	}
+	if n, ok := node.(interface{ GetSemicolon() *ast.RuneNode }); ok {
+		if semicolon := n.GetSemicolon(); semicolon != nil && !semicolon.GetVirtual() {
+			info = fileNode.NodeInfo(semicolon)
+			end = max(end, endOffset(mapper, info))
+		}
+	}
+	if trailing := info.TrailingComments(); trailing.Len() > 0 {
+		end = max(end, trailing.Index(trailing.Len()-1).End().Offset+1)
+	}
+	return
+}
+
+// endOffset returns the offset immediately following the last character of
+// the node. The offset of the node's end position refers to its last character
+// (or the node's position, for zero-length tokens), so it is computed from the
+// exclusive line and column instead.
+func endOffset(mapper *protocol.Mapper, info ast.NodeInfo) int {
+	if offset, err := mapper.PositionOffset(toPosition(info.End())); err == nil {
+		return offset
+	}
+	return info.End().Offset + 1
+}
@@ -0,0 +1,133 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateConfigFilename is the name of the file in the root of a workspace
+// which configures code generation.
+const GenerateConfigFilename = "protols.yaml"
+
+// GenerateConfig lists the code generators to run for a workspace. It is read
+// from the "generate" section of a protols.yaml file, for example:
+//
+//	generate:
+//	  - name: go
+//	  - name: go-grpc
+//	    out: gen/go
+//	  - plugin: protoc-gen-validate
+//	    opt: [lang=go, paths=source_relative]
+//	    out: gen/go
+type GenerateConfig struct {
+	Generators []GeneratorConfig `yaml:"generate"`
+}
+
+// GeneratorConfig configures a single code generator. Exactly one of Name or
+// Plugin must be set.
+type GeneratorConfig struct {
+	// Name of a built-in generator, e.g. "go" or "go-grpc".
+	Name string `yaml:"name"`
+	// Command to run an external protoc plugin, either as a single executable
+	// name or path, or as a list of arguments.
+	Plugin yamlStringList `yaml:"plugin"`
+	// Parameter passed to the generator, as with protoc's --<name>_opt flag.
+	// Multiple options given as a list are joined with commas.
+	Opt yamlStringList `yaml:"opt"`
+	// Output directory, relative to the workspace root. If empty, generated
+	// files are written alongside the sources they were generated from.
+	Out string `yaml:"out"`
+}
+
+// DisplayName returns the name of the built-in generator, or the base name
+// of the plugin executable.
+func (g GeneratorConfig) DisplayName() string {
+	if g.Name != "" {
+		return g.Name
+	}
+	if len(g.Plugin) > 0 {
+		return filepath.Base(g.Plugin[0])
+	}
+	return ""
+}
+
+// Parameter returns the options joined with commas.
+func (g GeneratorConfig) Parameter() string {
+	return strings.Join(g.Opt, ",")
+}
+
+// yamlStringList is a list of strings which may also be written as a single
+// string in yaml.
+type yamlStringList []string
+
+func (l *yamlStringList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var s string
+		if err := value.Decode(&s); err != nil {
+			return err
+		}
+		*l = yamlStringList{s}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// LoadGenerateConfig reads the protols.yaml file in the root of the workspace
+// at dir. If the file does not exist, or it has no "generate" section, a nil
+// config is returned.
+func LoadGenerateConfig(dir string) (*GenerateConfig, error) {
+	filename := filepath.Join(dir, GenerateConfigFilename)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var doc struct {
+		Generate *[]GeneratorConfig `yaml:"generate"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if doc.Generate == nil {
+		return nil, nil
+	}
+	config := &GenerateConfig{Generators: *doc.Generate}
+	for i, g := range config.Generators {
+		if err := g.validate(); err != nil {
+			return nil, fmt.Errorf("%s: generate[%d]: %w", filename, i, err)
+		}
+	}
+	return config, nil
+}
+
+func (g GeneratorConfig) validate() error {
+	switch {
+	case g.Name == "" && len(g.Plugin) == 0:
+		return errors.New("one of name or plugin is required")
+	case g.Name != "" && len(g.Plugin) != 0:
+		return errors.New("name and plugin are mutually exclusive")
+	case len(g.Plugin) != 0 && strings.TrimSpace(g.Plugin[0]) == "":
+		return errors.New("plugin command is empty")
+	case g.Out != "" && !filepath.IsLocal(filepath.FromSlash(g.Out)):
+		return fmt.Errorf("output directory %q is not within the workspace", g.Out)
+	}
+	return nil
+}
+
+// GenerateConfig reads the code generation config for the workspace. See
+// LoadGenerateConfig.
+func (c *Cache) GenerateConfig() (*GenerateConfig, error) {
+	return LoadGenerateConfig(c.compiler.workdir)
+}
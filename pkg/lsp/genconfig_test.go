@@ -0,0 +1,65 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadGenerateConfig(t *testing.T) {
+	dir := t.TempDir()
+	config, err := LoadGenerateConfig(dir)
+	require.NoError(t, err)
+	require.Nil(t, config)
+
+	writeFiles(t, dir, map[string]string{
+		"protols.yaml": `
+generate:
+  - name: go
+  - name: go-grpc
+    opt: paths=source_relative
+  - plugin: [protoc-gen-validate, --verbose]
+    opt: [lang=go, paths=source_relative]
+    out: gen/go
+`,
+	})
+	config, err = LoadGenerateConfig(dir)
+	require.NoError(t, err)
+	require.Equal(t, &GenerateConfig{
+		Generators: []GeneratorConfig{
+			{Name: "go"},
+			{Name: "go-grpc", Opt: yamlStringList{"paths=source_relative"}},
+			{
+				Plugin: yamlStringList{"protoc-gen-validate", "--verbose"},
+				Opt:    yamlStringList{"lang=go", "paths=source_relative"},
+				Out:    "gen/go",
+			},
+		},
+	}, config)
+	require.Equal(t, "protoc-gen-validate", config.Generators[2].DisplayName())
+	require.Equal(t, "lang=go,paths=source_relative", config.Generators[2].Parameter())
+
+	for content, wantErr := range map[string]string{
+		"other: {}":            "",
+		"generate: []":         "",
+		"generate: [{opt: x}]": "one of name or plugin is required",
+		"generate: [{name: go, plugin: protoc-gen-x}]": "mutually exclusive",
+		"generate: [{name: go, out: ../gen}]":          "not within the workspace",
+		"generate: [{plugin: ['']}]":                   "plugin command is empty",
+		"generate: {}":                                 "cannot unmarshal",
+	} {
+		writeFiles(t, dir, map[string]string{"protols.yaml": content})
+		config, err := LoadGenerateConfig(dir)
+		if wantErr != "" {
+			require.ErrorContains(t, err, wantErr, content)
+			continue
+		}
+		require.NoError(t, err, content)
+		if content == "generate: []" {
+			require.NotNil(t, config)
+			require.Empty(t, config.Generators)
+		} else {
+			require.Nil(t, config)
+		}
+	}
+}
@@ -73,17 +73,7 @@ func (c *Cache) FindGeneratedDefinition(ctx context.Context, params protocol.Tex
 			}
 		}
 	case protoreflect.EnumValueDescriptor:
-		if parentEnum, ok := desc.Parent().(protoreflect.EnumDescriptor); ok {
-			// names of enum values are different for top-level enums and enums
-			// nested in messages.
-			// see newEnumValue() in protobuf-go/compiler/protogen/protogen.go
-			var identPrefix string
-			switch container := parentEnum.Parent().(type) {
-			case protoreflect.MessageDescriptor:
-				identPrefix = GoIdent(container)
-			case protoreflect.FileDescriptor:
-				identPrefix = GoIdent(parentEnum)
-			}
+		if identPrefix, ok := goEnumValueIdentPrefix(desc, GoIdent); ok {
 			for _, gen := range genFiles {
 				spec := lookupValueSpec(gen, desc, identPrefix+"_%s")
 				if spec != nil {
@@ -122,6 +112,105 @@ func (c *Cache) FindGeneratedDefinition(ctx context.Context, params protocol.Tex
 	return locations, nil
 }
 
+// goEnumValueIdentPrefix returns the prefix of the generated Go identifier for
+// an enum value. Names of enum values are different for top-level enums and
+// enums nested in messages; see newEnumValue() in
+// protobuf-go/compiler/protogen/protogen.go
+func goEnumValueIdentPrefix(desc protoreflect.EnumValueDescriptor, goIdent func(protoreflect.Descriptor) string) (string, bool) {
+	parentEnum, ok := desc.Parent().(protoreflect.EnumDescriptor)
+	if !ok {
+		return "", false
+	}
+	switch container := parentEnum.Parent().(type) {
+	case protoreflect.MessageDescriptor:
+		return goIdent(container), true
+	case protoreflect.FileDescriptor:
+		return goIdent(parentEnum), true
+	}
+	return "", false
+}
+
+// GoSymbols returns the generated Go symbols (from protoc-gen-go and
+// protoc-gen-go-grpc) that correspond to the given descriptor.
+func GoSymbols(desc protoreflect.Descriptor) []GoSymbol {
+	return goSymbols(desc, GoIdent)
+}
+
+// goSymbols is like GoSymbols, but uses the given function to compute the Go
+// identifiers of descriptors. The symbols are always returned in the same
+// order for a given descriptor, regardless of the identifiers.
+func goSymbols(desc protoreflect.Descriptor, goIdent func(protoreflect.Descriptor) string) []GoSymbol {
+	switch desc := desc.(type) {
+	case protoreflect.MessageDescriptor:
+		if desc.IsMapEntry() {
+			return nil
+		}
+		return []GoSymbol{{Name: goIdent(desc)}}
+	case protoreflect.EnumDescriptor:
+		return []GoSymbol{{Name: goIdent(desc)}}
+	case protoreflect.EnumValueDescriptor:
+		if identPrefix, ok := goEnumValueIdentPrefix(desc, goIdent); ok {
+			return []GoSymbol{{Name: identPrefix + "_" + goIdent(desc)}}
+		}
+	case protoreflect.FieldDescriptor:
+		if desc.IsExtension() {
+			return []GoSymbol{{Name: "E_" + goIdent(desc)}}
+		}
+		msgIdent, fieldIdent := goIdent(desc.ContainingMessage()), goIdent(desc)
+		symbols := []GoSymbol{
+			{Name: msgIdent, Member: fieldIdent},
+			{Name: msgIdent, Member: "Get" + fieldIdent},
+		}
+		if oneof := desc.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			// fields in a oneof are wrapped in a separate type
+			symbols = append(symbols,
+				GoSymbol{Name: msgIdent + "_" + fieldIdent},
+				GoSymbol{Name: msgIdent + "_" + fieldIdent, Member: fieldIdent},
+			)
+		}
+		return symbols
+	case protoreflect.OneofDescriptor:
+		if desc.IsSynthetic() {
+			return nil
+		}
+		msgIdent, oneofIdent := goIdent(desc.Parent()), goIdent(desc)
+		return []GoSymbol{
+			{Name: msgIdent, Member: oneofIdent},
+			{Name: msgIdent, Member: "Get" + oneofIdent},
+		}
+	case protoreflect.ServiceDescriptor:
+		svcIdent := goIdent(desc)
+		return []GoSymbol{
+			{Name: svcIdent + "Client"},
+			{Name: svcIdent + "Server"},
+			{Name: "New" + svcIdent + "Client"},
+			{Name: "Register" + svcIdent + "Server"},
+			{Name: "Unimplemented" + svcIdent + "Server"},
+			{Name: "Unsafe" + svcIdent + "Server"},
+			{Name: svcIdent + "_ServiceDesc"},
+		}
+	case protoreflect.MethodDescriptor:
+		if parentSvc, ok := desc.Parent().(protoreflect.ServiceDescriptor); ok {
+			svcIdent, methodIdent := goIdent(parentSvc), goIdent(desc)
+			symbols := []GoSymbol{
+				{Name: svcIdent + "Client", Member: methodIdent},
+				{Name: svcIdent + "Server", Member: methodIdent},
+				{Name: "Unimplemented" + svcIdent + "Server", Member: methodIdent},
+				{Name: svcIdent + "_" + methodIdent + "_FullMethodName"},
+			}
+			if desc.IsStreamingClient() || desc.IsStreamingServer() {
+				// stream types (or aliases of the generic stream types)
+				symbols = append(symbols,
+					GoSymbol{Name: svcIdent + "_" + methodIdent + "Client"},
+					GoSymbol{Name: svcIdent + "_" + methodIdent + "Server"},
+				)
+			}
+			return symbols
+		}
+	}
+	return nil
+}
+
 func nodeLocation(gen ParsedGoFile, node ast.Node) protocol.Location {
 	return protocol.Location{
 		URI: protocol.URIFromPath(gen.Filename),
@@ -148,16 +237,75 @@ var (
 )
 
 func GoIdent(desc protoreflect.Descriptor) string {
-	switch desc := desc.(type) {
+	return goIdent(desc, desc.Name(), desc.FullName())
+}
+
+func goIdent(desc protoreflect.Descriptor, name protoreflect.Name, fullName protoreflect.FullName) string {
+	switch desc.(type) {
 	case protoreflect.EnumValueDescriptor:
 		// enum values are not camel-cased
-		return strs.GoSanitized(string(desc.Name()))
-	case protoreflect.FieldDescriptor, protoreflect.MethodDescriptor:
-		return strs.GoCamelCase(string(desc.Name()))
+		return strs.GoSanitized(string(name))
+	case protoreflect.FieldDescriptor, protoreflect.OneofDescriptor, protoreflect.MethodDescriptor:
+		return strs.GoCamelCase(string(name))
 	default:
-		name := strs.GoCamelCase(strings.TrimPrefix(string(desc.FullName()), string(desc.ParentFile().Package())+"."))
-		return name
+		return strs.GoCamelCase(strings.TrimPrefix(string(fullName), string(desc.ParentFile().Package())+"."))
+	}
+}
+
+// goRenames returns the generated Go symbols whose identifiers would change if
+// the given descriptor were renamed, mapped to their new identifiers. This
+// includes symbols for nested declarations, such as nested messages, enum
+// values and oneof wrapper types, which are prefixed with the name of their
+// parent.
+func goRenames(desc protoreflect.Descriptor, newName protoreflect.Name) map[GoSymbol]string {
+	oldFqn := desc.FullName()
+	newFqn := oldFqn.Parent().Append(newName)
+	renamedIdent := func(d protoreflect.Descriptor) string {
+		name, fullName := d.Name(), d.FullName()
+		if fullName == oldFqn {
+			name, fullName = newName, newFqn
+		} else if strings.HasPrefix(string(fullName), string(oldFqn)+".") {
+			fullName = newFqn + fullName[len(oldFqn):]
+		}
+		return goIdent(d, name, fullName)
+	}
+
+	renames := map[GoSymbol]string{}
+	var visit func(d protoreflect.Descriptor)
+	visit = func(d protoreflect.Descriptor) {
+		oldSymbols, newSymbols := goSymbols(d, GoIdent), goSymbols(d, renamedIdent)
+		for i, sym := range oldSymbols {
+			if oldIdent, newIdent := sym.Ident(), newSymbols[i].Ident(); oldIdent != newIdent {
+				renames[sym] = newIdent
+			}
+		}
+		switch d := d.(type) {
+		case protoreflect.MessageDescriptor:
+			for i := 0; i < d.Fields().Len(); i++ {
+				visit(d.Fields().Get(i))
+			}
+			for i := 0; i < d.Oneofs().Len(); i++ {
+				visit(d.Oneofs().Get(i))
+			}
+			for i := 0; i < d.Messages().Len(); i++ {
+				visit(d.Messages().Get(i))
+			}
+			for i := 0; i < d.Enums().Len(); i++ {
+				visit(d.Enums().Get(i))
+			}
+		case protoreflect.EnumDescriptor:
+			for i := 0; i < d.Values().Len(); i++ {
+				visit(d.Values().Get(i))
+			}
+		case protoreflect.ServiceDescriptor:
+			// method constants and stream types are prefixed with the service name
+			for i := 0; i < d.Methods().Len(); i++ {
+				visit(d.Methods().Get(i))
+			}
+		}
 	}
+	visit(desc)
+	return renames
 }
 
 func tryResolvePathToGeneratedImport(files []ParsedGoFile, unresolvedPath string, whence protocompile.ImportContext) (string, error) {
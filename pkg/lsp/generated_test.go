@@ -0,0 +1,196 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGoRenames(t *testing.T) {
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("foo/foo.proto"),
+		Package: proto.String("foo"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("display_name"),
+						Number:   proto.Int32(1),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						JsonName: proto.String("displayName"),
+					},
+					{
+						Name:       proto.String("id"),
+						Number:     proto.Int32(2),
+						Type:       descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+						Label:      descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						OneofIndex: proto.Int32(0),
+						JsonName:   proto.String("id"),
+					},
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{Name: proto.String("value")},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{Name: proto.String("Bar")},
+				},
+				EnumType: []*descriptorpb.EnumDescriptorProto{
+					{
+						Name: proto.String("Kind"),
+						Value: []*descriptorpb.EnumValueDescriptorProto{
+							{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+						},
+					},
+				},
+			},
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{
+				Name: proto.String("Color"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("COLOR_UNSPECIFIED"), Number: proto.Int32(0)},
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("FooService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("GetFoo"),
+						InputType:  proto.String(".foo.Foo"),
+						OutputType: proto.String(".foo.Foo"),
+					},
+					{
+						Name:            proto.String("WatchFoo"),
+						InputType:       proto.String(".foo.Foo"),
+						OutputType:      proto.String(".foo.Foo"),
+						ServerStreaming: proto.Bool(true),
+					},
+				},
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	foo := fd.Messages().ByName("Foo")
+	tests := []struct {
+		name    string
+		desc    protoreflect.Descriptor
+		newName protoreflect.Name
+		want    map[GoSymbol]string
+	}{
+		{
+			name:    "message",
+			desc:    foo,
+			newName: "Baz",
+			want: map[GoSymbol]string{
+				{Name: "Foo"}:                  "Baz",
+				{Name: "Foo_Id"}:               "Baz_Id",
+				{Name: "Foo_Bar"}:              "Baz_Bar",
+				{Name: "Foo_Kind"}:             "Baz_Kind",
+				{Name: "Foo_KIND_UNSPECIFIED"}: "Baz_KIND_UNSPECIFIED",
+			},
+		},
+		{
+			name:    "field",
+			desc:    foo.Fields().ByName("display_name"),
+			newName: "title",
+			want: map[GoSymbol]string{
+				{Name: "Foo", Member: "DisplayName"}:    "Title",
+				{Name: "Foo", Member: "GetDisplayName"}: "GetTitle",
+			},
+		},
+		{
+			name:    "oneof field",
+			desc:    foo.Fields().ByName("id"),
+			newName: "uid",
+			want: map[GoSymbol]string{
+				{Name: "Foo", Member: "Id"}:    "Uid",
+				{Name: "Foo", Member: "GetId"}: "GetUid",
+				{Name: "Foo_Id"}:               "Foo_Uid",
+				{Name: "Foo_Id", Member: "Id"}: "Uid",
+			},
+		},
+		{
+			name:    "oneof",
+			desc:    foo.Oneofs().ByName("value"),
+			newName: "choice",
+			want: map[GoSymbol]string{
+				{Name: "Foo", Member: "Value"}:    "Choice",
+				{Name: "Foo", Member: "GetValue"}: "GetChoice",
+			},
+		},
+		{
+			name:    "top-level enum",
+			desc:    fd.Enums().ByName("Color"),
+			newName: "Shade",
+			want: map[GoSymbol]string{
+				{Name: "Color"}:                   "Shade",
+				{Name: "Color_COLOR_UNSPECIFIED"}: "Shade_COLOR_UNSPECIFIED",
+			},
+		},
+		{
+			name:    "enum value",
+			desc:    fd.Enums().ByName("Color").Values().ByName("COLOR_UNSPECIFIED"),
+			newName: "COLOR_NONE",
+			want: map[GoSymbol]string{
+				{Name: "Color_COLOR_UNSPECIFIED"}: "Color_COLOR_NONE",
+			},
+		},
+		{
+			name:    "service",
+			desc:    fd.Services().ByName("FooService"),
+			newName: "BarService",
+			want: map[GoSymbol]string{
+				{Name: "FooServiceClient"}:                   "BarServiceClient",
+				{Name: "FooServiceServer"}:                   "BarServiceServer",
+				{Name: "NewFooServiceClient"}:                "NewBarServiceClient",
+				{Name: "RegisterFooServiceServer"}:           "RegisterBarServiceServer",
+				{Name: "UnimplementedFooServiceServer"}:      "UnimplementedBarServiceServer",
+				{Name: "UnsafeFooServiceServer"}:             "UnsafeBarServiceServer",
+				{Name: "FooService_ServiceDesc"}:             "BarService_ServiceDesc",
+				{Name: "FooService_GetFoo_FullMethodName"}:   "BarService_GetFoo_FullMethodName",
+				{Name: "FooService_WatchFoo_FullMethodName"}: "BarService_WatchFoo_FullMethodName",
+				{Name: "FooService_WatchFooClient"}:          "BarService_WatchFooClient",
+				{Name: "FooService_WatchFooServer"}:          "BarService_WatchFooServer",
+			},
+		},
+		{
+			name:    "method",
+			desc:    fd.Services().ByName("FooService").Methods().ByName("GetFoo"),
+			newName: "FetchFoo",
+			want: map[GoSymbol]string{
+				{Name: "FooServiceClient", Member: "GetFoo"}:              "FetchFoo",
+				{Name: "FooServiceServer", Member: "GetFoo"}:              "FetchFoo",
+				{Name: "UnimplementedFooServiceServer", Member: "GetFoo"}: "FetchFoo",
+				{Name: "FooService_GetFoo_FullMethodName"}:                "FooService_FetchFoo_FullMethodName",
+			},
+		},
+		{
+			name:    "streaming method",
+			desc:    fd.Services().ByName("FooService").Methods().ByName("WatchFoo"),
+			newName: "ObserveFoo",
+			want: map[GoSymbol]string{
+				{Name: "FooServiceClient", Member: "WatchFoo"}:              "ObserveFoo",
+				{Name: "FooServiceServer", Member: "WatchFoo"}:              "ObserveFoo",
+				{Name: "UnimplementedFooServiceServer", Member: "WatchFoo"}: "ObserveFoo",
+				{Name: "FooService_WatchFoo_FullMethodName"}:                "FooService_ObserveFoo_FullMethodName",
+				{Name: "FooService_WatchFooClient"}:                         "FooService_ObserveFooClient",
+				{Name: "FooService_WatchFooServer"}:                         "FooService_ObserveFooServer",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, goRenames(tt.desc, tt.newName))
+		})
+	}
+}
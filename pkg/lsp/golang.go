@@ -2,11 +2,13 @@ package lsp
 
 import (
 	"bytes"
+	"cmp"
 	"compress/gzip"
 	"fmt"
 	goast "go/ast"
 	goparser "go/parser"
 	"go/token"
+	"go/types"
 	"io"
 	"io/fs"
 	"log/slog"
@@ -16,6 +18,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -91,16 +94,18 @@ func (f ParsedGoFile) Position(pos token.Pos) protocol.Position {
 	}
 }
 
-func (s *GoLanguageDriver) FindGeneratedFiles(uri protocol.DocumentURI, fileOpts *descriptorpb.FileOptions, matchSourcePath string) ([]ParsedGoFile, error) {
-	pkgPath := fileOpts.GetGoPackage()
+// goPackagePath returns the import path of the go package that code for the
+// given file would be generated into, and the package name if it differs from
+// the last element of the path.
+func (s *GoLanguageDriver) goPackagePath(uri protocol.DocumentURI, fileOpts *descriptorpb.FileOptions) (pkgPath string, pkgNameAlias string, _ error) {
+	pkgPath = fileOpts.GetGoPackage()
 	if pkgPath == "" && uri.IsFile() {
 		var err error
 		pkgPath, err = s.ImplicitGoPackagePath(uri.Path())
 		if err != nil {
-			return nil, err
+			return "", "", err
 		}
 	}
-	var pkgNameAlias string
 	if strings.Contains(pkgPath, ";") {
 		// path/to/package;alias
 		pkgPath, pkgNameAlias, _ = strings.Cut(pkgPath, ";")
@@ -108,10 +113,18 @@ func (s *GoLanguageDriver) FindGeneratedFiles(uri protocol.DocumentURI, fileOpts
 		// alias only
 		implicitPath, err := s.ImplicitGoPackagePath(uri.Path())
 		if err != nil {
-			return nil, err
+			return "", "", err
 		}
 		pkgPath, pkgNameAlias = implicitPath, pkgPath
 	}
+	return pkgPath, pkgNameAlias, nil
+}
+
+func (s *GoLanguageDriver) FindGeneratedFiles(uri protocol.DocumentURI, fileOpts *descriptorpb.FileOptions, matchSourcePath string) ([]ParsedGoFile, error) {
+	pkgPath, pkgNameAlias, err := s.goPackagePath(uri, fileOpts)
+	if err != nil {
+		return nil, err
+	}
 	mod, dir := s.moduleResolver.FindPackage(pkgPath)
 	if mod == nil {
 		return nil, fmt.Errorf("no package found for %s", pkgPath)
@@ -162,6 +175,111 @@ func (s *GoLanguageDriver) FindGeneratedFiles(uri protocol.DocumentURI, fileOpts
 	return res, nil
 }
 
+// GoCallSite is a method call expression found in a Go source file.
+type GoCallSite struct {
+	File ParsedGoFile
+	Call *goast.CallExpr
+	Sel  *goast.SelectorExpr
+	// Func is the function containing the call, or nil if the call is not
+	// inside a function (e.g. in a package-level variable initializer).
+	Func *goast.FuncDecl
+}
+
+// FindMethodCallSites type-checks the packages in the local module, along
+// with their tests, and returns the calls to a method of a generated client
+// interface, which is looked up in the package that code for the given file
+// would be generated into. Calls within generated files are not included.
+func (s *GoLanguageDriver) FindMethodCallSites(uri protocol.DocumentURI, fileOpts *descriptorpb.FileOptions, clientName, methodName string) ([]GoCallSite, error) {
+	tc, genPkg, err := s.checkGeneratedPackage(uri, fileOpts)
+	if err != nil || genPkg == nil {
+		return nil, err
+	}
+	client := genPkg.pkg.Scope().Lookup(clientName)
+	if client == nil {
+		return nil, nil
+	}
+	method, _, _ := types.LookupFieldOrMethod(client.Type(), true, genPkg.pkg, methodName)
+	if method == nil || !method.Pos().IsValid() {
+		return nil, nil
+	}
+
+	var sites []GoCallSite
+	seen := map[token.Pos]bool{}
+	for _, checked := range tc.checkAll() {
+		for _, f := range checked.files {
+			filename := tc.fset.Position(f.Package).Filename
+			if strings.HasSuffix(filename, ".pb.go") {
+				continue
+			}
+			pf := ParsedGoFile{
+				File:     f,
+				Fset:     tc.fset,
+				Filename: filename,
+			}
+			for _, decl := range f.Decls {
+				fn, _ := decl.(*goast.FuncDecl)
+				goast.Inspect(decl, func(n goast.Node) bool {
+					call, ok := n.(*goast.CallExpr)
+					if !ok {
+						return true
+					}
+					sel, ok := call.Fun.(*goast.SelectorExpr)
+					if !ok || seen[call.Pos()] {
+						return true
+					}
+					// compared by position, since a package checked together with its
+					// tests has its own copy of the method
+					if obj := checked.info.Uses[sel.Sel]; obj != nil && obj.Pos() == method.Pos() {
+						// non-test files are checked more than once if the package has tests
+						seen[call.Pos()] = true
+						sites = append(sites, GoCallSite{
+							File: pf,
+							Call: call,
+							Sel:  sel,
+							Func: fn,
+						})
+					}
+					return true
+				})
+			}
+		}
+	}
+	slices.SortFunc(sites, func(a, b GoCallSite) int {
+		return cmp.Or(
+			strings.Compare(a.File.Filename, b.File.Filename),
+			cmp.Compare(a.Call.Pos(), b.Call.Pos()),
+		)
+	})
+	return sites, nil
+}
+
+// walkLocalModule calls fn for each Go source file in the local module.
+// Vendored code, test data, hidden directories, and nested modules are skipped.
+func (s *GoLanguageDriver) walkLocalModule(fn func(filename string)) error {
+	return filepath.WalkDir(s.localModDir, func(filename string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if filename == s.localModDir {
+				return nil
+			}
+			switch name := d.Name(); {
+			case strings.HasPrefix(name, "."), strings.HasPrefix(name, "_"), name == "vendor", name == "testdata":
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(filename, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(filename, ".go") {
+			fn(filename)
+		}
+		return nil
+	})
+}
+
 type GoModuleImportResults struct {
 	Module       *gocommand.ModuleJSON
 	DirInModule  string
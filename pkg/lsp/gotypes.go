@@ -0,0 +1,386 @@
+package lsp
+
+import (
+	"cmp"
+	"fmt"
+	goast "go/ast"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// GoSymbol identifies a package-level object in a generated Go package, or a
+// field or method of a package-level type if Member is set.
+type GoSymbol struct {
+	Name   string
+	Member string
+}
+
+// Ident returns the identifier used to refer to the symbol.
+func (s GoSymbol) Ident() string {
+	if s.Member != "" {
+		return s.Member
+	}
+	return s.Name
+}
+
+// GoReference is a reference to a generated Go symbol.
+type GoReference struct {
+	Symbol   GoSymbol
+	Location protocol.Location
+}
+
+// FindSymbolReferences type-checks the packages in the local module, along
+// with their tests, and returns all references to the given symbols, which are
+// looked up in the package that code for the given file would be generated
+// into. If a symbol is a method of a generated server interface, the
+// declarations of methods which implement it (and their references) are
+// included as well, as are references to fields which embed a generated type. References within generated files are not
+// included.
+func (s *GoLanguageDriver) FindSymbolReferences(uri protocol.DocumentURI, fileOpts *descriptorpb.FileOptions, symbols []GoSymbol) ([]GoReference, error) {
+	tc, genPkg, err := s.checkGeneratedPackage(uri, fileOpts)
+	if err != nil || genPkg == nil {
+		return nil, err
+	}
+
+	// Objects are matched by the position of their declaration rather than by
+	// identity, since a package checked together with its tests has its own
+	// copies of the objects declared in the package.
+	targets := map[token.Pos]GoSymbol{}
+	var ifaceMethods []goInterfaceMethod
+	for _, sym := range symbols {
+		obj := genPkg.pkg.Scope().Lookup(sym.Name)
+		if obj == nil {
+			continue
+		}
+		if sym.Member != "" {
+			// server interfaces require implementations to embed the generated
+			// Unimplemented<Svc>Server type, so unlike client interfaces, they
+			// won't be matched by unrelated types with methods of the same name
+			if iface, ok := obj.Type().Underlying().(*types.Interface); ok && strings.HasSuffix(sym.Name, "Server") {
+				ifaceMethods = append(ifaceMethods, goInterfaceMethod{iface: iface, symbol: sym})
+			}
+			obj, _, _ = types.LookupFieldOrMethod(obj.Type(), true, genPkg.pkg, sym.Member)
+			if obj == nil {
+				continue
+			}
+		}
+		if obj.Pos().IsValid() {
+			targets[obj.Pos()] = sym
+		}
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	pkgs := tc.checkAll()
+	for _, checked := range pkgs {
+		for _, obj := range checked.info.Defs {
+			switch obj := obj.(type) {
+			case *types.Func:
+				for _, m := range ifaceMethods {
+					if obj.Pos().IsValid() && m.implementedBy(obj) {
+						targets[obj.Pos()] = m.symbol
+					}
+				}
+			case *types.Var:
+				// a field embedding a generated type, such as
+				// Unimplemented<Svc>Server, is named after the type
+				if !obj.Embedded() {
+					continue
+				}
+				typ := obj.Type()
+				if ptr, ok := typ.(*types.Pointer); ok {
+					typ = ptr.Elem()
+				}
+				if named, ok := typ.(*types.Named); ok {
+					if sym, ok := targets[named.Obj().Pos()]; ok && sym.Member == "" {
+						targets[obj.Pos()] = sym
+					}
+				}
+			}
+		}
+	}
+
+	var refs []GoReference
+	seen := map[protocol.Location]bool{}
+	addRef := func(ident *goast.Ident, obj types.Object) {
+		if obj == nil {
+			return
+		}
+		sym, ok := targets[obj.Pos()]
+		if !ok || strings.HasSuffix(tc.fset.Position(ident.Pos()).Filename, ".pb.go") {
+			return
+		}
+		loc, err := tc.location(ident.Pos(), ident.End())
+		if err != nil || seen[loc] {
+			// non-test files are checked more than once if the package has tests
+			return
+		}
+		seen[loc] = true
+		refs = append(refs, GoReference{
+			Symbol:   sym,
+			Location: loc,
+		})
+	}
+	for _, checked := range pkgs {
+		for ident, obj := range checked.info.Uses {
+			addRef(ident, obj)
+		}
+		for ident, obj := range checked.info.Defs {
+			addRef(ident, obj)
+		}
+	}
+	slices.SortFunc(refs, func(a, b GoReference) int {
+		return cmp.Or(
+			strings.Compare(string(a.Location.URI), string(b.Location.URI)),
+			protocol.CompareRange(a.Location.Range, b.Location.Range),
+		)
+	})
+	return refs, nil
+}
+
+// checkGeneratedPackage type-checks the package that code for the given file
+// would be generated into, and returns the type checker used, which can then
+// be used to check the rest of the local module. If the package has no Go
+// files, the returned package is nil.
+func (s *GoLanguageDriver) checkGeneratedPackage(uri protocol.DocumentURI, fileOpts *descriptorpb.FileOptions) (*goTypeChecker, *goCheckedPackage, error) {
+	pkgPath, _, err := s.goPackagePath(uri, fileOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	tc := s.newTypeChecker()
+	if _, dir := s.moduleResolver.FindPackage(pkgPath); dir != "" {
+		tc.dirs[pkgPath] = dir
+	}
+	return tc, tc.check(pkgPath), nil
+}
+
+// goInterfaceMethod is a method of a generated server interface.
+type goInterfaceMethod struct {
+	iface  *types.Interface
+	symbol GoSymbol
+}
+
+// implementedBy reports whether fn is a method with the same name as the
+// interface method, whose receiver type (or a pointer to it) implements the
+// interface.
+func (m goInterfaceMethod) implementedBy(fn *types.Func) bool {
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil || fn.Name() != m.symbol.Member {
+		return false
+	}
+	if types.IsInterface(recv.Type()) {
+		return false
+	}
+	if types.Implements(recv.Type(), m.iface) {
+		return true
+	}
+	if _, ok := recv.Type().(*types.Pointer); !ok {
+		return types.Implements(types.NewPointer(recv.Type()), m.iface)
+	}
+	return false
+}
+
+// goTypeChecker type-checks packages in the local module from source. Imports
+// of packages outside the local module (other than any explicitly added to
+// dirs) are satisfied with empty placeholder packages, and the resulting type
+// errors are ignored; only references to objects in checked packages are
+// needed.
+type goTypeChecker struct {
+	fset    *token.FileSet
+	dirs    map[string]string // import path -> directory
+	parsed  map[string]*goPackageFiles
+	mappers map[string]*protocol.Mapper // filename -> mapper
+	checked map[string]*goCheckedPackage
+	fake    map[string]*types.Package
+}
+
+// goPackageFiles holds the parsed files in a package directory. Files are only
+// parsed once, so that the same positions are used when the package is checked
+// with and without its tests.
+type goPackageFiles struct {
+	files      []*goast.File
+	testFiles  []*goast.File // _test.go files in the package itself
+	xtestFiles []*goast.File // _test.go files in the external test package
+}
+
+type goCheckedPackage struct {
+	pkg   *types.Package
+	info  *types.Info
+	files []*goast.File
+}
+
+func (s *GoLanguageDriver) newTypeChecker() *goTypeChecker {
+	tc := &goTypeChecker{
+		fset:    token.NewFileSet(),
+		dirs:    map[string]string{},
+		parsed:  map[string]*goPackageFiles{},
+		mappers: map[string]*protocol.Mapper{},
+		checked: map[string]*goCheckedPackage{},
+		fake:    map[string]*types.Package{},
+	}
+	s.walkLocalModule(func(filename string) {
+		dir := filepath.Dir(filename)
+		rel, err := filepath.Rel(s.localModDir, dir)
+		if err != nil {
+			return
+		}
+		tc.dirs[path.Join(s.localModName, filepath.ToSlash(rel))] = dir
+	})
+	return tc
+}
+
+// Import implements types.Importer.
+func (tc *goTypeChecker) Import(importPath string) (*types.Package, error) {
+	if checked := tc.check(importPath); checked != nil {
+		return checked.pkg, nil
+	}
+	if pkg, ok := tc.fake[importPath]; ok {
+		return pkg, nil
+	}
+	pkg := types.NewPackage(importPath, path.Base(importPath))
+	pkg.MarkComplete()
+	tc.fake[importPath] = pkg
+	return pkg, nil
+}
+
+// check type-checks the package with the given import path, without its tests.
+func (tc *goTypeChecker) check(importPath string) *goCheckedPackage {
+	if checked, ok := tc.checked[importPath]; ok {
+		// nil while the package is being checked (import cycle)
+		return checked
+	}
+	files := tc.parse(importPath)
+	if files == nil || len(files.files) == 0 {
+		return nil
+	}
+	tc.checked[importPath] = nil
+	checked := tc.checkFiles(importPath, files.files)
+	tc.checked[importPath] = checked
+	return checked
+}
+
+// checkAll type-checks every package in the local module, along with their
+// tests. Since packages with in-package tests are checked both with and
+// without them, non-test files may appear in more than one of the results.
+func (tc *goTypeChecker) checkAll() []*goCheckedPackage {
+	var pkgs []*goCheckedPackage
+	for importPath := range tc.dirs {
+		if checked := tc.check(importPath); checked != nil {
+			pkgs = append(pkgs, checked)
+		}
+		pkgs = append(pkgs, tc.checkTests(importPath)...)
+	}
+	return pkgs
+}
+
+// checkTests type-checks the package with the given import path together with
+// its in-package tests, and its external test package, if either exist.
+func (tc *goTypeChecker) checkTests(importPath string) []*goCheckedPackage {
+	files := tc.parse(importPath)
+	if files == nil {
+		return nil
+	}
+	var checked []*goCheckedPackage
+	if len(files.testFiles) > 0 {
+		checked = append(checked, tc.checkFiles(importPath, slices.Concat(files.files, files.testFiles)))
+	}
+	if len(files.xtestFiles) > 0 {
+		checked = append(checked, tc.checkFiles(importPath+"_test", files.xtestFiles))
+	}
+	return checked
+}
+
+func (tc *goTypeChecker) checkFiles(importPath string, files []*goast.File) *goCheckedPackage {
+	conf := types.Config{
+		Importer:    tc,
+		Error:       func(error) {},
+		FakeImportC: true,
+	}
+	info := &types.Info{
+		Defs: map[*goast.Ident]types.Object{},
+		Uses: map[*goast.Ident]types.Object{},
+	}
+	pkg, _ := conf.Check(importPath, tc.fset, files, info)
+	return &goCheckedPackage{
+		pkg:   pkg,
+		info:  info,
+		files: files,
+	}
+}
+
+// parse parses the files in the directory of the package with the given
+// import path, or returns nil if the package is not in a known directory.
+func (tc *goTypeChecker) parse(importPath string) *goPackageFiles {
+	dir, ok := tc.dirs[importPath]
+	if !ok {
+		return nil
+	}
+	if files, ok := tc.parsed[dir]; ok {
+		return files
+	}
+	entries, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	var files, testFiles []*goast.File
+	for _, filename := range entries {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		f, _ := goparser.ParseFile(tc.fset, filename, content, goparser.SkipObjectResolution)
+		if f == nil {
+			continue
+		}
+		tc.mappers[filename] = protocol.NewMapper(protocol.URIFromPath(filename), content)
+		if strings.HasSuffix(filename, "_test.go") {
+			testFiles = append(testFiles, f)
+		} else {
+			files = append(files, f)
+		}
+	}
+
+	pkgFiles := &goPackageFiles{}
+	var pkgName string
+	if len(files) > 0 {
+		pkgName = files[0].Name.Name
+	} else if len(testFiles) > 0 {
+		pkgName = strings.TrimSuffix(testFiles[0].Name.Name, "_test")
+	}
+	for _, f := range files {
+		if f.Name.Name == pkgName {
+			pkgFiles.files = append(pkgFiles.files, f)
+		}
+	}
+	for _, f := range testFiles {
+		switch f.Name.Name {
+		case pkgName:
+			pkgFiles.testFiles = append(pkgFiles.testFiles, f)
+		case pkgName + "_test":
+			pkgFiles.xtestFiles = append(pkgFiles.xtestFiles, f)
+		}
+	}
+	tc.parsed[dir] = pkgFiles
+	return pkgFiles
+}
+
+// location returns the location of the given range of a parsed file, with
+// UTF-16 character offsets.
+func (tc *goTypeChecker) location(start, end token.Pos) (protocol.Location, error) {
+	tf := tc.fset.File(start)
+	if tf == nil {
+		return protocol.Location{}, fmt.Errorf("no file for position %d", start)
+	}
+	m, ok := tc.mappers[tf.Name()]
+	if !ok {
+		return protocol.Location{}, fmt.Errorf("file %s was not parsed", tf.Name())
+	}
+	return m.PosLocation(tf, start, end)
+}
@@ -0,0 +1,257 @@
+package lsp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFindSymbolReferences(t *testing.T) {
+	files := map[string]string{
+		"go.mod": `module example.com/refs
+
+go 1.23
+`,
+		"foo/foo.pb.go": `// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: foo/foo.proto
+
+package foo
+
+type Foo struct {
+	Name string
+	// Types that are assignable to Value:
+	//
+	//	*Foo_Id
+	Value isFoo_Value
+}
+
+func (x *Foo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type isFoo_Value interface {
+	isFoo_Value()
+}
+
+type Foo_Id struct {
+	Id int64
+}
+
+func (*Foo_Id) isFoo_Value() {}
+
+type Kind int32
+
+const (
+	Kind_KIND_UNSPECIFIED Kind = 0
+)
+`,
+		"foo/foo_grpc.pb.go": `// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// source: foo/foo.proto
+
+package foo
+
+import context "context"
+
+type FooServiceClient interface {
+	Get(ctx context.Context, in *Foo) (*Foo, error)
+}
+
+type FooServiceServer interface {
+	Get(context.Context, *Foo) (*Foo, error)
+	mustEmbedUnimplementedFooServiceServer()
+}
+
+type UnimplementedFooServiceServer struct{}
+
+func (UnimplementedFooServiceServer) Get(context.Context, *Foo) (*Foo, error) {
+	return nil, nil
+}
+func (UnimplementedFooServiceServer) mustEmbedUnimplementedFooServiceServer() {}
+`,
+		"server/server.go": `package server
+
+import (
+	"context"
+
+	"example.com/refs/foo"
+)
+
+type server struct {
+	foo.UnimplementedFooServiceServer
+}
+
+func (s *server) Get(ctx context.Context, in *foo.Foo) (*foo.Foo, error) {
+	return in, nil
+}
+
+func (s *server) Other() {}
+
+type notServer struct{}
+
+func (notServer) Get(ctx context.Context, in *foo.Foo) (*foo.Foo, error) { return in, nil }
+
+func call(s *server) { s.Get(context.Background(), nil) }
+
+func embedded(s *server) any { return s.UnimplementedFooServiceServer }
+`,
+		"client/client.go": `package client
+
+import (
+	"context"
+
+	"example.com/refs/foo"
+)
+
+func Run(ctx context.Context, c foo.FooServiceClient) string {
+	f, _ := c.Get(ctx, &foo.Foo{Name: "x", Value: &foo.Foo_Id{Id: 1}})
+	_ = foo.Kind_KIND_UNSPECIFIED
+	return f.GetName() + f.Name
+}
+`,
+		"client/client_test.go": `package client
+
+import (
+	"testing"
+
+	"example.com/refs/foo"
+)
+
+func TestRun(t *testing.T) {
+	_, _ = "é🙂", foo.Kind_KIND_UNSPECIFIED
+}
+`,
+		"client/x_test.go": `package client_test
+
+import "example.com/refs/foo"
+
+var _ = foo.Foo{}
+`,
+		"foo/foo_test.go": `package foo
+
+var _ = Foo{Name: "y"}
+`,
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0o755))
+		require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+	}
+
+	driver := NewGoLanguageDriver(dir)
+	require.True(t, driver.HasGoModule())
+
+	protoURI := protocol.URIFromPath(filepath.Join(dir, "foo", "foo.proto"))
+	fileOpts := &descriptorpb.FileOptions{GoPackage: proto.String("example.com/refs/foo")}
+	uri := func(name string) protocol.DocumentURI {
+		return protocol.URIFromPath(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+	locIn := func(name string, line, startCol, endCol uint32) protocol.Location {
+		return protocol.Location{
+			URI: uri(name),
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line, Character: startCol},
+				End:   protocol.Position{Line: line, Character: endCol},
+			},
+		}
+	}
+	loc := func(line, startCol, endCol uint32) protocol.Location {
+		return locIn("client/client.go", line, startCol, endCol)
+	}
+
+	tests := []struct {
+		name    string
+		symbols []GoSymbol
+		want    []protocol.Location
+	}{
+		{
+			name:    "message",
+			symbols: []GoSymbol{{Name: "Foo"}},
+			want: []protocol.Location{
+				loc(9, 25, 28),
+				locIn("client/x_test.go", 4, 12, 15),
+				locIn("foo/foo_test.go", 2, 8, 11),
+				locIn("server/server.go", 12, 50, 53),
+				locIn("server/server.go", 12, 61, 64),
+				locIn("server/server.go", 20, 50, 53),
+				locIn("server/server.go", 20, 61, 64),
+			},
+		},
+		{
+			name: "field",
+			symbols: []GoSymbol{
+				{Name: "Foo", Member: "Name"},
+				{Name: "Foo", Member: "GetName"},
+			},
+			want: []protocol.Location{loc(9, 29, 33), loc(11, 10, 17), loc(11, 24, 28), locIn("foo/foo_test.go", 2, 12, 16)},
+		},
+		{
+			name: "oneof field",
+			symbols: []GoSymbol{
+				{Name: "Foo", Member: "Id"},
+				{Name: "Foo", Member: "GetId"},
+				{Name: "Foo_Id", Member: "Id"},
+			},
+			want: []protocol.Location{loc(9, 59, 61)},
+		},
+		{
+			name:    "enum value",
+			symbols: []GoSymbol{{Name: "Kind_KIND_UNSPECIFIED"}},
+			// columns are in UTF-16 code units
+			want: []protocol.Location{loc(10, 9, 30), locIn("client/client_test.go", 9, 19, 40)},
+		},
+		{
+			name:    "client method",
+			symbols: []GoSymbol{{Name: "FooServiceClient", Member: "Get"}},
+			want:    []protocol.Location{loc(9, 11, 14)},
+		},
+		{
+			// implementations of the server interface are renamed along with it
+			name:    "server method",
+			symbols: []GoSymbol{{Name: "FooServiceServer", Member: "Get"}},
+			want:    []protocol.Location{locIn("server/server.go", 12, 17, 20), locIn("server/server.go", 22, 25, 28)},
+		},
+		{
+			name: "service",
+			symbols: []GoSymbol{
+				{Name: "FooServiceClient"},
+				{Name: "FooServiceServer"},
+				{Name: "NewFooServiceClient"},
+				{Name: "RegisterFooServiceServer"},
+				{Name: "UnimplementedFooServiceServer"},
+				{Name: "UnsafeFooServiceServer"},
+				{Name: "FooService_ServiceDesc"},
+			},
+			want: []protocol.Location{
+				loc(8, 36, 52),
+				locIn("server/server.go", 9, 5, 34),
+				locIn("server/server.go", 24, 40, 69),
+			},
+		},
+		{
+			name:    "missing symbol",
+			symbols: []GoSymbol{{Name: "Bar"}},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := driver.FindSymbolReferences(protoURI, fileOpts, tt.symbols)
+			require.NoError(t, err)
+			var got []protocol.Location
+			for _, ref := range refs {
+				require.Contains(t, tt.symbols, ref.Symbol)
+				got = append(got, ref.Location)
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
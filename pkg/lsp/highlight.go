@@ -0,0 +1,170 @@
+package lsp
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+)
+
+// FindDocumentHighlights returns the occurrences of the symbol at the given
+// position within the same document. The definition of the symbol (if it is
+// in the document) is marked as a write, and all other occurrences as reads.
+func (c *Cache) FindDocumentHighlights(params protocol.TextDocumentPositionParams) ([]protocol.DocumentHighlight, error) {
+	desc, _, err := c.FindTypeDescriptorAtLocation(params)
+	if err != nil {
+		return nil, err
+	}
+	if desc == nil {
+		return c.findPackageNameHighlights(params)
+	}
+	c.resultsMu.RLock()
+	defer c.resultsMu.RUnlock()
+	path, err := c.resolver.URIToPath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	linkRes, err := c.findResultOrPartialResultByPathLocked(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var highlights []protocol.DocumentHighlight
+	if parentFile := desc.ParentFile(); parentFile != nil && parentFile.Path() == linkRes.Path() {
+		if def, err := findDefinition(desc, linkRes); err == nil {
+			highlights = append(highlights, protocol.DocumentHighlight{
+				Range: toRange(def.NodeInfo),
+				Kind:  protocol.Write,
+			})
+		}
+	}
+	for ref := range findNodeReferences(desc, linker.Files{linkRes}) {
+		info := referenceNameInfo(ref)
+		if !info.IsValid() {
+			continue
+		}
+		highlights = append(highlights, protocol.DocumentHighlight{
+			Range: toRange(info),
+			Kind:  protocol.Read,
+		})
+	}
+	return sortDocumentHighlights(highlights), nil
+}
+
+// referenceNameInfo returns the span of the name being referred to by a
+// reference, excluding any qualifiers, the parentheses around extension
+// names, and the values of message literal fields.
+func referenceNameInfo(ref ast.NodeReference) ast.NodeInfo {
+	fileNode := ref.NodeInfo.Internal().ParentFile()
+	node := ast.Unwrap(ref.Node)
+	if msgField, ok := node.(*ast.MessageFieldNode); ok && msgField.Name != nil {
+		node = msgField.Name
+	}
+	if rpcType, ok := node.(*ast.RPCTypeNode); ok && rpcType.MessageType != nil {
+		node = rpcType.MessageType.Unwrap()
+	}
+	if fieldRef, ok := node.(*ast.FieldReferenceNode); ok && fieldRef.Name != nil {
+		node = fieldRef.Name.Unwrap()
+	}
+	switch node := node.(type) {
+	case *ast.IdentNode:
+		return fileNode.NodeInfo(node)
+	case *ast.CompoundIdentNode:
+		idents := node.FilterIdents()
+		if len(idents) > 0 {
+			return fileNode.NodeInfo(idents[len(idents)-1])
+		}
+	}
+	return ref.NodeInfo
+}
+
+// findPackageNameHighlights returns highlights for the package name (or the
+// leading segments of the package name) at the given position. The package
+// declaration is marked as a write, and qualified references in the document
+// which begin with the same segments are marked as reads.
+func (c *Cache) findPackageNameHighlights(params protocol.TextDocumentPositionParams) ([]protocol.DocumentHighlight, error) {
+	parseRes, err := c.FindParseResultByURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	fileNode := parseRes.AST()
+	if fileNode == nil {
+		return nil, nil
+	}
+	mapper, err := c.GetMapper(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := mapper.PositionOffset(params.Position)
+	if err != nil {
+		return nil, err
+	}
+	pkgNode, segments := findPackageSegmentsAtOffset(fileNode, offset)
+	if pkgNode == nil {
+		return nil, nil
+	}
+
+	highlightSegments := func(name ast.Node, kind protocol.DocumentHighlightKind) *protocol.DocumentHighlight {
+		var idents []*ast.IdentNode
+		switch name := ast.Unwrap(name).(type) {
+		case *ast.IdentNode:
+			idents = []*ast.IdentNode{name}
+		case *ast.CompoundIdentNode:
+			idents = name.FilterIdents()
+		}
+		if len(idents) < len(segments) {
+			return nil
+		}
+		for i, segment := range segments {
+			if idents[i].Val != segment {
+				return nil
+			}
+		}
+		start := fileNode.NodeInfo(idents[0])
+		end := fileNode.NodeInfo(idents[len(segments)-1])
+		if !start.IsValid() || !end.IsValid() {
+			return nil
+		}
+		return &protocol.DocumentHighlight{
+			Range: protocol.Range{
+				Start: toPosition(start.Start()),
+				End:   toPosition(end.End()),
+			},
+			Kind: kind,
+		}
+	}
+
+	var highlights []protocol.DocumentHighlight
+	if h := highlightSegments(pkgNode.Name, protocol.Write); h != nil {
+		highlights = append(highlights, *h)
+	}
+	ast.Inspect(fileNode, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.PackageNode, *ast.ImportNode:
+			return false
+		case *ast.CompoundIdentNode:
+			if h := highlightSegments(node, protocol.Read); h != nil {
+				highlights = append(highlights, *h)
+			}
+			return false
+		}
+		return true
+	})
+	return sortDocumentHighlights(highlights), nil
+}
+
+// sortDocumentHighlights sorts highlights by range and removes duplicates,
+// preferring writes over reads for the same range.
+func sortDocumentHighlights(highlights []protocol.DocumentHighlight) []protocol.DocumentHighlight {
+	slices.SortStableFunc(highlights, func(a, b protocol.DocumentHighlight) int {
+		return cmp.Or(
+			protocol.CompareRange(a.Range, b.Range),
+			-cmp.Compare(a.Kind, b.Kind),
+		)
+	})
+	return slices.CompactFunc(highlights, func(a, b protocol.DocumentHighlight) bool {
+		return a.Range == b.Range
+	})
+}
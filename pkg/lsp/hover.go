@@ -51,10 +51,14 @@ func (c *Cache) ComputeHover(params protocol.TextDocumentPositionParams) (*proto
 	if err != nil {
 		return nil, err
 	}
+	value := fmt.Sprintf("```protobuf\n%s\n```\n", text)
+	if features := resolvedFeaturesMarkdown(desc); features != "" {
+		value += "\n" + features
+	}
 	return &protocol.Hover{
 		Contents: protocol.MarkupContent{
 			Kind:  protocol.Markdown,
-			Value: fmt.Sprintf("```protobuf\n%s\n```\n", text),
+			Value: value,
 		},
 		Range: rng,
 	}, nil
@@ -0,0 +1,130 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/reporter"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+)
+
+// SetIncludePaths sets the directories to search for imported files, in order
+// of precedence, similar to the --proto_path flags accepted by protoc. Relative
+// paths are resolved against the workspace root. Existing path mappings are not
+// updated, so include paths should be set before any files are loaded.
+func (r *Resolver) SetIncludePaths(paths []string) {
+	workdir := protocol.DocumentURI(r.folder.URI).Path()
+	includePaths := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(workdir, p)
+		}
+		p = filepath.Clean(p)
+		if !slices.Contains(includePaths, p) {
+			includePaths = append(includePaths, p)
+		}
+	}
+
+	r.pathsMu.Lock()
+	defer r.pathsMu.Unlock()
+	r.includePaths = includePaths
+	clear(r.shadowedImports)
+}
+
+// IncludePaths returns the absolute include paths, in order of precedence.
+func (r *Resolver) IncludePaths() []string {
+	r.pathsMu.RLock()
+	defer r.pathsMu.RUnlock()
+	return slices.Clone(r.includePaths)
+}
+
+// includeRelativePathLocked returns the import path of the given file relative
+// to the first include path containing it.
+func (r *Resolver) includeRelativePathLocked(filename string) (string, bool) {
+	for _, dir := range r.includePaths {
+		if !isWithinDir(filename, dir) {
+			continue
+		}
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			continue
+		}
+		return filepath.ToSlash(rel), true
+	}
+	return "", false
+}
+
+// lookupIncludePathsLocked returns the files matching the given import path
+// within each include path, in order of precedence. If more than one file is
+// found, the import path is recorded as shadowed.
+func (r *Resolver) lookupIncludePathsLocked(path string) []string {
+	if len(r.includePaths) == 0 || !filepath.IsLocal(filepath.FromSlash(path)) {
+		return nil
+	}
+	var matches []string
+	for _, dir := range r.includePaths {
+		candidate := filepath.Join(dir, filepath.FromSlash(path))
+		if isRegularFile(candidate) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) > 1 {
+		r.shadowedImports[path] = matches
+	} else {
+		delete(r.shadowedImports, path)
+	}
+	return matches
+}
+
+// ShadowedImport returns the files found for the given import path if it
+// resolves under more than one include path. The first file is the one used
+// for the import; the remaining files are shadowed by it.
+func (r *Resolver) ShadowedImport(path string) ([]string, bool) {
+	r.pathsMu.RLock()
+	defer r.pathsMu.RUnlock()
+	matches, ok := r.shadowedImports[path]
+	return slices.Clone(matches), ok
+}
+
+// reportShadowedImports adds a warning to each import statement in the given
+// results that refers to a path found under more than one include path.
+func (c *Cache) reportShadowedImports(results ...linker.Result) {
+	if len(c.resolver.IncludePaths()) == 0 {
+		return
+	}
+	workdir := protocol.DocumentURI(c.workspace.URI).Path()
+	relative := func(filename string) string {
+		if rel, err := filepath.Rel(workdir, filename); err == nil && filepath.IsLocal(rel) {
+			return rel
+		}
+		return filename
+	}
+	for _, res := range results {
+		resAst := res.AST()
+		if resAst == nil {
+			continue
+		}
+		for _, decl := range resAst.Decls {
+			imp := decl.GetImport()
+			if imp == nil || imp.IsIncomplete() {
+				continue
+			}
+			matches, ok := c.resolver.ShadowedImport(imp.Name.AsString())
+			if !ok {
+				continue
+			}
+			shadowed := make([]string, len(matches)-1)
+			for i, filename := range matches[1:] {
+				shadowed[i] = relative(filename)
+			}
+			c.diagHandler.HandleWarning(reporter.Error(resAst.NodeInfo(imp.Name),
+				fmt.Errorf("import %q resolves to %s, which shadows %s", imp.Name.AsString(), relative(matches[0]), strings.Join(shadowed, ", "))))
+		}
+	}
+}
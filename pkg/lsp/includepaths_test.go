@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolverIncludePaths(t *testing.T) {
+	dir := t.TempDir()
+	external := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a/foo/foo.proto": `// a`,
+		"b/foo/foo.proto": `// b`,
+		"b/bar/bar.proto": `// b`,
+	})
+	writeFiles(t, external, map[string]string{
+		"baz/baz.proto": `// external`,
+	})
+
+	r := NewResolver(protocol.WorkspaceFolder{URI: string(protocol.URIFromPath(dir))})
+	r.SetIncludePaths([]string{"a", "b", "", "./a", external})
+	require.Equal(t, []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "b"),
+		external,
+	}, r.IncludePaths())
+
+	for path, want := range map[string]string{
+		"foo/foo.proto": "// a",
+		"bar/bar.proto": "// b",
+		"baz/baz.proto": "// external",
+	} {
+		res, err := r.FindFileByPath(protocompile.UnresolvedPath(path), nil)
+		require.NoError(t, err, path)
+		require.EqualValues(t, path, res.ResolvedPath)
+		content, err := io.ReadAll(res.Source)
+		require.NoError(t, err)
+		require.Equal(t, want, string(content))
+	}
+
+	matches, ok := r.ShadowedImport("foo/foo.proto")
+	require.True(t, ok)
+	require.Equal(t, []string{
+		filepath.Join(dir, "a/foo/foo.proto"),
+		filepath.Join(dir, "b/foo/foo.proto"),
+	}, matches)
+	_, ok = r.ShadowedImport("bar/bar.proto")
+	require.False(t, ok)
+
+	uri, err := r.PathToURI("foo/foo.proto")
+	require.NoError(t, err)
+	require.Equal(t, protocol.URIFromPath(filepath.Join(dir, "a/foo/foo.proto")), uri)
+
+	_, err = r.FindFileByPath("../foo/foo.proto", nil)
+	require.Error(t, err)
+}
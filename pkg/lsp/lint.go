@@ -0,0 +1,238 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kralicky/protocompile"
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// A LintRule checks a file for style problems after it has been compiled,
+// and reports them as diagnostics. Rules are disabled by default, and can be
+// enabled for a file with the debug pragma:
+//
+//	//protols:debug lint=all nolint=service-comments
+//
+// where "lint" enables a rule (or all rules), and "nolint" disables one.
+type LintRule struct {
+	// Name is used to refer to the rule in the debug pragma, and is reported
+	// as the code of the diagnostics it reports.
+	Name string
+	// Doc is a short description of what the rule checks.
+	Doc string
+	// Run checks the file in the pass.
+	Run func(pass *LintPass)
+}
+
+// A LintPass provides a lint rule with the file being checked, and a way to
+// report diagnostics for it.
+type LintPass struct {
+	Rule   *LintRule
+	Result linker.Result
+
+	// whether the file is imported by other files, which may refer to its
+	// declarations
+	imported bool
+	report   func(*ProtoDiagnostic)
+}
+
+var (
+	lintRulesMu sync.RWMutex
+	lintRules   = map[string]*LintRule{}
+)
+
+// RegisterLintRule adds a rule which can be enabled in the debug pragma. It
+// panics if a rule with the same name has already been registered.
+func RegisterLintRule(rule *LintRule) {
+	lintRulesMu.Lock()
+	defer lintRulesMu.Unlock()
+	if rule.Name == "" || rule.Name == LintAll {
+		panic(fmt.Sprintf("invalid lint rule name %q", rule.Name))
+	}
+	if _, ok := lintRules[rule.Name]; ok {
+		panic(fmt.Sprintf("lint rule %q is already registered", rule.Name))
+	}
+	lintRules[rule.Name] = rule
+}
+
+// LintRules returns all registered lint rules, sorted by name.
+func LintRules() []*LintRule {
+	lintRulesMu.RLock()
+	defer lintRulesMu.RUnlock()
+	rules := make([]*LintRule, 0, len(lintRules))
+	for _, rule := range lintRules {
+		rules = append(rules, rule)
+	}
+	slices.SortFunc(rules, func(a, b *LintRule) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return rules
+}
+
+// enabledLintRules returns the rules enabled by the debug pragma.
+func enabledLintRules(p Pragmas) []*LintRule {
+	dbg, ok := p.Lookup(PragmaDebug)
+	if !ok {
+		return nil
+	}
+	enabled := map[string]bool{}
+	all := false
+	for _, v := range strings.Fields(dbg) {
+		k, v, ok := strings.Cut(v, "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case PragmaDebugLint:
+			if v == LintAll {
+				all = true
+			} else {
+				enabled[v] = true
+			}
+		case PragmaDebugNolint:
+			if v == LintAll {
+				return nil
+			}
+			enabled[v] = false
+		}
+	}
+	var rules []*LintRule
+	for _, rule := range LintRules() {
+		if on, ok := enabled[rule.Name]; (ok && on) || (!ok && all) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func (c *Cache) runLintRules(results ...linker.Result) {
+	imported := map[string]bool{}
+	for _, res := range c.results {
+		imports := res.Imports()
+		for i, l := 0, imports.Len(); i < l; i++ {
+			imported[imports.Get(i).Path()] = true
+		}
+	}
+	for _, res := range results {
+		if res.AST() == nil {
+			continue
+		}
+		p, ok := c.FindPragmasByPath(protocompile.ResolvedPath(res.Path()))
+		if !ok {
+			continue
+		}
+		for _, rule := range enabledLintRules(p) {
+			pass := &LintPass{
+				Rule:     rule,
+				Result:   res,
+				imported: imported[res.Path()],
+				report:   c.diagHandler.HandleDiagnostic,
+			}
+			pass.run()
+		}
+	}
+}
+
+func (p *LintPass) run() {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.With(
+				"rule", p.Rule.Name,
+				"path", p.Result.Path(),
+				"panic", r,
+			).Error("lint rule panicked")
+		}
+	}()
+	p.Rule.Run(p)
+}
+
+// Report reports a diagnostic for the given node, along with any number of
+// quick fixes.
+func (p *LintPass) Report(node ast.Node, message string, fixes ...CodeAction) {
+	info := p.Result.AST().NodeInfo(node)
+	if !info.IsValid() {
+		return
+	}
+	if fixes == nil {
+		fixes = []CodeAction{}
+	}
+	p.report(&ProtoDiagnostic{
+		Path:        info.Start().Filename,
+		Version:     p.Result.AST().Version(),
+		Range:       info,
+		Severity:    protocol.SeverityWarning,
+		Error:       errors.New(message),
+		Tags:        []protocol.DiagnosticTag{},
+		CodeActions: fixes,
+		LintRule:    p.Rule.Name,
+	})
+}
+
+// Reportf is like Report, but formats the message.
+func (p *LintPass) Reportf(node ast.Node, format string, args ...any) {
+	p.Report(node, fmt.Sprintf(format, args...))
+}
+
+// ReplaceFix returns a quick fix which replaces the text of each of the given
+// nodes with newText.
+func (p *LintPass) ReplaceFix(title string, newText string, nodes ...ast.Node) CodeAction {
+	fix := CodeAction{
+		Title: title,
+		Kind:  protocol.QuickFix,
+		Path:  p.Result.AST().Name(),
+	}
+	for _, node := range nodes {
+		fix.Edits = append(fix.Edits, protocol.TextEdit{
+			Range:   toRange(p.Result.AST().NodeInfo(node)),
+			NewText: newText,
+		})
+	}
+	return fix
+}
+
+// InsertFix returns a quick fix which inserts text at the start of the given
+// node.
+func (p *LintPass) InsertFix(title string, text string, before ast.Node) CodeAction {
+	pos := toPosition(p.Result.AST().NodeInfo(before).Start())
+	return CodeAction{
+		Title: title,
+		Kind:  protocol.QuickFix,
+		Path:  p.Result.AST().Name(),
+		Edits: []protocol.TextEdit{
+			{
+				Range:   protocol.Range{Start: pos, End: pos},
+				NewText: text,
+			},
+		},
+	}
+}
+
+// RenameFix returns a quick fix which renames a declaration and the references
+// to it within the file. If the file is imported by other files, which could
+// also refer to the descriptor, or if the descriptor is referenced in a way
+// that can't be renamed by replacing the referencing identifier, such as by a
+// qualified name, ok is false.
+func (p *LintPass) RenameFix(desc protoreflect.Descriptor, name ast.Node, newName string) (fix CodeAction, ok bool) {
+	if p.imported {
+		return CodeAction{}, false
+	}
+	nodes := []ast.Node{name}
+	for _, ref := range p.Result.FindReferences(desc) {
+		if ref.NodeInfo.Start().Filename != p.Result.AST().Name() || ref.Node == name {
+			continue
+		}
+		if ref.NodeInfo.RawText() != string(desc.Name()) {
+			return CodeAction{}, false
+		}
+		nodes = append(nodes, ref.Node)
+	}
+	return p.ReplaceFix(fmt.Sprintf("Rename %s to %s", desc.Name(), newName), newName, nodes...), true
+}
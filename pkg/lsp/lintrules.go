@@ -0,0 +1,262 @@
+package lsp
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func init() {
+	RegisterLintRule(messageCaseRule)
+	RegisterLintRule(fieldCaseRule)
+	RegisterLintRule(enumValueCaseRule)
+	RegisterLintRule(enumZeroUnspecifiedRule)
+	RegisterLintRule(packageDirectoryRule)
+	RegisterLintRule(serviceCommentsRule)
+}
+
+var messageCaseRule = &LintRule{
+	Name: "message-case",
+	Doc:  "message names should be PascalCase",
+	Run: func(pass *LintPass) {
+		rangeDescriptorProtos(pass, func(_ protoreflect.FullName, d proto.Message) {
+			msg, ok := d.(*descriptorpb.DescriptorProto)
+			if !ok || msg.GetOptions().GetMapEntry() {
+				return
+			}
+			name := pass.Result.MessageNode(msg).GetName()
+			if name == nil || isPascalCase(msg.GetName()) {
+				return
+			}
+			// messages can be referenced from other files, so there is no quick
+			// fix; the rename action updates the whole workspace.
+			pass.Reportf(name, "message name %q should be PascalCase, such as %q", msg.GetName(), toPascalCase(msg.GetName()))
+		})
+	},
+}
+
+var fieldCaseRule = &LintRule{
+	Name: "field-case",
+	Doc:  "field names should be lower_snake_case",
+	Run: func(pass *LintPass) {
+		rangeDescriptorProtos(pass, func(fqn protoreflect.FullName, d proto.Message) {
+			field, ok := d.(*descriptorpb.FieldDescriptorProto)
+			if !ok || field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_GROUP {
+				return
+			}
+			name := pass.Result.FieldNode(field).GetName()
+			if name == nil || isLowerSnakeCase(field.GetName()) {
+				return
+			}
+			want := toLowerSnakeCase(field.GetName())
+			reportWithRenameFix(pass, fqn, name, want, fmt.Sprintf("field name %q should be lower_snake_case, such as %q", field.GetName(), want))
+		})
+	},
+}
+
+var enumValueCaseRule = &LintRule{
+	Name: "enum-value-case",
+	Doc:  "enum value names should be UPPER_SNAKE_CASE",
+	Run: func(pass *LintPass) {
+		rangeDescriptorProtos(pass, func(fqn protoreflect.FullName, d proto.Message) {
+			value, ok := d.(*descriptorpb.EnumValueDescriptorProto)
+			if !ok || isUpperSnakeCase(value.GetName()) {
+				return
+			}
+			node := pass.Result.EnumValueNode(value)
+			if node == nil || node.Name == nil {
+				return
+			}
+			want := toUpperSnakeCase(value.GetName())
+			reportWithRenameFix(pass, fqn, node.Name, want, fmt.Sprintf("enum value name %q should be UPPER_SNAKE_CASE, such as %q", value.GetName(), want))
+		})
+	},
+}
+
+var enumZeroUnspecifiedRule = &LintRule{
+	Name: "enum-zero-unspecified",
+	Doc:  "the zero value of an enum should be named <ENUM_NAME>_UNSPECIFIED",
+	Run: func(pass *LintPass) {
+		rangeDescriptorProtos(pass, func(fqn protoreflect.FullName, d proto.Message) {
+			enum, ok := d.(*descriptorpb.EnumDescriptorProto)
+			if !ok {
+				return
+			}
+			node := pass.Result.EnumNode(enum)
+			if node == nil || node.Name == nil || len(enum.GetValue()) == 0 {
+				return
+			}
+			want := toUpperSnakeCase(enum.GetName()) + "_UNSPECIFIED"
+			for _, value := range enum.GetValue() {
+				if value.GetNumber() != 0 {
+					continue
+				}
+				if value.GetName() == want {
+					return
+				}
+				valueNode := pass.Result.EnumValueNode(value)
+				if valueNode == nil || valueNode.Name == nil {
+					return
+				}
+				reportWithRenameFix(pass, fqn.Parent().Append(protoreflect.Name(value.GetName())), valueNode.Name, want,
+					fmt.Sprintf("zero value of enum %s should be named %q", enum.GetName(), want))
+				return
+			}
+			// no zero value; suggest adding one before the first value
+			first := pass.Result.EnumValueNode(enum.GetValue()[0])
+			if first == nil {
+				pass.Reportf(node.Name, "enum %s should have a zero value named %q", enum.GetName(), want)
+				return
+			}
+			indent := pass.Result.AST().NodeInfo(first).LeadingWhitespace()
+			if i := strings.LastIndexByte(indent, '\n'); i >= 0 {
+				indent = indent[i+1:]
+			}
+			pass.Report(node.Name, fmt.Sprintf("enum %s should have a zero value named %q", enum.GetName(), want),
+				pass.InsertFix("Add "+want+" = 0", fmt.Sprintf("%s = 0;\n%s", want, indent), first))
+		})
+	},
+}
+
+var packageDirectoryRule = &LintRule{
+	Name: "package-directory",
+	Doc:  "the package name should match the directory containing the file",
+	Run: func(pass *LintPass) {
+		pkg := pass.Result.FileDescriptorProto().GetPackage()
+		if pkg == "" {
+			return
+		}
+		var pkgNode *ast.PackageNode
+		for _, decl := range pass.Result.AST().Decls {
+			if p := decl.GetPackage(); p != nil {
+				pkgNode = p
+				break
+			}
+		}
+		if pkgNode == nil || pkgNode.Name == nil {
+			return
+		}
+		dir := path.Dir(pass.Result.Path())
+		want := strings.ReplaceAll(pkg, ".", "/")
+		if dir == want || strings.HasSuffix(dir, "/"+want) {
+			return
+		}
+		pass.Reportf(pkgNode.Name, "package %q should be in a directory named %q, but the file is in %q", pkg, want, dir)
+	},
+}
+
+var serviceCommentsRule = &LintRule{
+	Name: "service-comments",
+	Doc:  "services should have a leading comment",
+	Run: func(pass *LintPass) {
+		for _, svc := range pass.Result.FileDescriptorProto().GetService() {
+			node := pass.Result.ServiceNode(svc)
+			if node == nil || node.Name == nil {
+				continue
+			}
+			info := pass.Result.AST().NodeInfo(node)
+			if info.LeadingComments().Len() > 0 {
+				continue
+			}
+			indent := info.LeadingWhitespace()
+			if i := strings.LastIndexByte(indent, '\n'); i >= 0 {
+				indent = indent[i+1:]
+			}
+			pass.Report(node.Name, fmt.Sprintf("service %s should have a comment", svc.GetName()),
+				pass.InsertFix("Add comment", fmt.Sprintf("// %s\n%s", svc.GetName(), indent), node))
+		}
+	},
+}
+
+// rangeDescriptorProtos calls fn for each descriptor proto in the file, along
+// with its fully-qualified name.
+func rangeDescriptorProtos(pass *LintPass, fn func(protoreflect.FullName, proto.Message)) {
+	walk.DescriptorProtos(pass.Result.FileDescriptorProto(), func(fqn protoreflect.FullName, d proto.Message) error {
+		fn(fqn, d)
+		return nil
+	})
+}
+
+// reportWithRenameFix reports a diagnostic for the named declaration, with a
+// quick fix to rename it if it can only be referenced by name within the file.
+func reportWithRenameFix(pass *LintPass, fqn protoreflect.FullName, name ast.Node, newName string, message string) {
+	desc := pass.Result.FindDescriptorByName(fqn)
+	if desc == nil {
+		pass.Report(name, message)
+		return
+	}
+	if fix, ok := pass.RenameFix(desc, name, newName); ok {
+		fix.IsPreferred = true
+		pass.Report(name, message, fix)
+		return
+	}
+	pass.Report(name, message)
+}
+
+// splitWords splits an identifier into words at underscores and at changes
+// in case, keeping acronyms and trailing digits together, e.g. "HTTPServer2_id"
+// becomes ["HTTP", "Server2", "id"].
+func splitWords(s string) []string {
+	var words []string
+	runes := []rune(s)
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			words = append(words, string(runes[start:end]))
+		}
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '_':
+			flush(i)
+			start = i + 1
+		case unicode.IsUpper(r) && i > start:
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush(i)
+				start = i
+			}
+		}
+	}
+	flush(len(runes))
+	return words
+}
+
+func isPascalCase(s string) bool {
+	return s != "" && unicode.IsUpper([]rune(s)[0]) && !strings.Contains(s, "_")
+}
+
+func isLowerSnakeCase(s string) bool {
+	return s != "" && s == toLowerSnakeCase(s)
+}
+
+func isUpperSnakeCase(s string) bool {
+	return s != "" && s == toUpperSnakeCase(s)
+}
+
+func toPascalCase(s string) string {
+	var b strings.Builder
+	for _, w := range splitWords(s) {
+		r := []rune(strings.ToLower(w))
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+func toLowerSnakeCase(s string) string {
+	return strings.ToLower(strings.Join(splitWords(s), "_"))
+}
+
+func toUpperSnakeCase(s string) string {
+	return strings.ToUpper(strings.Join(splitWords(s), "_"))
+}
@@ -0,0 +1,32 @@
+package lsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaseConversions(t *testing.T) {
+	for _, tc := range []struct {
+		in, pascal, lowerSnake, upperSnake string
+	}{
+		{"foo", "Foo", "foo", "FOO"},
+		{"fooBar", "FooBar", "foo_bar", "FOO_BAR"},
+		{"FooBar", "FooBar", "foo_bar", "FOO_BAR"},
+		{"foo_bar", "FooBar", "foo_bar", "FOO_BAR"},
+		{"FOO_BAR", "FooBar", "foo_bar", "FOO_BAR"},
+		{"HTTPServer", "HttpServer", "http_server", "HTTP_SERVER"},
+		{"field2Name", "Field2Name", "field2_name", "FIELD2_NAME"},
+		{"_leading__underscores_", "LeadingUnderscores", "leading_underscores", "LEADING_UNDERSCORES"},
+	} {
+		require.Equal(t, tc.pascal, toPascalCase(tc.in), tc.in)
+		require.Equal(t, tc.lowerSnake, toLowerSnakeCase(tc.in), tc.in)
+		require.Equal(t, tc.upperSnake, toUpperSnakeCase(tc.in), tc.in)
+	}
+	require.True(t, isPascalCase("HTTPServer"))
+	require.False(t, isPascalCase("my_message"))
+	require.True(t, isLowerSnakeCase("field2_name"))
+	require.False(t, isLowerSnakeCase("fooBar"))
+	require.True(t, isUpperSnakeCase("KIND_UNSPECIFIED"))
+	require.False(t, isUpperSnakeCase("Kind_Unspecified"))
+}
@@ -32,37 +32,51 @@ func (c *Cache) TryFindPackageReferences(params protocol.TextDocumentPositionPar
 		return nil
 	}
 
-	tokenAtOffset, comment := fileNode.ItemAtOffset(offset)
-	if tokenAtOffset == ast.TokenError || comment.IsValid() {
+	pkgNode, segments := findPackageSegmentsAtOffset(fileNode, offset)
+	if pkgNode == nil {
 		return nil
 	}
+	var nameLen int
+	switch name := pkgNode.Name.Unwrap().(type) {
+	case *ast.IdentNode:
+		nameLen = 1
+	case *ast.CompoundIdentNode:
+		nameLen = len(name.FilterIdents())
+	}
+	return c.FindPackageNameRefs(protoreflect.FullName(strings.Join(segments, ".")), len(segments) < nameLen)
+}
 
-LOOP:
+// findPackageSegmentsAtOffset returns the package declaration and the leading
+// segments of the package name up to and including the segment at the given
+// offset, if the offset is within the package name.
+func findPackageSegmentsAtOffset(fileNode *ast.FileNode, offset int) (*ast.PackageNode, []string) {
+	tokenAtOffset, comment := fileNode.ItemAtOffset(offset)
+	if tokenAtOffset == ast.TokenError || comment.IsValid() {
+		return nil, nil
+	}
 	for _, decl := range fileNode.Decls {
-		if decl := decl.GetPackage(); decl != nil {
-			if decl.Name != nil {
-				if tokenAtOffset >= decl.Name.Start() && tokenAtOffset <= decl.Name.End() {
-					switch name := decl.Name.Unwrap().(type) {
-					case *ast.IdentNode:
-						return c.FindPackageNameRefs(protoreflect.FullName(name.Val), false)
-					case *ast.CompoundIdentNode:
-						idents := name.FilterIdents()
-						for i, ident := range idents {
-							if tokenAtOffset >= ident.Start() && tokenAtOffset <= ident.End() {
-								var parts []string
-								for j := 0; j <= i; j++ {
-									parts = append(parts, idents[j].Val)
-								}
-								return c.FindPackageNameRefs(protoreflect.FullName(strings.Join(parts, ".")), i < len(idents)-1)
-							}
-						}
-					}
+		pkgNode := decl.GetPackage()
+		if pkgNode == nil {
+			continue
+		}
+		if pkgNode.Name == nil || tokenAtOffset < pkgNode.Name.Start() || tokenAtOffset > pkgNode.Name.End() {
+			return nil, nil
+		}
+		switch name := pkgNode.Name.Unwrap().(type) {
+		case *ast.IdentNode:
+			return pkgNode, []string{name.Val}
+		case *ast.CompoundIdentNode:
+			var segments []string
+			for _, ident := range name.FilterIdents() {
+				segments = append(segments, ident.Val)
+				if tokenAtOffset >= ident.Start() && tokenAtOffset <= ident.End() {
+					return pkgNode, segments
 				}
 			}
-			break LOOP
 		}
+		return nil, nil
 	}
-	return nil
+	return nil, nil
 }
 
 func (c *Cache) FindPackageNameRefs(name protoreflect.FullName, prefixMatch bool) []protocol.Location {
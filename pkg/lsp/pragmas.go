@@ -17,6 +17,10 @@ const (
 
 	PragmaDebugWnoerror = "Wnoerror"
 	WnoerrorAll         = "all"
+
+	PragmaDebugLint   = "lint"
+	PragmaDebugNolint = "nolint"
+	LintAll           = "all"
 )
 
 type Pragmas interface {
@@ -2,6 +2,8 @@ package lsp
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"slices"
 
 	"github.com/kralicky/protocompile/ast"
@@ -69,5 +71,43 @@ func (c *Cache) FindReferences(ctx context.Context, params protocol.TextDocument
 		return nil, err
 	}
 	locations = append(locations, refs...)
+
+	if c.settings.Load().References.GetIncludeGoCode() {
+		goRefs, err := c.FindGoReferenceLocationsForTypeDescriptor(desc)
+		if err != nil {
+			slog.Warn("failed to find references in go code", "descriptor", desc.FullName(), "error", err)
+		}
+		locations = append(locations, goRefs...)
+	}
+	return locations, nil
+}
+
+// FindGoReferenceLocationsForTypeDescriptor returns the locations of references
+// to the generated Go code for the given descriptor in the local Go module.
+func (c *Cache) FindGoReferenceLocationsForTypeDescriptor(desc protoreflect.Descriptor) ([]protocol.Location, error) {
+	symbols := GoSymbols(desc)
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+	refs, err := c.findGoReferencesForSymbols(desc.ParentFile(), symbols)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]protocol.Location, 0, len(refs))
+	for _, ref := range refs {
+		locations = append(locations, ref.Location)
+	}
 	return locations, nil
 }
+
+func (c *Cache) findGoReferencesForSymbols(file protoreflect.FileDescriptor, symbols []GoSymbol) ([]GoReference, error) {
+	parentUri, err := c.resolver.PathToURI(file.Path())
+	if err != nil {
+		return nil, err
+	}
+	refs, err := c.resolver.FindGoSymbolReferences(parentUri, file, symbols)
+	if errors.Is(err, ErrNoModule) {
+		return nil, nil
+	}
+	return refs, err
+}
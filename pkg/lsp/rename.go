@@ -2,6 +2,8 @@ package lsp
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/kralicky/protocompile/ast"
 	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
@@ -71,19 +73,44 @@ func (c *Cache) canRename(desc protoreflect.Descriptor) error {
 
 func (c *Cache) Rename(params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
 	c.resultsMu.RLock()
-	defer c.resultsMu.RUnlock()
+	desc, editsByDocument, err := c.renameLocked(params)
+	c.resultsMu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// type-checking the go module can take a while, so it is done without
+	// holding the lock; descriptors are immutable once linked.
+	if c.settings.Load().Rename.GetIncludeGoCode() {
+		goEdits, err := c.goRenameEdits(desc, protoreflect.Name(params.NewName))
+		if err != nil {
+			return nil, err
+		}
+		for uri, edits := range goEdits {
+			editsByDocument[uri] = append(editsByDocument[uri], edits...)
+		}
+	}
+
+	// the rename is valid, return the edits to the server
+	return &protocol.WorkspaceEdit{
+		Changes: editsByDocument,
+	}, nil
+}
 
+// renameLocked returns the descriptor being renamed, and the edits to proto
+// source files needed to rename it.
+func (c *Cache) renameLocked(params *protocol.RenameParams) (protoreflect.Descriptor, map[protocol.DocumentURI][]protocol.TextEdit, error) {
 	desc, _, err := c.FindTypeDescriptorAtLocation(protocol.TextDocumentPositionParams{
 		TextDocument: params.TextDocument,
 		Position:     params.Position,
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// check if desc can be renamed
 	if err := c.canRename(desc); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// check if the new name is valid
@@ -93,33 +120,33 @@ func (c *Cache) Rename(params *protocol.RenameParams) (*protocol.WorkspaceEdit,
 	newFqn := oldFqn.Parent().Append(protoreflect.Name(params.NewName))
 	// check if the new name is valid
 	if !newFqn.IsValid() {
-		return nil, fmt.Errorf("invalid name %q", newFqn)
+		return nil, nil, fmt.Errorf("invalid name %q", newFqn)
 	}
 
 	// check if the new name is already taken
 	if _, err := c.results.AsResolver().FindDescriptorByName(newFqn); err == nil {
-		return nil, fmt.Errorf("a type already exists with name %q", newFqn)
+		return nil, nil, fmt.Errorf("a type already exists with name %q", newFqn)
 	}
 
 	parentFile := desc.ParentFile()
 	if parentFile == nil {
-		return nil, fmt.Errorf("no parent file found for descriptor")
+		return nil, nil, fmt.Errorf("no parent file found for descriptor")
 	}
 	linkRes, err := c.findResultOrPartialResultByPathLocked(parentFile.Path())
 	if err != nil {
-		return nil, fmt.Errorf("failed to find result for %q: %w", parentFile.Path(), err)
+		return nil, nil, fmt.Errorf("failed to find result for %q: %w", parentFile.Path(), err)
 	}
 
 	// find the definition
 	definition, err := findDefinition(desc, linkRes)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// find all references
 	refs, err := c.FindReferencesForTypeDescriptor(desc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	editsByDocument := map[protocol.DocumentURI][]protocol.TextEdit{}
@@ -173,24 +200,43 @@ func (c *Cache) Rename(params *protocol.RenameParams) (*protocol.WorkspaceEdit,
 		case *ast.RPCTypeNode:
 			editRange = ref.NodeInfo.Internal().ParentFile().NodeInfo(node.MessageType)
 		default:
-			return nil, fmt.Errorf("cannot rename %T", node)
+			return nil, nil, fmt.Errorf("cannot rename %T", node)
 		}
 
 		uri, err := c.resolver.PathToURI(ref.NodeInfo.Start().Filename)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if !c.resolver.IsRealWorkspaceLocalFile(uri) {
-			return nil, fmt.Errorf("references exist outside of the workspace")
+			return nil, nil, fmt.Errorf("references exist outside of the workspace")
 		}
 		editsByDocument[uri] = append(editsByDocument[uri], protocol.TextEdit{
 			Range:   toRange(editRange),
 			NewText: params.NewName,
 		})
 	}
+	return desc, editsByDocument, nil
+}
 
-	// the rename is valid, return the edits to the server
-	return &protocol.WorkspaceEdit{
-		Changes: editsByDocument,
-	}, nil
+// goRenameEdits returns edits to Go files in the local module which reference
+// generated code for the given descriptor, such that they refer to the code
+// that would be generated after renaming the descriptor.
+func (c *Cache) goRenameEdits(desc protoreflect.Descriptor, newName protoreflect.Name) (map[protocol.DocumentURI][]protocol.TextEdit, error) {
+	renames := goRenames(desc, newName)
+	if len(renames) == 0 {
+		return nil, nil
+	}
+	symbols := slices.Collect(maps.Keys(renames))
+	refs, err := c.findGoReferencesForSymbols(desc.ParentFile(), symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find references in go code: %w", err)
+	}
+	editsByDocument := map[protocol.DocumentURI][]protocol.TextEdit{}
+	for _, ref := range refs {
+		editsByDocument[ref.Location.URI] = append(editsByDocument[ref.Location.URI], protocol.TextEdit{
+			Range:   ref.Location.Range,
+			NewText: renames[ref.Symbol],
+		})
+	}
+	return editsByDocument, nil
 }
@@ -36,6 +36,11 @@ const (
 	SourceLocalGoModule
 	SourceGoModuleCache
 	SourceSynthetic
+	SourceBufModule
+	SourceBufModuleCache
+	SourceIncludePath
+	SourceBazelPackage
+	SourceBazelExternal
 )
 
 type Resolver struct {
@@ -43,7 +48,11 @@ type Resolver struct {
 	fsDelegate                 *cache.MemoizedFS
 	folder                     protocol.WorkspaceFolder
 	goLanguageDriver           *GoLanguageDriver
+	bufWorkspace               *BufWorkspace
+	bazelWorkspace             *BazelWorkspace
 	pathsMu                    sync.RWMutex
+	includePaths               []string                        // absolute include paths, in order of precedence
+	shadowedImports            map[string][]string             // import path -> all matching files, if found under more than one include path
 	filePathsByURI             map[protocol.DocumentURI]string // URI -> canonical file path (go package + file name)
 	fileURIsByPath             map[string]protocol.DocumentURI // canonical file path (go package + file name) -> URI
 	importSourcesByURI         map[protocol.DocumentURI]ImportSource
@@ -53,16 +62,30 @@ type Resolver struct {
 
 func NewResolver(folder protocol.WorkspaceFolder) *Resolver {
 	fsDelegate := cache.NewMemoizedFS()
+	workdir := protocol.DocumentURI(folder.URI).Path()
+	// buf.yaml and BUILD files are found with a single walk of the workspace
+	files := newWorkspaceFiles(workdir)
+	bufWorkspace, err := loadBufWorkspace(workdir, files)
+	if err != nil {
+		slog.With("error", err).Warn("failed to load buf workspace configuration")
+	}
+	bazelWorkspace, err := loadBazelWorkspace(workdir, files)
+	if err != nil {
+		slog.With("error", err).Warn("failed to load bazel workspace")
+	}
 	return &Resolver{
 		folder:                     folder,
 		OverlayFS:                  cache.NewOverlayFS(fsDelegate),
 		fsDelegate:                 fsDelegate,
-		goLanguageDriver:           NewGoLanguageDriver(protocol.DocumentURI(folder.URI).Path()),
+		goLanguageDriver:           NewGoLanguageDriver(workdir),
+		bufWorkspace:               bufWorkspace,
+		bazelWorkspace:             bazelWorkspace,
 		filePathsByURI:             make(map[protocol.DocumentURI]string),
 		fileURIsByPath:             make(map[string]protocol.DocumentURI),
 		syntheticFileOriginalNames: make(map[protocol.DocumentURI]string),
 		syntheticFiles:             make(map[protocol.DocumentURI]string),
 		importSourcesByURI:         map[protocol.DocumentURI]ImportSource{},
+		shadowedImports:            map[string][]string{},
 	}
 }
 
@@ -162,6 +185,29 @@ func (r *Resolver) UpdateURIPathMappings(modifications []file.Modification) {
 			}
 		case file.Create:
 			filename := m.URI.Path()
+			if path, ok := r.includeRelativePathLocked(filename); ok {
+				r.filePathsByURI[m.URI] = path
+				r.importSourcesByURI[m.URI] = SourceIncludePath
+				// if the path is shadowed, only the file found first maps to it
+				if matches := r.lookupIncludePathsLocked(path); len(matches) > 0 && matches[0] == filename {
+					r.fileURIsByPath[path] = m.URI
+				}
+				continue
+			}
+			// buf modules take precedence over bazel packages, in the same order
+			// as in findFileByPathLocked
+			if path, ok := r.bufWorkspace.ModuleRelativePath(filename); ok {
+				r.filePathsByURI[m.URI] = path
+				r.fileURIsByPath[path] = m.URI
+				r.importSourcesByURI[m.URI] = SourceBufModule
+				continue
+			}
+			if path, ok := r.bazelWorkspace.ImportPath(filename); ok {
+				r.filePathsByURI[m.URI] = path
+				r.fileURIsByPath[path] = m.URI
+				r.importSourcesByURI[m.URI] = SourceBazelPackage
+				continue
+			}
 			f, err := os.Open(filename)
 			if err != nil {
 				slog.With(
@@ -267,7 +313,9 @@ func (r *Resolver) CheckIncompleteDescriptors(results linker.Files) []string {
 
 // Path resolution order:
 // 1. Check for well-known import paths like google/*
-// 2. Check if the path is a file on disk
+// 2. Check if the path is a file on disk, or is found within one of the include paths
+// 2.5. Check if the path is in a buf module or one of its dependencies
+// 2.6. Check if the path is a source of a bazel proto_library rule, or is found in an external repository
 // 3. Check if the path is a go module containing proto sources
 // 3.5. Check if the path is a go module path containing generated code, but no proto sources
 // 4. Check if the path is found in the global message cache
@@ -334,6 +382,20 @@ func (r *Resolver) findFileByPathLocked(path string, whence protocompile.ImportC
 			slog.With("path", path, "error", err).Debug("failed to check cached file")
 			return protocompile.SearchResult{}, err
 		}
+		if result, err := r.checkBufWorkspace(path); err == nil {
+			lg.Debug("resolved to buf module")
+			return result, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			lg.Debug("failed to check buf workspace")
+			return protocompile.SearchResult{}, err
+		}
+		if result, err := r.checkBazelWorkspace(path); err == nil {
+			lg.Debug("resolved to bazel workspace")
+			return result, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			lg.Debug("failed to check bazel workspace")
+			return protocompile.SearchResult{}, err
+		}
 	}
 
 	if result, err := r.checkGoModule(path, whence); err == nil {
@@ -374,6 +436,17 @@ const largeFileThreshold = 1024 * 1024 // 1MB
 
 func (r *Resolver) checkFS(path string, whence protocompile.ImportContext) (protocompile.SearchResult, error) {
 	uri, ok := r.fileURIsByPath[path]
+	if ok && uri.IsFile() && r.bufWorkspace.IsExcluded(uri.Path()) {
+		return protocompile.SearchResult{}, os.ErrNotExist
+	}
+	if !ok {
+		if matches := r.lookupIncludePathsLocked(path); len(matches) > 0 {
+			uri, ok = protocol.URIFromPath(matches[0]), true
+			r.filePathsByURI[uri] = path
+			r.fileURIsByPath[path] = uri
+			r.importSourcesByURI[uri] = SourceIncludePath
+		}
+	}
 	if ok {
 		if fh, err := r.ReadFile(context.TODO(), uri); err == nil {
 			content, err := fh.Content()
@@ -393,6 +466,60 @@ func (r *Resolver) checkFS(path string, whence protocompile.ImportContext) (prot
 	return protocompile.SearchResult{}, os.ErrNotExist
 }
 
+// checkBufWorkspace looks for the path within the roots of the buf modules in
+// the workspace, followed by the sources of their dependencies in the local
+// buf module cache.
+func (r *Resolver) checkBufWorkspace(path string) (protocompile.SearchResult, error) {
+	filename, isDep, ok := r.bufWorkspace.FindFile(path)
+	if !ok {
+		return protocompile.SearchResult{}, os.ErrNotExist
+	}
+	src, err := os.Open(filename)
+	if err != nil {
+		return protocompile.SearchResult{}, err
+	}
+	uri := protocol.URIFromPath(filename)
+	r.filePathsByURI[uri] = path
+	r.fileURIsByPath[path] = uri
+	if isDep {
+		r.importSourcesByURI[uri] = SourceBufModuleCache
+	} else {
+		r.importSourcesByURI[uri] = SourceBufModule
+	}
+	return protocompile.SearchResult{
+		Version:      1,
+		ResolvedPath: protocompile.ResolvedPath(path),
+		Source:       src, // this is closed by the compiler
+	}, nil
+}
+
+// checkBazelWorkspace looks for the path within the import paths of the
+// proto_library rules in the workspace, followed by the external repositories
+// in the bazel output base.
+func (r *Resolver) checkBazelWorkspace(path string) (protocompile.SearchResult, error) {
+	filename, isExternal, ok := r.bazelWorkspace.FindFile(path)
+	if !ok {
+		return protocompile.SearchResult{}, os.ErrNotExist
+	}
+	src, err := os.Open(filename)
+	if err != nil {
+		return protocompile.SearchResult{}, err
+	}
+	uri := protocol.URIFromPath(filename)
+	r.filePathsByURI[uri] = path
+	r.fileURIsByPath[path] = uri
+	if isExternal {
+		r.importSourcesByURI[uri] = SourceBazelExternal
+	} else {
+		r.importSourcesByURI[uri] = SourceBazelPackage
+	}
+	return protocompile.SearchResult{
+		Version:      1,
+		ResolvedPath: protocompile.ResolvedPath(path),
+		Source:       src, // this is closed by the compiler
+	}, nil
+}
+
 func (r *Resolver) checkGoModule(path string, whence protocompile.ImportContext) (protocompile.SearchResult, error) {
 	if !r.goLanguageDriver.HasGoModule() {
 		return protocompile.SearchResult{}, ErrNoModule
@@ -546,7 +673,7 @@ func (r *Resolver) IsRealWorkspaceLocalFile(uri protocol.DocumentURI) bool {
 	// check if the file has a known import source indicating it is not a real file
 	// or is outside the workspace root
 	if src, ok := r.importSourcesByURI[uri]; ok {
-		if src == SourceSynthetic || src == SourceGoModuleCache || src == SourceWellKnown {
+		if src == SourceSynthetic || src == SourceGoModuleCache || src == SourceBufModuleCache || src == SourceBazelExternal || src == SourceWellKnown {
 			return false
 		}
 	}
@@ -582,6 +709,20 @@ func (r *Resolver) FindGeneratedFiles(uri protocol.DocumentURI, fd protoreflect.
 	return r.goLanguageDriver.FindGeneratedFiles(uri, fd.Options().(*descriptorpb.FileOptions), fd.Path())
 }
 
+func (r *Resolver) FindGoMethodCallSites(uri protocol.DocumentURI, fd protoreflect.FileDescriptor, clientName, methodName string) ([]GoCallSite, error) {
+	if !r.goLanguageDriver.HasGoModule() {
+		return nil, ErrNoModule
+	}
+	return r.goLanguageDriver.FindMethodCallSites(uri, fd.Options().(*descriptorpb.FileOptions), clientName, methodName)
+}
+
+func (r *Resolver) FindGoSymbolReferences(uri protocol.DocumentURI, fd protoreflect.FileDescriptor, symbols []GoSymbol) ([]GoReference, error) {
+	if !r.goLanguageDriver.HasGoModule() {
+		return nil, ErrNoModule
+	}
+	return r.goLanguageDriver.FindSymbolReferences(uri, fd.Options().(*descriptorpb.FileOptions), symbols)
+}
+
 func (r *Resolver) findImportPathsByPrefix(prefix string) map[protocol.DocumentURI]string {
 	r.pathsMu.RLock()
 	defer r.pathsMu.RUnlock()
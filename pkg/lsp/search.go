@@ -904,6 +904,16 @@ func findDefinition(desc protoreflect.Descriptor, linkRes linker.Result) (ast.No
 }
 
 func findNodeReferences(desc protoreflect.Descriptor, files linker.Files) <-chan ast.NodeReference {
+	var extType protoreflect.ExtensionTypeDescriptor
+	if fld, ok := desc.(protoreflect.FieldDescriptor); ok && fld.IsExtension() {
+		// extensions used in option names are resolved to the descriptor of the
+		// extension type, which is distinct from the linker's field descriptor
+		if xt, err := files.AsResolver().FindExtensionByName(fld.FullName()); err == nil {
+			if td := xt.TypeDescriptor(); td != desc {
+				extType = td
+			}
+		}
+	}
 	var wg sync.WaitGroup
 	refs := make(chan ast.NodeReference, len(files))
 	seen := sync.Map{}
@@ -915,11 +925,24 @@ func findNodeReferences(desc protoreflect.Descriptor, files linker.Files) <-chan
 		res := res.(linker.Result)
 		go func() {
 			defer wg.Done()
-			for _, ref := range res.FindReferences(desc) {
+			send := func(ref ast.NodeReference) {
 				if _, seen := seen.LoadOrStore(ref.String(), struct{}{}); !seen {
 					refs <- ref
 				}
 			}
+			for _, ref := range res.FindReferences(desc) {
+				send(ref)
+			}
+			if extType != nil {
+				for _, ref := range res.FindReferences(extType) {
+					// refer to the extension name only, without parentheses. Qualified
+					// names will have already been found above.
+					if fieldRef, ok := ref.Node.(*ast.FieldReferenceNode); ok && fieldRef.Name != nil {
+						ref = ast.NewNodeReference(res.AST(), fieldRef.Name.Unwrap())
+					}
+					send(ref)
+				}
+			}
 		}()
 	}
 	go func() {
@@ -0,0 +1,138 @@
+package lsp
+
+import (
+	"bytes"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/ast/paths"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"google.golang.org/protobuf/reflect/protopath"
+)
+
+// ComputeSelectionRanges returns a chain of nested selection ranges for each
+// of the given positions. Each chain starts at the innermost token under the
+// cursor and expands outward through every enclosing node (and the contents
+// of any enclosing body, such as the inside of a message or a compact options
+// list) up to the entire file.
+func (c *Cache) ComputeSelectionRanges(doc protocol.TextDocumentIdentifier, positions []protocol.Position) ([]protocol.SelectionRange, error) {
+	mapper, err := c.GetMapper(doc.URI)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.FindParseResultByURI(doc.URI)
+	if err != nil {
+		return nil, err
+	}
+	fileNode := res.AST()
+	if fileNode == nil {
+		return nil, nil
+	}
+	ranges := make([]protocol.SelectionRange, 0, len(positions))
+	for _, pos := range positions {
+		offset, err := mapper.PositionOffset(pos)
+		if err != nil {
+			return nil, err
+		}
+		b := selectionRangeBuilder{
+			mapper:   mapper,
+			fileNode: fileNode,
+		}
+		b.add(0, len(mapper.Content))
+		b.addNodesAtOffset(offset)
+		ranges = append(ranges, b.build(pos))
+	}
+	return ranges, nil
+}
+
+type selectionRangeBuilder struct {
+	mapper   *protocol.Mapper
+	fileNode *ast.FileNode
+	// offset pairs, ordered from outermost to innermost
+	offsets [][2]int
+}
+
+func (b *selectionRangeBuilder) addNodesAtOffset(offset int) {
+	token, comment := b.fileNode.ItemAtOffset(offset)
+	if comment.IsValid() {
+		b.add(comment.Start().Offset, comment.End().Offset+1)
+		return
+	}
+	if token == ast.TokenError {
+		return
+	}
+	tracker := &paths.AncestorTracker{}
+	var deepest protopath.Values
+	ast.Inspect(b.fileNode, func(ast.Node) bool {
+		if values := tracker.Values(); len(values.Path) > len(deepest.Path) {
+			deepest = values
+		}
+		return true
+	}, append(tracker.AsWalkOptions(), ast.WithIntersection(token))...)
+
+	for i := range deepest.Path {
+		if !paths.NodeIsConcrete(deepest, i) {
+			continue
+		}
+		node := deepest.Index(i).Value.Message().Interface().(ast.Node)
+		if _, ok := node.(*ast.FileNode); ok {
+			continue
+		}
+		info := b.fileNode.NodeInfo(node)
+		b.add(info.Start().Offset, endOffset(b.mapper, info))
+		b.addBody(node)
+	}
+}
+
+// addBody adds the range between the (non-empty) open and close delimiters of
+// a node's body, excluding surrounding whitespace.
+func (b *selectionRangeBuilder) addBody(node ast.Node) {
+	open, close := bodyDelimiters(node)
+	if open == nil || close == nil || open.GetVirtual() || close.GetVirtual() {
+		return
+	}
+	start := endOffset(b.mapper, b.fileNode.NodeInfo(open))
+	end := b.fileNode.NodeInfo(close).Start().Offset
+	if start >= end {
+		return
+	}
+	content := b.mapper.Content[start:end]
+	trimmed := bytes.TrimLeft(content, " \t\r\n")
+	start += len(content) - len(trimmed)
+	end -= len(trimmed) - len(bytes.TrimRight(trimmed, " \t\r\n"))
+	if start < end {
+		b.add(start, end)
+	}
+}
+
+// add appends a range if it is strictly contained within the previous
+// (enclosing) range. Ranges that do not narrow the selection are dropped.
+func (b *selectionRangeBuilder) add(start, end int) {
+	if start > end {
+		return
+	}
+	if len(b.offsets) > 0 {
+		prev := b.offsets[len(b.offsets)-1]
+		if start < prev[0] || end > prev[1] || (start == prev[0] && end == prev[1]) {
+			return
+		}
+	}
+	b.offsets = append(b.offsets, [2]int{start, end})
+}
+
+func (b *selectionRangeBuilder) build(pos protocol.Position) protocol.SelectionRange {
+	var parent *protocol.SelectionRange
+	for _, offsets := range b.offsets {
+		rng, err := b.mapper.OffsetRange(offsets[0], offsets[1])
+		if err != nil {
+			continue
+		}
+		parent = &protocol.SelectionRange{
+			Range:  rng,
+			Parent: parent,
+		}
+	}
+	if parent == nil {
+		return protocol.SelectionRange{Range: protocol.Range{Start: pos, End: pos}}
+	}
+	return *parent
+}
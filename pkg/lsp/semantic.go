@@ -116,7 +116,10 @@ func (s *semanticItems) AST() *ast.FileNode {
 	return s.parseRes.AST()
 }
 
-func (c *Cache) ComputeSemanticTokens(doc protocol.TextDocumentIdentifier) ([]uint32, error) {
+// ComputeSemanticTokens computes the semantic tokens for the entire document.
+// The result is retained, and its result ID can be used to request a delta
+// with ComputeSemanticTokensDelta.
+func (c *Cache) ComputeSemanticTokens(doc protocol.TextDocumentIdentifier) (*protocol.SemanticTokens, error) {
 	c.resultsMu.RLock()
 	defer c.resultsMu.RUnlock()
 	if ok, err := c.latestDocumentContentsWellFormedLocked(doc.URI, false); err != nil {
@@ -129,7 +132,7 @@ func (c *Cache) ComputeSemanticTokens(doc protocol.TextDocumentIdentifier) ([]ui
 	if err != nil {
 		return nil, err
 	}
-	return result.Data, nil
+	return c.semanticTokens.put(doc.URI, result.Data), nil
 }
 
 func (c *Cache) ComputeSemanticTokensRange(doc protocol.TextDocumentIdentifier, rng protocol.Range) ([]uint32, error) {
@@ -0,0 +1,100 @@
+package lsp
+
+import (
+	"slices"
+	"strconv"
+	"sync/atomic"
+
+	gsync "github.com/kralicky/gpkg/sync"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+)
+
+// semanticTokensEncodedLen is the number of integers used to encode a single
+// token in the semantic tokens data array.
+const semanticTokensEncodedLen = 5
+
+// semanticTokensStore keeps the most recent full semantic tokens result for
+// each document, so that subsequent requests can be answered with a delta.
+type semanticTokensStore struct {
+	lastResultId atomic.Uint64
+	results      gsync.Map[protocol.DocumentURI, *protocol.SemanticTokens]
+}
+
+// put stores the data as the latest result for the document and returns the
+// result with a newly assigned result ID.
+func (s *semanticTokensStore) put(uri protocol.DocumentURI, data []uint32) *protocol.SemanticTokens {
+	tokens := &protocol.SemanticTokens{
+		ResultID: strconv.FormatUint(s.lastResultId.Add(1), 10),
+		Data:     data,
+	}
+	s.results.Store(uri, tokens)
+	return tokens
+}
+
+// previous returns the data for the document if the latest stored result has
+// the given result ID.
+func (s *semanticTokensStore) previous(uri protocol.DocumentURI, resultId string) ([]uint32, bool) {
+	tokens, ok := s.results.Load(uri)
+	if !ok || tokens.ResultID != resultId {
+		return nil, false
+	}
+	return tokens.Data, true
+}
+
+func (s *semanticTokensStore) delete(uri protocol.DocumentURI) {
+	s.results.Delete(uri)
+}
+
+// ComputeSemanticTokensDelta computes the semantic tokens for the document,
+// and returns the edits required to transform the result previously returned
+// with the given ID into the new result. If the previous result is not known,
+// the full result is returned instead.
+//
+// The returned value is either a *protocol.SemanticTokensDelta or a
+// *protocol.SemanticTokens.
+func (c *Cache) ComputeSemanticTokensDelta(doc protocol.TextDocumentIdentifier, previousResultId string) (any, error) {
+	prev, hasPrev := c.semanticTokens.previous(doc.URI, previousResultId)
+	tokens, err := c.ComputeSemanticTokens(doc)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPrev {
+		return tokens, nil
+	}
+	return &protocol.SemanticTokensDelta{
+		ResultID: tokens.ResultID,
+		Edits:    semanticTokensEdits(prev, tokens.Data),
+	}, nil
+}
+
+// semanticTokensEdits returns the edits required to transform prev into next.
+// Only the common prefix and suffix of the two arrays are preserved, which is
+// sufficient for typical edits made within a single region of the document.
+// Edits are aligned to token boundaries.
+func semanticTokensEdits(prev, next []uint32) []protocol.SemanticTokensEdit {
+	if slices.Equal(prev, next) {
+		return []protocol.SemanticTokensEdit{}
+	}
+	maxCommon := min(len(prev), len(next))
+	prefix := 0
+	for prefix < maxCommon && prev[prefix] == next[prefix] {
+		prefix++
+	}
+	prefix -= prefix % semanticTokensEncodedLen
+
+	maxCommon -= prefix
+	suffix := 0
+	for suffix < maxCommon && prev[len(prev)-1-suffix] == next[len(next)-1-suffix] {
+		suffix++
+	}
+	suffix -= suffix % semanticTokensEncodedLen
+
+	edit := protocol.SemanticTokensEdit{
+		Start:       uint32(prefix),
+		DeleteCount: uint32(len(prev) - prefix - suffix),
+	}
+	if data := next[prefix : len(next)-suffix]; len(data) > 0 {
+		edit.Data = slices.Clone(data)
+	}
+	return []protocol.SemanticTokensEdit{edit}
+}
@@ -0,0 +1,86 @@
+package lsp
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemanticTokensEdits(t *testing.T) {
+	tok := func(vals ...uint32) []uint32 {
+		var data []uint32
+		for _, v := range vals {
+			data = append(data, v, v, v, v, v)
+		}
+		return data
+	}
+	tests := []struct {
+		name       string
+		prev, next []uint32
+		want       []protocol.SemanticTokensEdit
+	}{
+		{
+			name: "unchanged",
+			prev: tok(1, 2, 3),
+			next: tok(1, 2, 3),
+			want: []protocol.SemanticTokensEdit{},
+		},
+		{
+			name: "insert in middle",
+			prev: tok(1, 2, 3),
+			next: tok(1, 2, 4, 3),
+			want: []protocol.SemanticTokensEdit{{Start: 10, DeleteCount: 0, Data: tok(4)}},
+		},
+		{
+			name: "delete at start",
+			prev: tok(1, 2, 3),
+			next: tok(2, 3),
+			want: []protocol.SemanticTokensEdit{{Start: 0, DeleteCount: 5}},
+		},
+		{
+			name: "replace at end",
+			prev: tok(1, 2, 3),
+			next: tok(1, 2, 5, 6),
+			want: []protocol.SemanticTokensEdit{{Start: 10, DeleteCount: 5, Data: tok(5, 6)}},
+		},
+		{
+			name: "partial token change is aligned",
+			prev: []uint32{0, 0, 4, 1, 0, 1, 2, 3, 0, 0, 0, 2, 5, 2, 0},
+			next: []uint32{0, 0, 4, 1, 0, 1, 2, 7, 0, 0, 0, 2, 5, 2, 0},
+			want: []protocol.SemanticTokensEdit{{Start: 5, DeleteCount: 5, Data: []uint32{1, 2, 7, 0, 0}}},
+		},
+		{
+			name: "repeated tokens",
+			prev: tok(1, 1, 1),
+			next: tok(1, 1, 1, 1),
+			want: []protocol.SemanticTokensEdit{{Start: 15, DeleteCount: 0, Data: tok(1)}},
+		},
+		{
+			name: "from empty",
+			prev: nil,
+			next: tok(1),
+			want: []protocol.SemanticTokensEdit{{Start: 0, DeleteCount: 0, Data: tok(1)}},
+		},
+		{
+			name: "to empty",
+			prev: tok(1, 2),
+			next: nil,
+			want: []protocol.SemanticTokensEdit{{Start: 0, DeleteCount: 10}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edits := semanticTokensEdits(tt.prev, tt.next)
+			require.Equal(t, tt.want, edits)
+
+			applied := slices.Clone(tt.prev)
+			for _, edit := range edits {
+				applied = slices.Replace(applied, int(edit.Start), int(edit.Start+edit.DeleteCount), edit.Data...)
+			}
+			require.Equal(t, len(tt.next), len(applied))
+			require.True(t, slices.Equal(tt.next, applied))
+		})
+	}
+}
@@ -28,6 +28,10 @@ type Server struct {
 
 	client             protocol.ClientCloser
 	clientCapabilities protocol.ClientCapabilities
+	initialSettings    Settings
+
+	diagnosticStreamMu     sync.Mutex
+	diagnosticStreamCancel context.CancelFunc
 
 	trackerMu    sync.Mutex
 	tracker      *progress.Tracker
@@ -85,6 +89,30 @@ func (s *Server) cacheInitLocked(cache *Cache, path string) {
 	cache.LoadFiles(sources.SearchDirs(path))
 	s.caches[path] = cache
 
+	if s.usePullDiagnostics() {
+		// the client will request diagnostics using textDocument/diagnostic and
+		// workspace/diagnostic, but needs to be told when they change in files
+		// other than the one being edited.
+		if s.clientCapabilities.Workspace.Diagnostics != nil && s.clientCapabilities.Workspace.Diagnostics.RefreshSupport {
+			changed := make(chan struct{}, 1)
+			go cache.WatchDiagnostics(ctx, changed)
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-changed:
+						slog.Debug("refreshing diagnostics")
+						if err := s.client.DiagnosticRefresh(ctx); err != nil {
+							slog.Error("failed to refresh diagnostics", "error", err)
+						}
+					}
+				}
+			}()
+		}
+		return
+	}
+
 	diagnostics := make(chan protocol.WorkspaceFullDocumentDiagnosticReport, 1)
 	go cache.StreamWorkspaceDiagnostics(ctx, diagnostics)
 	go func() {
@@ -106,6 +134,12 @@ func (s *Server) cacheInitLocked(cache *Cache, path string) {
 	}()
 }
 
+// usePullDiagnostics reports whether the client supports the diagnostic pull
+// model. If it does, diagnostics are not published to the client.
+func (s *Server) usePullDiagnostics() bool {
+	return s.clientCapabilities.TextDocument.Diagnostic != nil
+}
+
 // requires s.cachesMu held for writing
 func (s *Server) cacheDestroyLocked(path string, err error) {
 	if _, ok := s.caches[path]; ok {
@@ -121,11 +155,21 @@ func (s *Server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 	folders := params.WorkspaceFolders
 	s.clientCapabilities = params.Capabilities
 	s.tracker.SetSupportsWorkDoneProgress(params.Capabilities.Window.WorkDoneProgress)
+	if params.InitializationOptions != nil {
+		// settings which affect how files are resolved need to be known before
+		// the workspace is loaded, which happens before the client is asked for
+		// its configuration.
+		if settings, err := decodeSettings(params.InitializationOptions); err == nil {
+			s.initialSettings = settings
+		} else {
+			slog.Error("failed to decode initialization options", "error", err)
+		}
+	}
 	s.cachesMu.Lock()
 	for _, folder := range folders {
 		path := protocol.DocumentURI(folder.URI).Path()
 		slog.Info("adding workspace folder", "path", path)
-		cache := NewCache(folder)
+		cache := NewCache(folder, WithSettings(s.initialSettings))
 		s.cacheInitLocked(cache, path)
 	}
 	s.cachesMu.Unlock()
@@ -142,6 +186,16 @@ func (s *Server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 		Type:    protocol.Info,
 		Message: fmt.Sprintf("initialized workspace folders: %v", folders),
 	})
+	var diagnosticProvider *protocol.Or_ServerCapabilities_diagnosticProvider
+	if s.usePullDiagnostics() {
+		diagnosticProvider = &protocol.Or_ServerCapabilities_diagnosticProvider{
+			Value: protocol.DiagnosticOptions{
+				Identifier:            "protols",
+				InterFileDependencies: true,
+				WorkspaceDiagnostics:  true,
+			},
+		}
+	}
 	return &protocol.InitializeResult{
 		Capabilities: protocol.ServerCapabilities{
 			TextDocumentSync: protocol.TextDocumentSyncOptions{
@@ -172,6 +226,15 @@ func (s *Server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 			DocumentFormattingProvider: &protocol.Or_ServerCapabilities_documentFormattingProvider{
 				Value: protocol.DocumentFormattingOptions{},
 			},
+			DocumentRangeFormattingProvider: &protocol.Or_ServerCapabilities_documentRangeFormattingProvider{
+				Value: protocol.DocumentRangeFormattingOptions{
+					RangesSupport: true,
+				},
+			},
+			DocumentOnTypeFormattingProvider: &protocol.DocumentOnTypeFormattingOptions{
+				FirstTriggerCharacter: ";",
+				MoreTriggerCharacter:  []string{"}"},
+			},
 			CompletionProvider: &protocol.CompletionOptions{
 				TriggerCharacters: []string{".", "(", "["},
 			},
@@ -192,18 +255,24 @@ func (s *Server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 			CodeLensProvider: &protocol.CodeLensOptions{
 				ResolveProvider: false,
 			},
-			ReferencesProvider:      &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
-			WorkspaceSymbolProvider: &protocol.Or_ServerCapabilities_workspaceSymbolProvider{Value: true},
-			DefinitionProvider:      &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
+			ReferencesProvider:        &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
+			DocumentHighlightProvider: &protocol.Or_ServerCapabilities_documentHighlightProvider{Value: true},
+			WorkspaceSymbolProvider:   &protocol.Or_ServerCapabilities_workspaceSymbolProvider{Value: true},
+			DefinitionProvider:        &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
 			SemanticTokensProvider: &protocol.SemanticTokensOptions{
 				Legend: protocol.SemanticTokensLegend{
 					TokenTypes:     semanticTokenTypes,
 					TokenModifiers: semanticTokenModifiers,
 				},
-				Full:  &protocol.Or_SemanticTokensOptions_full{Value: true},
+				Full:  &protocol.Or_SemanticTokensOptions_full{Value: protocol.SemanticTokensFullDelta{Delta: true}},
 				Range: &protocol.Or_SemanticTokensOptions_range{Value: true},
 			},
 			DocumentSymbolProvider: &protocol.Or_ServerCapabilities_documentSymbolProvider{Value: true},
+			FoldingRangeProvider:   &protocol.Or_ServerCapabilities_foldingRangeProvider{Value: true},
+			SelectionRangeProvider: &protocol.Or_ServerCapabilities_selectionRangeProvider{Value: true},
+			TypeHierarchyProvider:  &protocol.Or_ServerCapabilities_typeHierarchyProvider{Value: true},
+			CallHierarchyProvider:  &protocol.Or_ServerCapabilities_callHierarchyProvider{Value: true},
+			DiagnosticProvider:     diagnosticProvider,
 		},
 
 		ServerInfo: &protocol.ServerInfo{
@@ -523,18 +592,16 @@ func (s *Server) SemanticTokensFull(ctx context.Context, params *protocol.Semant
 	if err != nil {
 		return nil, err
 	}
-	tokens, err := c.ComputeSemanticTokens(params.TextDocument)
-	if err != nil {
-		return nil, err
-	}
-	return &protocol.SemanticTokens{
-		Data: tokens,
-	}, nil
+	return c.ComputeSemanticTokens(params.TextDocument)
 }
 
 // SemanticTokensFullDelta implements protocol.Server.
 func (s *Server) SemanticTokensFullDelta(ctx context.Context, params *protocol.SemanticTokensDeltaParams) (result interface{}, err error) {
-	return nil, nil
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.ComputeSemanticTokensDelta(params.TextDocument, params.PreviousResultID)
 }
 
 // SemanticTokensRange implements protocol.Server.
@@ -616,93 +683,136 @@ var semanticTokenModifiers = []string{
 
 // Diagnostic implements protocol.Server.
 func (s *Server) Diagnostic(ctx context.Context, params *protocol.DocumentDiagnosticParams) (*protocol.Or_DocumentDiagnosticReport, error) {
-	return nil, notImplemented("Diagnostic")
-	// c, err := s.CacheForURI(params.TextDocument.URI)
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	// reports, kind, resultId, err := c.ComputeDiagnosticReports(params.TextDocument.URI, params.PreviousResultID)
-	// if err != nil {
-	// 	slog.Error("failed to compute diagnostic reports", "error", err)
-	// 	return nil, err
-	// }
-	// switch kind {
-	// case protocol.DiagnosticFull:
-	// 	return &protocol.Or_DocumentDiagnosticReport{
-	// 		Value: protocol.RelatedFullDocumentDiagnosticReport{
-	// 			FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
-	// 				Kind:     string(protocol.DiagnosticFull),
-	// 				ResultID: resultId,
-	// 				Items:    reports,
-	// 			},
-	// 		},
-	// 	}, nil
-	// case protocol.DiagnosticUnchanged:
-	// 	return &protocol.Or_DocumentDiagnosticReport{
-	// 		Value: protocol.RelatedUnchangedDocumentDiagnosticReport{
-	// 			UnchangedDocumentDiagnosticReport: protocol.UnchangedDocumentDiagnosticReport{
-	// 				Kind:     string(protocol.DiagnosticUnchanged),
-	// 				ResultID: resultId,
-	// 			},
-	// 		},
-	// 	}, nil
-	// default:
-	// 	panic("bug: unknown diagnostic kind: " + kind)
-	// }
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, kind, resultId, err := c.ComputeDiagnosticReports(params.TextDocument.URI, params.PreviousResultID)
+	if err != nil {
+		slog.Error("failed to compute diagnostic reports", "error", err)
+		return nil, err
+	}
+	switch kind {
+	case protocol.DiagnosticFull:
+		return &protocol.Or_DocumentDiagnosticReport{
+			Value: protocol.RelatedFullDocumentDiagnosticReport{
+				FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
+					Kind:     string(protocol.DiagnosticFull),
+					ResultID: resultId,
+					Items:    reports,
+				},
+			},
+		}, nil
+	case protocol.DiagnosticUnchanged:
+		return &protocol.Or_DocumentDiagnosticReport{
+			Value: protocol.RelatedUnchangedDocumentDiagnosticReport{
+				UnchangedDocumentDiagnosticReport: protocol.UnchangedDocumentDiagnosticReport{
+					Kind:     string(protocol.DiagnosticUnchanged),
+					ResultID: resultId,
+				},
+			},
+		}, nil
+	default:
+		panic("bug: unknown diagnostic kind: " + kind)
+	}
 }
 
 // DiagnosticWorkspace implements protocol.Server.
 func (s *Server) DiagnosticWorkspace(ctx context.Context, params *protocol.WorkspaceDiagnosticParams) (*protocol.WorkspaceDiagnosticReport, error) {
-	return nil, notImplemented("DiagnosticWorkspace")
-	// if params.PartialResultToken == nil {
-	// 	return nil, jsonrpc2.ErrInvalidRequest
-	// }
-
-	// s.diagnosticStreamMu.RLock()
-	// if s.diagnosticStreamCancel != nil {
-	// 	s.diagnosticStreamCancel()
-	// }
-	// s.diagnosticStreamMu.RUnlock()
-
-	// s.diagnosticStreamMu.Lock()
-	// ctx, s.diagnosticStreamCancel = context.WithCancel(context.Background())
-	// s.diagnosticStreamMu.Unlock()
-
-	// s.cachesMu.RLock()
-	// caches := maps.Clone(s.caches)
-	// s.cachesMu.RUnlock()
-
-	// s.diagnosticStreamMu.RLock()
-	// defer s.diagnosticStreamMu.RUnlock()
-
-	// eg, ctx := errgroup.WithContext(ctx)
-	// reportsC := make(chan protocol.WorkspaceDiagnosticReportPartialResult, 100)
-	// for _, c := range caches {
-	// 	c := c
-	// 	eg.Go(func() error {
-	// 		c.StreamWorkspaceDiagnostics(ctx, reportsC)
-	// 		return nil
-	// 	})
-	// }
-	// go func() {
-	// 	for {
-	// 		select {
-	// 		case <-ctx.Done():
-	// 			return
-	// 		case report := <-reportsC:
-	// 			s.client.Progress(ctx, &protocol.ProgressParams{
-	// 				Token: params.PartialResultToken,
-	// 				Value: report,
-	// 			})
-	// 		}
-	// 	}
-	// }()
-	// eg.Wait()
-
-	// return &protocol.WorkspaceDiagnosticReport{
-	// 	Items: []protocol.Or_WorkspaceDocumentDiagnosticReport{},
-	// }, nil
+	s.cachesMu.RLock()
+	caches := maps.Clone(s.caches)
+	s.cachesMu.RUnlock()
+
+	prevResultIds := make(map[protocol.DocumentURI]string, len(params.PreviousResultIds))
+	for _, prev := range params.PreviousResultIds {
+		prevResultIds[prev.URI] = prev.Value
+	}
+
+	if params.PartialResultToken == nil {
+		// the client can't accept partial results, so return a snapshot of the
+		// current diagnostics for all workspaces
+		items := []protocol.WorkspaceDocumentDiagnosticReport{}
+		for _, c := range caches {
+			for _, report := range c.ComputeWorkspaceDiagnosticReports() {
+				items = append(items, workspaceReportItem(report, prevResultIds))
+			}
+		}
+		return &protocol.WorkspaceDiagnosticReport{
+			Items: items,
+		}, nil
+	}
+
+	// only one workspace diagnostic stream is kept open at a time; a new request
+	// replaces the previous one.
+	ctx, ca := context.WithCancel(ctx)
+	defer ca()
+	s.diagnosticStreamMu.Lock()
+	if s.diagnosticStreamCancel != nil {
+		s.diagnosticStreamCancel()
+	}
+	s.diagnosticStreamCancel = ca
+	s.diagnosticStreamMu.Unlock()
+
+	reportsC := make(chan protocol.WorkspaceFullDocumentDiagnosticReport, 100)
+	for _, c := range caches {
+		go c.StreamWorkspaceDiagnostics(ctx, reportsC)
+	}
+	for {
+		var report protocol.WorkspaceFullDocumentDiagnosticReport
+		select {
+		case <-ctx.Done():
+			// all results have been sent as partial results
+			return &protocol.WorkspaceDiagnosticReport{
+				Items: []protocol.WorkspaceDocumentDiagnosticReport{},
+			}, nil
+		case report = <-reportsC:
+		}
+		// send everything that is ready in a single batch
+		var batch []protocol.WorkspaceDocumentDiagnosticReport
+	DRAIN:
+		for {
+			batch = append(batch, workspaceReportItem(report, prevResultIds))
+			select {
+			case report = <-reportsC:
+			default:
+				break DRAIN
+			}
+		}
+		if err := s.client.Progress(ctx, &protocol.ProgressParams{
+			Token: *params.PartialResultToken,
+			Value: protocol.WorkspaceDiagnosticReportPartialResult{
+				Items: batch,
+			},
+		}); err != nil {
+			slog.Error("failed to send workspace diagnostics", "error", err)
+		}
+	}
+}
+
+// workspaceReportItem converts a full report into an unchanged report if the
+// client already has the same result id. Previous result ids only apply to the
+// first report for each uri; they are deleted from the map once used.
+func workspaceReportItem(report protocol.WorkspaceFullDocumentDiagnosticReport, prevResultIds map[protocol.DocumentURI]string) protocol.WorkspaceDocumentDiagnosticReport {
+	prev, ok := prevResultIds[report.URI]
+	if ok {
+		delete(prevResultIds, report.URI)
+		if prev == report.ResultID {
+			return protocol.WorkspaceDocumentDiagnosticReport{
+				Value: protocol.WorkspaceUnchangedDocumentDiagnosticReport{
+					URI:     report.URI,
+					Version: report.Version,
+					UnchangedDocumentDiagnosticReport: protocol.UnchangedDocumentDiagnosticReport{
+						Kind:     string(protocol.DiagnosticUnchanged),
+						ResultID: report.ResultID,
+					},
+				},
+			}
+		}
+	}
+	return protocol.WorkspaceDocumentDiagnosticReport{
+		Value: report,
+	}
 }
 
 // DocumentColor implements protocol.Server.
@@ -711,8 +821,12 @@ func (*Server) DocumentColor(context.Context, *protocol.DocumentColorParams) ([]
 }
 
 // DocumentHighlight implements protocol.Server.
-func (*Server) DocumentHighlight(context.Context, *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
-	return nil, nil
+func (s *Server) DocumentHighlight(ctx context.Context, params *protocol.DocumentHighlightParams) ([]protocol.DocumentHighlight, error) {
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindDocumentHighlights(params.TextDocumentPositionParams)
 }
 
 // DocumentLink implements protocol.Server.
@@ -779,7 +893,7 @@ func (s *Server) DidChangeWorkspaceFolders(ctx context.Context, params *protocol
 	for _, folder := range added {
 		path := protocol.DocumentURI(folder.URI).Path()
 		slog.Info("adding workspace folder", "path", path)
-		c := NewCache(folder)
+		c := NewCache(folder, WithSettings(s.initialSettings))
 		s.cacheInitLocked(c, path)
 	}
 	for _, folder := range removed {
@@ -874,8 +988,8 @@ func (s *Server) ResolveCodeAction(ctx context.Context, codeAction *protocol.Cod
 // DidChangeConfiguration implements protocol.Server.
 func (s *Server) DidChangeConfiguration(ctx context.Context, params *protocol.DidChangeConfigurationParams) error {
 	s.cachesMu.RLock()
-	defer s.cachesMu.RUnlock()
-
+	var reindex []*Cache
+	var reindexSettings []Settings
 	for _, c := range s.caches {
 		resp, err := s.client.Configuration(ctx, &protocol.ParamConfiguration{
 			Items: []protocol.ConfigurationItem{
@@ -893,23 +1007,52 @@ func (s *Server) DidChangeConfiguration(ctx context.Context, params *protocol.Di
 			slog.Error("unexpected number of configuration items received", "workspace", c.workspace.Name, "items", resp)
 			continue
 		}
-		var settings Settings
-		decoder, _ := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
-			ErrorUnused:      false,
-			ErrorUnset:       false,
-			ZeroFields:       false,
-			WeaklyTypedInput: true,
-			Result:           &settings,
-		})
-		if err := decoder.Decode(resp[0]); err != nil {
+		settings, err := decodeSettings(resp[0])
+		if err != nil {
 			slog.Error("failed to decode configuration", "workspace", c.workspace.Name, "error", err)
 			continue
 		}
+		if !slices.Equal(c.settings.Load().IncludePaths, settings.IncludePaths) {
+			// path mappings depend on the include paths, so the cache needs to be
+			// rebuilt for them to take effect
+			reindex = append(reindex, c)
+			reindexSettings = append(reindexSettings, settings)
+			continue
+		}
 		c.DidChangeConfiguration(ctx, settings)
 	}
+	s.cachesMu.RUnlock()
+
+	if len(reindex) == 0 {
+		return nil
+	}
+	s.cachesMu.Lock()
+	defer s.cachesMu.Unlock()
+	for i, c := range reindex {
+		if s.caches[protocol.DocumentURI(c.workspace.URI).Path()] != c {
+			continue // the workspace was removed or reindexed in the meantime
+		}
+		slog.Info("include paths changed, reindexing workspace", "workspace", c.workspace.Name)
+		s.reindexCacheLocked(ctx, c, reindexSettings[i])
+	}
 	return nil
 }
 
+func decodeSettings(input any) (Settings, error) {
+	var settings Settings
+	decoder, _ := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		ErrorUnused:      false,
+		ErrorUnset:       false,
+		ZeroFields:       false,
+		WeaklyTypedInput: true,
+		Result:           &settings,
+	})
+	if err := decoder.Decode(input); err != nil {
+		return Settings{}, err
+	}
+	return settings, nil
+}
+
 // =====================
 // Unimplemented Methods
 // =====================
@@ -929,13 +1072,21 @@ func (*Server) SignatureHelp(context.Context, *protocol.SignatureHelpParams) (*p
 }
 
 // Subtypes implements protocol.Server.
-func (*Server) Subtypes(context.Context, *protocol.TypeHierarchySubtypesParams) ([]protocol.TypeHierarchyItem, error) {
-	return nil, notImplemented("Subtypes")
+func (s *Server) Subtypes(ctx context.Context, params *protocol.TypeHierarchySubtypesParams) ([]protocol.TypeHierarchyItem, error) {
+	c, err := s.CacheForURI(params.Item.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindTypeHierarchySubtypes(ctx, params.Item)
 }
 
 // Supertypes implements protocol.Server.
-func (*Server) Supertypes(context.Context, *protocol.TypeHierarchySupertypesParams) ([]protocol.TypeHierarchyItem, error) {
-	return nil, notImplemented("Supertypes")
+func (s *Server) Supertypes(ctx context.Context, params *protocol.TypeHierarchySupertypesParams) ([]protocol.TypeHierarchyItem, error) {
+	c, err := s.CacheForURI(params.Item.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindTypeHierarchySupertypes(ctx, params.Item)
 }
 
 // TypeDefinition implements protocol.Server.
@@ -1019,8 +1170,12 @@ func (*Server) ResolveWorkspaceSymbol(context.Context, *protocol.WorkspaceSymbol
 }
 
 // SelectionRange implements protocol.Server.
-func (*Server) SelectionRange(context.Context, *protocol.SelectionRangeParams) ([]protocol.SelectionRange, error) {
-	return nil, notImplemented("SelectionRange")
+func (s *Server) SelectionRange(ctx context.Context, params *protocol.SelectionRangeParams) ([]protocol.SelectionRange, error) {
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.ComputeSelectionRanges(params.TextDocument, params.Positions)
 }
 
 // SetTrace implements protocol.Server.
@@ -1029,23 +1184,39 @@ func (*Server) SetTrace(context.Context, *protocol.SetTraceParams) error {
 }
 
 // OnTypeFormatting implements protocol.Server.
-func (*Server) OnTypeFormatting(context.Context, *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
-	return nil, notImplemented("OnTypeFormatting")
+func (s *Server) OnTypeFormatting(ctx context.Context, params *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.FormatOnType(params.TextDocument, params.Position, params.Ch)
 }
 
 // OutgoingCalls implements protocol.Server.
-func (*Server) OutgoingCalls(context.Context, *protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error) {
-	return nil, notImplemented("OutgoingCalls")
+func (s *Server) OutgoingCalls(ctx context.Context, params *protocol.CallHierarchyOutgoingCallsParams) ([]protocol.CallHierarchyOutgoingCall, error) {
+	c, err := s.CacheForURI(params.Item.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindOutgoingCalls(ctx, params.Item)
 }
 
 // PrepareCallHierarchy implements protocol.Server.
-func (*Server) PrepareCallHierarchy(context.Context, *protocol.CallHierarchyPrepareParams) ([]protocol.CallHierarchyItem, error) {
-	return nil, notImplemented("PrepareCallHierarchy")
+func (s *Server) PrepareCallHierarchy(ctx context.Context, params *protocol.CallHierarchyPrepareParams) ([]protocol.CallHierarchyItem, error) {
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.PrepareCallHierarchy(params.TextDocumentPositionParams)
 }
 
 // PrepareTypeHierarchy implements protocol.Server.
-func (*Server) PrepareTypeHierarchy(context.Context, *protocol.TypeHierarchyPrepareParams) ([]protocol.TypeHierarchyItem, error) {
-	return nil, notImplemented("PrepareTypeHierarchy")
+func (s *Server) PrepareTypeHierarchy(ctx context.Context, params *protocol.TypeHierarchyPrepareParams) ([]protocol.TypeHierarchyItem, error) {
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.PrepareTypeHierarchy(params.TextDocumentPositionParams)
 }
 
 // Progress implements protocol.Server.
@@ -1055,7 +1226,11 @@ func (*Server) Progress(context.Context, *protocol.ProgressParams) error {
 
 // RangeFormatting implements protocol.Server.
 func (s *Server) RangeFormatting(ctx context.Context, params *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
-	return nil, notImplemented("RangeFormatting")
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.FormatDocument(params.TextDocument, params.Options, params.Range)
 }
 
 // InlayHintRefresh implements protocol.Server.
@@ -1089,8 +1264,12 @@ func (s *Server) Implementation(ctx context.Context, params *protocol.Implementa
 }
 
 // IncomingCalls implements protocol.Server.
-func (*Server) IncomingCalls(context.Context, *protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error) {
-	return nil, notImplemented("IncomingCalls")
+func (s *Server) IncomingCalls(ctx context.Context, params *protocol.CallHierarchyIncomingCallsParams) ([]protocol.CallHierarchyIncomingCall, error) {
+	c, err := s.CacheForURI(params.Item.URI)
+	if err != nil {
+		return nil, err
+	}
+	return c.FindIncomingCalls(ctx, params.Item)
 }
 
 // DidChangeNotebookDocument implements protocol.Server.
@@ -1114,8 +1293,25 @@ func (*Server) DidSaveNotebookDocument(context.Context, *protocol.DidSaveNoteboo
 }
 
 // FoldingRange implements protocol.Server.
-func (*Server) FoldingRange(context.Context, *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
-	return nil, notImplemented("FoldingRange")
+func (s *Server) FoldingRange(ctx context.Context, params *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	var lineFoldingOnly bool
+	var rangeLimit int
+	if caps := s.clientCapabilities.TextDocument.FoldingRange; caps != nil {
+		lineFoldingOnly = caps.LineFoldingOnly
+		rangeLimit = int(caps.RangeLimit)
+	}
+	ranges, err := c.ComputeFoldingRanges(params.TextDocument, lineFoldingOnly)
+	if err != nil {
+		return nil, err
+	}
+	if rangeLimit > 0 && len(ranges) > rangeLimit {
+		ranges = ranges[:rangeLimit]
+	}
+	return ranges, nil
 }
 
 // InlineCompletion implements protocol.Server.
@@ -1124,8 +1320,15 @@ func (*Server) InlineCompletion(context.Context, *protocol.InlineCompletionParam
 }
 
 // RangesFormatting implements protocol.Server.
-func (*Server) RangesFormatting(context.Context, *protocol.DocumentRangesFormattingParams) ([]protocol.TextEdit, error) {
-	return nil, notImplemented("RangesFormatting")
+func (s *Server) RangesFormatting(ctx context.Context, params *protocol.DocumentRangesFormattingParams) ([]protocol.TextEdit, error) {
+	c, err := s.CacheForURI(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.Ranges) == 0 {
+		return nil, nil
+	}
+	return c.FormatDocument(params.TextDocument, params.Options, params.Ranges...)
 }
 
 // DiagnosticRefresh implements protocol.Server.
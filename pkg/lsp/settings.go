@@ -2,6 +2,13 @@ package lsp
 
 type Settings struct {
 	InlayHints InlayHintsSettings `mapstructure:"inlayHints"`
+	References ReferencesSettings `mapstructure:"references"`
+	Rename     RenameSettings     `mapstructure:"rename"`
+
+	// IncludePaths lists directories to search for imported files, in order of
+	// precedence, similar to the --proto_path flags accepted by protoc. Relative
+	// paths are resolved against the workspace root.
+	IncludePaths []string `mapstructure:"includePaths"`
 }
 
 type InlayHintsSettings struct {
@@ -22,3 +29,29 @@ func (s *InlayHintsSettings) GetImports() bool {
 	}
 	return *s.Imports
 }
+
+type ReferencesSettings struct {
+	// IncludeGoCode enables searching for references to generated Go code
+	// in the local Go module.
+	IncludeGoCode *bool `mapstructure:"includeGoCode"`
+}
+
+func (s *ReferencesSettings) GetIncludeGoCode() bool {
+	if s.IncludeGoCode == nil {
+		return false
+	}
+	return *s.IncludeGoCode
+}
+
+type RenameSettings struct {
+	// IncludeGoCode enables editing references to generated Go code in the
+	// local Go module when renaming symbols.
+	IncludeGoCode *bool `mapstructure:"includeGoCode"`
+}
+
+func (s *RenameSettings) GetIncludeGoCode() bool {
+	if s.IncludeGoCode == nil {
+		return false
+	}
+	return *s.IncludeGoCode
+}
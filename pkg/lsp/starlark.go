@@ -0,0 +1,434 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// This file implements a parser for the subset of Starlark needed to read the
+// attributes of rules declared in BUILD files. Only string and list-of-string
+// values are evaluated, including concatenation with '+', references to
+// variables assigned at the top level of the file, and calls to glob(). Any
+// other expressions are skipped.
+
+type starlarkTokenKind int
+
+const (
+	starlarkEOF starlarkTokenKind = iota
+	starlarkIdent
+	starlarkString
+	starlarkNumber
+	starlarkPunct
+)
+
+type starlarkToken struct {
+	kind starlarkTokenKind
+	text string // identifier name, unquoted string value, or punctuation
+	line int
+}
+
+// tokenizeStarlark splits the source into tokens. Newlines and indentation
+// are not significant for the subset of the language being parsed, so they
+// are not included.
+func tokenizeStarlark(src []byte) ([]starlarkToken, error) {
+	var tokens []starlarkToken
+	s := string(src)
+	line := 1
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\\':
+			i++
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '"' || c == '\'' ||
+			((c == 'r' || c == 'b') && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\'')):
+			raw := false
+			if c == 'r' || c == 'b' {
+				raw = c == 'r'
+				i++
+			}
+			value, n, lines, err := scanStarlarkString(s[i:], raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			tokens = append(tokens, starlarkToken{kind: starlarkString, text: value, line: line})
+			line += lines
+			i += n
+		case c == '_' || c < utf8.RuneSelf && unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(s) && (s[i] == '_' || s[i] < utf8.RuneSelf && (unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i])))) {
+				i++
+			}
+			tokens = append(tokens, starlarkToken{kind: starlarkIdent, text: s[start:i], line: line})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(s) && (s[i] == '.' || s[i] == '_' || s[i] < utf8.RuneSelf && (unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i])))) {
+				i++
+			}
+			tokens = append(tokens, starlarkToken{kind: starlarkNumber, text: s[start:i], line: line})
+		default:
+			n := 1
+			if i+1 < len(s) && strings.Contains("=!<>+-*/%|&^", string(c)) && s[i+1] == '=' {
+				n = 2
+			} else if i+1 < len(s) && (s[i:i+2] == "**" || s[i:i+2] == "//" || s[i:i+2] == "->") {
+				n = 2
+			}
+			tokens = append(tokens, starlarkToken{kind: starlarkPunct, text: s[i : i+n], line: line})
+			i += n
+		}
+	}
+	tokens = append(tokens, starlarkToken{kind: starlarkEOF, line: line})
+	return tokens, nil
+}
+
+// scanStarlarkString scans a string literal at the start of s, returning its
+// value, the number of bytes consumed, and the number of newlines it spans.
+func scanStarlarkString(s string, raw bool) (string, int, int, error) {
+	quote := s[:1]
+	if strings.HasPrefix(s, strings.Repeat(quote, 3)) {
+		quote = s[:3]
+	}
+	var sb strings.Builder
+	lines := 0
+	for i := len(quote); i < len(s); {
+		if strings.HasPrefix(s[i:], quote) {
+			return sb.String(), i + len(quote), lines, nil
+		}
+		c := s[i]
+		if c == '\n' {
+			if len(quote) == 1 {
+				break
+			}
+			lines++
+		}
+		if c == '\\' && i+1 < len(s) {
+			next := s[i+1]
+			if raw {
+				sb.WriteByte(c)
+				sb.WriteByte(next)
+			} else {
+				switch next {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				case 'r':
+					sb.WriteByte('\r')
+				case '\n':
+					lines++
+				case '\\', '\'', '"':
+					sb.WriteByte(next)
+				default:
+					sb.WriteByte(c)
+					sb.WriteByte(next)
+				}
+			}
+			if raw && next == '\n' {
+				lines++
+			}
+			i += 2
+			continue
+		}
+		sb.WriteByte(c)
+		i++
+	}
+	return "", 0, 0, fmt.Errorf("unterminated string literal")
+}
+
+// starlarkValue is the result of evaluating an expression. Values which are
+// neither strings nor lists of strings are not valid.
+type starlarkValue struct {
+	valid  bool
+	isList bool
+	str    string
+	list   []string
+}
+
+type starlarkCall struct {
+	args   []starlarkValue
+	kwargs map[string]starlarkValue
+}
+
+func (c starlarkCall) stringAttr(name string) string {
+	return c.stringAttrDefault(name, "")
+}
+
+func (c starlarkCall) stringAttrDefault(name, defaultValue string) string {
+	if v, ok := c.kwargs[name]; ok && v.valid && !v.isList {
+		return v.str
+	}
+	return defaultValue
+}
+
+func (c starlarkCall) listAttr(name string) []string {
+	if v, ok := c.kwargs[name]; ok && v.valid && v.isList {
+		return v.list
+	}
+	return nil
+}
+
+type starlarkParser struct {
+	tokens []starlarkToken
+	pos    int
+	depth  int // nesting level of brackets around the current expression
+	vars   map[string]starlarkValue
+	glob   func(include, exclude []string) []string
+}
+
+// parseStarlarkCalls returns the keyword arguments of each call to the named
+// function in the source. The glob function is called to evaluate calls to
+// glob() within arguments.
+func parseStarlarkCalls(src []byte, function string, glob func(include, exclude []string) []string) ([]starlarkCall, error) {
+	tokens, err := tokenizeStarlark(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &starlarkParser{
+		tokens: tokens,
+		vars:   map[string]starlarkValue{},
+		glob:   glob,
+	}
+	var calls []starlarkCall
+	for p.peek().kind != starlarkEOF {
+		tok := p.next()
+		switch {
+		case tok.kind == starlarkIdent && p.peekIs("(") && !p.prevIs("."):
+			if tok.text == function {
+				calls = append(calls, p.parseCall())
+			} else {
+				p.skipBalanced()
+			}
+		case tok.kind == starlarkIdent && p.peekIs("=") && !p.prevIs("."):
+			p.next()
+			p.vars[tok.text] = p.parseExpr()
+		case tok.kind == starlarkIdent && p.peekIs("+=") && !p.prevIs("."):
+			p.next()
+			p.vars[tok.text] = concatStarlarkValues(p.vars[tok.text], p.parseExpr())
+		case tok.kind == starlarkPunct && (tok.text == "(" || tok.text == "[" || tok.text == "{"):
+			p.pos--
+			p.skipBalanced()
+		}
+	}
+	return calls, nil
+}
+
+func (p *starlarkParser) peek() starlarkToken {
+	return p.tokens[p.pos]
+}
+
+func (p *starlarkParser) next() starlarkToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != starlarkEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *starlarkParser) peekIs(punct string) bool {
+	tok := p.peek()
+	return tok.kind == starlarkPunct && tok.text == punct
+}
+
+// prevIs reports whether the token before the most recently consumed token
+// is the given punctuation.
+func (p *starlarkParser) prevIs(punct string) bool {
+	if p.pos < 2 {
+		return false
+	}
+	tok := p.tokens[p.pos-2]
+	return tok.kind == starlarkPunct && tok.text == punct
+}
+
+// skipBalanced skips the next token, and if it is an opening bracket, all
+// tokens up to and including the matching closing bracket.
+func (p *starlarkParser) skipBalanced() {
+	depth := 0
+	for {
+		tok := p.next()
+		if tok.kind == starlarkEOF {
+			return
+		}
+		if tok.kind == starlarkPunct {
+			switch tok.text {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+		}
+		if depth <= 0 {
+			return
+		}
+	}
+}
+
+// skipExpr skips tokens up to the next ',' or closing bracket at the current
+// nesting level, or the start of the next statement at the top level.
+func (p *starlarkParser) skipExpr() {
+	for {
+		tok := p.peek()
+		if tok.kind == starlarkEOF || p.atNextStatement() {
+			return
+		}
+		if tok.kind == starlarkPunct {
+			switch tok.text {
+			case ",", ")", "]", "}":
+				return
+			case "(", "[", "{":
+				p.skipBalanced()
+				continue
+			}
+		}
+		p.next()
+	}
+}
+
+// parseCall parses the arguments of a call, starting at the opening paren.
+func (p *starlarkParser) parseCall() starlarkCall {
+	call := starlarkCall{kwargs: map[string]starlarkValue{}}
+	p.next() // (
+	p.depth++
+	defer func() { p.depth-- }()
+	for !p.peekIs(")") && p.peek().kind != starlarkEOF {
+		if p.peek().kind == starlarkIdent && p.tokens[p.pos+1].kind == starlarkPunct && p.tokens[p.pos+1].text == "=" {
+			name := p.next().text
+			p.next() // =
+			call.kwargs[name] = p.parseExpr()
+		} else {
+			call.args = append(call.args, p.parseExpr())
+		}
+		if !p.peekIs(",") {
+			break
+		}
+		p.next()
+	}
+	if p.peekIs(")") {
+		p.next()
+	} else {
+		p.skipExpr()
+	}
+	return call
+}
+
+func (p *starlarkParser) parseExpr() starlarkValue {
+	value := p.parseOperand()
+	for p.peekIs("+") {
+		p.next()
+		value = concatStarlarkValues(value, p.parseOperand())
+	}
+	if !p.atExprEnd() {
+		p.skipExpr()
+		return starlarkValue{}
+	}
+	return value
+}
+
+func (p *starlarkParser) atExprEnd() bool {
+	tok := p.peek()
+	if tok.kind == starlarkEOF {
+		return true
+	}
+	if tok.kind == starlarkPunct {
+		switch tok.text {
+		case ",", ")", "]", "}":
+			return true
+		}
+	}
+	return p.atNextStatement()
+}
+
+// atNextStatement reports whether the next token begins a new statement,
+// which is assumed when an identifier is found at the start of a line outside
+// of any brackets.
+func (p *starlarkParser) atNextStatement() bool {
+	tok := p.peek()
+	return p.depth == 0 && p.pos > 0 && tok.kind == starlarkIdent && tok.line > p.tokens[p.pos-1].line
+}
+
+func (p *starlarkParser) parseOperand() starlarkValue {
+	tok := p.peek()
+	switch {
+	case tok.kind == starlarkString:
+		p.next()
+		return starlarkValue{valid: true, str: tok.text}
+	case tok.kind == starlarkPunct && tok.text == "[":
+		p.next()
+		p.depth++
+		defer func() { p.depth-- }()
+		value := starlarkValue{valid: true, isList: true, list: []string{}}
+		for !p.peekIs("]") && p.peek().kind != starlarkEOF {
+			elem := p.parseExpr()
+			if !elem.valid || elem.isList {
+				value.valid = false
+			} else {
+				value.list = append(value.list, elem.str)
+			}
+			if !p.peekIs(",") {
+				break
+			}
+			p.next()
+		}
+		if !p.peekIs("]") {
+			// e.g. a list comprehension
+			p.skipExpr()
+			value.valid = false
+		}
+		p.next()
+		return value
+	case tok.kind == starlarkIdent && tok.text == "glob" && p.tokens[p.pos+1].text == "(":
+		p.next()
+		return p.parseGlobCall()
+	case tok.kind == starlarkIdent && p.tokens[p.pos+1].text != "(":
+		p.next()
+		if v, ok := p.vars[tok.text]; ok {
+			return v
+		}
+		return starlarkValue{}
+	}
+	p.skipExpr()
+	return starlarkValue{}
+}
+
+// parseGlobCall evaluates a call to glob(include, exclude = [...]), starting
+// at the opening paren.
+func (p *starlarkParser) parseGlobCall() starlarkValue {
+	call := p.parseCall()
+	include, ok := call.kwargs["include"]
+	if !ok && len(call.args) > 0 {
+		include = call.args[0]
+	}
+	if !include.valid || !include.isList || p.glob == nil {
+		return starlarkValue{}
+	}
+	return starlarkValue{
+		valid:  true,
+		isList: true,
+		list:   p.glob(include.list, call.listAttr("exclude")),
+	}
+}
+
+// concatStarlarkValues evaluates a + b. If one operand is a list and the other
+// could not be evaluated (e.g. a call to select()), the list is returned, so
+// that the known elements of a list are not discarded.
+func concatStarlarkValues(a, b starlarkValue) starlarkValue {
+	if a.valid && a.isList && !b.valid {
+		return a
+	} else if b.valid && b.isList && !a.valid {
+		return b
+	}
+	if !a.valid || !b.valid || a.isList != b.isList {
+		return starlarkValue{}
+	}
+	if a.isList {
+		return starlarkValue{valid: true, isList: true, list: append(append([]string{}, a.list...), b.list...)}
+	}
+	return starlarkValue{valid: true, str: a.str + b.str}
+}
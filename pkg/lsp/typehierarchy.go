@@ -0,0 +1,208 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Protobuf has no inheritance, so the type hierarchy is built from the
+// relationships between messages instead:
+//
+//   - The supertypes of a message are the messages it is used in, either as
+//     the type of a field, or as the type of an extension (in which case the
+//     supertype is the extendee).
+//   - The subtypes of a message are the message types of its fields, and the
+//     extensions which extend it.
+//
+// Extensions of a scalar or enum type appear in the hierarchy as fields, since
+// they have no message type of their own.
+
+func (c *Cache) PrepareTypeHierarchy(params protocol.TextDocumentPositionParams) ([]protocol.TypeHierarchyItem, error) {
+	desc, _, err := c.FindTypeDescriptorAtLocation(params)
+	if err != nil {
+		return nil, err
+	}
+	if !isTypeHierarchyDescriptor(desc) {
+		return nil, nil
+	}
+	c.resultsMu.RLock()
+	defer c.resultsMu.RUnlock()
+	item, err := c.toTypeHierarchyItemLocked(desc)
+	if err != nil {
+		return nil, err
+	}
+	return []protocol.TypeHierarchyItem{item}, nil
+}
+
+func (c *Cache) FindTypeHierarchySupertypes(ctx context.Context, item protocol.TypeHierarchyItem) ([]protocol.TypeHierarchyItem, error) {
+	c.resultsMu.RLock()
+	defer c.resultsMu.RUnlock()
+	desc, err := c.findTypeHierarchyDescriptorLocked(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var supertypes []protoreflect.Descriptor
+	switch desc := desc.(type) {
+	case protoreflect.MessageDescriptor:
+		var mu sync.Mutex
+		err := c.rangeAllDescriptorsLocked(ctx, func(d protoreflect.Descriptor) bool {
+			fld, ok := d.(protoreflect.FieldDescriptor)
+			if !ok || fld.Message() == nil || fld.Message().FullName() != desc.FullName() {
+				return true
+			}
+			parent := fld.ContainingMessage()
+			if parent.IsMapEntry() {
+				parent, _ = parent.Parent().(protoreflect.MessageDescriptor)
+			}
+			if parent != nil {
+				mu.Lock()
+				supertypes = append(supertypes, parent)
+				mu.Unlock()
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	case protoreflect.FieldDescriptor:
+		supertypes = append(supertypes, desc.ContainingMessage())
+	}
+	return c.toTypeHierarchyItemsLocked(supertypes), nil
+}
+
+func (c *Cache) FindTypeHierarchySubtypes(ctx context.Context, item protocol.TypeHierarchyItem) ([]protocol.TypeHierarchyItem, error) {
+	c.resultsMu.RLock()
+	defer c.resultsMu.RUnlock()
+	desc, err := c.findTypeHierarchyDescriptorLocked(item)
+	if err != nil {
+		return nil, err
+	}
+
+	var subtypes []protoreflect.Descriptor
+	switch desc := desc.(type) {
+	case protoreflect.MessageDescriptor:
+		fields := desc.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fld := fields.Get(i)
+			if fld.IsMap() {
+				fld = fld.MapValue()
+			}
+			if fld.Message() != nil {
+				subtypes = append(subtypes, fld.Message())
+			}
+		}
+		for _, res := range c.results {
+			if res.IsPlaceholder() {
+				continue
+			}
+			for _, ext := range res.(linker.Result).FindExtensionsByMessage(desc.FullName()) {
+				if ext.Message() != nil {
+					subtypes = append(subtypes, ext.Message())
+				} else {
+					subtypes = append(subtypes, ext)
+				}
+			}
+		}
+	case protoreflect.FieldDescriptor:
+		if desc.Message() != nil {
+			subtypes = append(subtypes, desc.Message())
+		}
+	}
+	return c.toTypeHierarchyItemsLocked(subtypes), nil
+}
+
+func isTypeHierarchyDescriptor(desc protoreflect.Descriptor) bool {
+	switch desc := desc.(type) {
+	case protoreflect.MessageDescriptor:
+		return !desc.IsPlaceholder() && !desc.IsMapEntry()
+	case protoreflect.FieldDescriptor:
+		return desc.IsExtension()
+	}
+	return false
+}
+
+func (c *Cache) findTypeHierarchyDescriptorLocked(item protocol.TypeHierarchyItem) (protoreflect.Descriptor, error) {
+	name, ok := item.Data.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid type hierarchy item data: %v", item.Data)
+	}
+	desc, err := c.results.AsResolver().FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, err
+	}
+	if !isTypeHierarchyDescriptor(desc) {
+		return nil, fmt.Errorf("%s is not a message or extension", name)
+	}
+	return desc, nil
+}
+
+// toTypeHierarchyItemsLocked converts the given descriptors to type hierarchy
+// items, removing duplicates and any descriptors which cannot be located.
+func (c *Cache) toTypeHierarchyItemsLocked(descs []protoreflect.Descriptor) []protocol.TypeHierarchyItem {
+	slices.SortFunc(descs, func(a, b protoreflect.Descriptor) int {
+		return strings.Compare(string(a.FullName()), string(b.FullName()))
+	})
+	descs = slices.CompactFunc(descs, func(a, b protoreflect.Descriptor) bool {
+		return a.FullName() == b.FullName()
+	})
+	items := make([]protocol.TypeHierarchyItem, 0, len(descs))
+	for _, desc := range descs {
+		if !isTypeHierarchyDescriptor(desc) {
+			continue
+		}
+		item, err := c.toTypeHierarchyItemLocked(desc)
+		if err != nil {
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func (c *Cache) toTypeHierarchyItemLocked(desc protoreflect.Descriptor) (protocol.TypeHierarchyItem, error) {
+	if xt, ok := desc.(protoreflect.ExtensionTypeDescriptor); ok {
+		desc = xt.Descriptor()
+	}
+	parentFile := desc.ParentFile()
+	if parentFile == nil {
+		return protocol.TypeHierarchyItem{}, fmt.Errorf("no parent file found for descriptor")
+	}
+	linkRes, err := c.findResultOrPartialResultByPathLocked(parentFile.Path())
+	if err != nil {
+		return protocol.TypeHierarchyItem{}, err
+	}
+	uri, err := c.resolver.PathToURI(parentFile.Path())
+	if err != nil {
+		return protocol.TypeHierarchyItem{}, err
+	}
+	wrapper, ok := desc.(protoutil.DescriptorProtoWrapper)
+	if !ok {
+		return protocol.TypeHierarchyItem{}, fmt.Errorf("no source information for %s", desc.FullName())
+	}
+	node := linkRes.Node(wrapper.AsProto())
+	if node == nil {
+		return protocol.TypeHierarchyItem{}, fmt.Errorf("no source information for %s", desc.FullName())
+	}
+	ref, err := findDefinition(desc, linkRes)
+	if err != nil {
+		return protocol.TypeHierarchyItem{}, err
+	}
+	return protocol.TypeHierarchyItem{
+		Name:           string(desc.Name()),
+		Kind:           symbolKind(desc),
+		Detail:         string(desc.FullName()),
+		URI:            uri,
+		Range:          toRange(linkRes.AST().NodeInfo(node)),
+		SelectionRange: toRange(ref.NodeInfo),
+		Data:           string(desc.FullName()),
+	}, nil
+}
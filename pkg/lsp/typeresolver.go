@@ -0,0 +1,109 @@
+package lsp
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// TypeResolver resolves message, enum, and extension types from the files in
+// a Cache, falling back to protoregistry.GlobalTypes for types which are not
+// found there. It provides the same lookup methods as *protoregistry.Types,
+// and can be used as the Resolver in the options for proto.Unmarshal and the
+// prototext and protojson packages, so that Any messages and extensions
+// defined in the workspace can be expanded.
+type TypeResolver struct {
+	cache *Cache
+}
+
+var (
+	_ protoregistry.MessageTypeResolver   = (*TypeResolver)(nil)
+	_ protoregistry.ExtensionTypeResolver = (*TypeResolver)(nil)
+)
+
+// XGetTypeResolver returns a TypeResolver backed by the cache.
+func (c *Cache) XGetTypeResolver() *TypeResolver {
+	return &TypeResolver{cache: c}
+}
+
+// FindMessageByName implements protoregistry.MessageTypeResolver.
+func (r *TypeResolver) FindMessageByName(message protoreflect.FullName) (protoreflect.MessageType, error) {
+	mt, err := r.cache.FindMessageByName(message)
+	if errors.Is(err, protoregistry.NotFound) {
+		return protoregistry.GlobalTypes.FindMessageByName(message)
+	}
+	return mt, err
+}
+
+// FindMessageByURL implements protoregistry.MessageTypeResolver.
+func (r *TypeResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	mt, err := r.cache.FindMessageByURL(url)
+	if errors.Is(err, protoregistry.NotFound) {
+		return protoregistry.GlobalTypes.FindMessageByURL(url)
+	}
+	return mt, err
+}
+
+// FindExtensionByName implements protoregistry.ExtensionTypeResolver.
+func (r *TypeResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	xt, err := r.cache.FindExtensionByName(field)
+	if errors.Is(err, protoregistry.NotFound) {
+		return protoregistry.GlobalTypes.FindExtensionByName(field)
+	}
+	return xt, err
+}
+
+// FindExtensionByNumber implements protoregistry.ExtensionTypeResolver.
+func (r *TypeResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	xt, err := r.cache.FindExtensionByNumber(message, field)
+	if errors.Is(err, protoregistry.NotFound) {
+		return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+	}
+	return xt, err
+}
+
+// FindEnumByName looks up an enum by its full name.
+func (r *TypeResolver) FindEnumByName(enum protoreflect.FullName) (protoreflect.EnumType, error) {
+	d, err := r.cache.FindDescriptorByName(enum)
+	if errors.Is(err, protoregistry.NotFound) {
+		return protoregistry.GlobalTypes.FindEnumByName(enum)
+	} else if err != nil {
+		return nil, err
+	}
+	ed, ok := d.(protoreflect.EnumDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an enum", enum)
+	}
+	return dynamicpb.NewEnumType(ed), nil
+}
+
+// RangeExtensionsByMessage iterates over all extensions of the given message,
+// including those in the global registry which are not defined in the cache,
+// until f returns false.
+func (r *TypeResolver) RangeExtensionsByMessage(message protoreflect.FullName, f func(protoreflect.ExtensionType) bool) {
+	seen := map[protoreflect.FieldNumber]bool{}
+	for _, xd := range r.cache.FindExtensionsByMessage(message) {
+		if seen[xd.Number()] {
+			continue
+		}
+		seen[xd.Number()] = true
+		var xt protoreflect.ExtensionType
+		if xtd, ok := xd.(protoreflect.ExtensionTypeDescriptor); ok {
+			xt = xtd.Type()
+		} else {
+			xt = dynamicpb.NewExtensionType(xd)
+		}
+		if !f(xt) {
+			return
+		}
+	}
+	protoregistry.GlobalTypes.RangeExtensionsByMessage(message, func(xt protoreflect.ExtensionType) bool {
+		if seen[xt.TypeDescriptor().Number()] {
+			return true
+		}
+		return f(xt)
+	})
+}
@@ -0,0 +1,83 @@
+package lsp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestTypeResolver(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"ex/ex.proto": `
+syntax = "proto2";
+package ex;
+import "google/protobuf/any.proto";
+message Env {
+  optional google.protobuf.Any payload = 1;
+  extensions 100 to 200;
+}
+message Inner {
+  optional string name = 1;
+}
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+}
+extend Env {
+  optional string note = 100;
+}
+`,
+	})
+	cache := NewCache(protocol.WorkspaceFolder{URI: string(protocol.URIFromPath(dir))})
+	cache.LoadFiles([]string{filepath.Join(dir, "ex/ex.proto")})
+	resolver := cache.XGetTypeResolver()
+
+	mt, err := resolver.FindMessageByURL("type.googleapis.com/ex.Inner")
+	require.NoError(t, err)
+	require.Equal(t, protoreflect.FullName("ex.Inner"), mt.Descriptor().FullName())
+
+	// types which are not in the workspace come from the global registry
+	mt, err = resolver.FindMessageByName("google.protobuf.Duration")
+	require.NoError(t, err)
+	require.Equal(t, protoreflect.FullName("google.protobuf.Duration"), mt.Descriptor().FullName())
+
+	et, err := resolver.FindEnumByName("ex.Kind")
+	require.NoError(t, err)
+	require.Equal(t, protoreflect.FullName("ex.Kind"), et.Descriptor().FullName())
+	_, err = resolver.FindEnumByName("ex.Inner")
+	require.ErrorContains(t, err, "not an enum")
+
+	xt, err := resolver.FindExtensionByNumber("ex.Env", 100)
+	require.NoError(t, err)
+	require.Equal(t, protoreflect.FullName("ex.note"), xt.TypeDescriptor().FullName())
+	var extensions []protoreflect.FullName
+	resolver.RangeExtensionsByMessage("ex.Env", func(xt protoreflect.ExtensionType) bool {
+		extensions = append(extensions, xt.TypeDescriptor().FullName())
+		return true
+	})
+	require.Equal(t, []protoreflect.FullName{"ex.note"}, extensions)
+
+	envType, err := resolver.FindMessageByName("ex.Env")
+	require.NoError(t, err)
+	env := dynamicpb.NewMessage(envType.Descriptor())
+	err = prototext.UnmarshalOptions{Resolver: resolver}.Unmarshal([]byte(`
+payload { [type.googleapis.com/ex.Inner] { name: "hi" } }
+[ex.note]: "n"
+`), env)
+	require.NoError(t, err)
+	wire, err := proto.Marshal(env)
+	require.NoError(t, err)
+
+	decoded := dynamicpb.NewMessage(envType.Descriptor())
+	require.NoError(t, proto.UnmarshalOptions{Resolver: resolver}.Unmarshal(wire, decoded))
+	text, err := prototext.MarshalOptions{Resolver: resolver}.Marshal(decoded)
+	require.NoError(t, err)
+	require.Contains(t, string(text), `[type.googleapis.com/ex.Inner]:`)
+	require.Contains(t, string(text), `[ex.note]:`)
+}
@@ -0,0 +1,79 @@
+package lsp
+
+import (
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// workspaceFiles finds the buf.yaml and BUILD files of a workspace. Both are
+// found with a single walk of the workspace, which happens the first time
+// either is needed.
+type workspaceFiles struct {
+	dir  string
+	once sync.Once
+
+	bufYamlDirs []string // directories containing a buf.yaml file
+	buildFiles  []string // BUILD and BUILD.bazel files
+	err         error
+}
+
+func newWorkspaceFiles(dir string) *workspaceFiles {
+	return &workspaceFiles{dir: dir}
+}
+
+// BufYamlDirs returns the directories containing a buf.yaml file, excluding
+// directories skipped by skipBufSearchDir.
+func (f *workspaceFiles) BufYamlDirs() ([]string, error) {
+	f.once.Do(f.walk)
+	return f.bufYamlDirs, f.err
+}
+
+// BuildFiles returns the BUILD and BUILD.bazel files, excluding directories
+// skipped by skipBazelSearchDir.
+func (f *workspaceFiles) BuildFiles() ([]string, error) {
+	f.once.Do(f.walk)
+	return f.buildFiles, f.err
+}
+
+func (f *workspaceFiles) walk() {
+	// directories skipped by only one of the searches are still walked; these
+	// are the skipped directories currently being walked, if any
+	var bufSkipped, bazelSkipped string
+	f.err = filepath.WalkDir(f.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if bufSkipped != "" && !isWithinDir(path, bufSkipped) {
+			bufSkipped = ""
+		}
+		if bazelSkipped != "" && !isWithinDir(path, bazelSkipped) {
+			bazelSkipped = ""
+		}
+		if d.IsDir() {
+			if path == f.dir {
+				return nil
+			}
+			skipBuf := bufSkipped != "" || skipBufSearchDir(d.Name())
+			skipBazel := bazelSkipped != "" || skipBazelSearchDir(d.Name())
+			if skipBuf && skipBazel {
+				return fs.SkipDir
+			}
+			if skipBuf && bufSkipped == "" {
+				bufSkipped = path
+			}
+			if skipBazel && bazelSkipped == "" {
+				bazelSkipped = path
+			}
+			return nil
+		}
+		switch {
+		case d.Name() == "buf.yaml" && bufSkipped == "":
+			f.bufYamlDirs = append(f.bufYamlDirs, filepath.Dir(path))
+		case slices.Contains(bazelBuildFiles, d.Name()) && bazelSkipped == "":
+			f.buildFiles = append(f.buildFiles, path)
+		}
+		return nil
+	})
+}
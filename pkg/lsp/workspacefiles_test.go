@@ -0,0 +1,47 @@
+package lsp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"BUILD.bazel":                "",
+		"a/buf.yaml":                 "",
+		"a/BUILD":                    "",
+		"vendor/x/buf.yaml":          "",
+		"vendor/x/BUILD":             "",
+		"bazel-out/y/buf.yaml":       "",
+		"bazel-out/y/BUILD.bazel":    "",
+		"bazel-out/vendor/buf.yaml":  "",
+		"node_modules/z/buf.yaml":    "",
+		"node_modules/z/BUILD.bazel": "",
+		".git/buf.yaml":              "",
+		"zz/buf.yaml":                "",
+		"zz/BUILD":                   "",
+	})
+
+	files := newWorkspaceFiles(dir)
+	bufYamlDirs, err := files.BufYamlDirs()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "a"),
+		filepath.Join(dir, "bazel-out/y"),
+		filepath.Join(dir, "zz"),
+	}, bufYamlDirs)
+
+	// the workspace is only walked once
+	writeFiles(t, dir, map[string]string{"b/BUILD": ""})
+	buildFiles, err := files.BuildFiles()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "BUILD.bazel"),
+		filepath.Join(dir, "a/BUILD"),
+		filepath.Join(dir, "vendor/x/BUILD"),
+		filepath.Join(dir, "zz/BUILD"),
+	}, buildFiles)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/kralicky/codegen/cli"
@@ -107,12 +108,20 @@ func (h *unknownHandler) Execute(ctx context.Context, uc lsp.UnknownCommand) (an
 		if uc.Cache == nil {
 			return nil, errors.New("no cache available")
 		}
-		return nil, h.doGenerate(ctx, uc.Cache, req.URIs)
+		generators, err := h.generators(uc.Cache)
+		if err != nil {
+			return nil, err
+		}
+		return nil, h.doGenerate(ctx, uc.Cache, generators, req.URIs)
 	case "protols/generateWorkspace":
 		if uc.Cache == nil {
 			return nil, errors.New("no cache available")
 		}
-		return nil, h.doGenerate(ctx, uc.Cache, uc.Cache.XListWorkspaceLocalURIs())
+		generators, err := h.generators(uc.Cache)
+		if err != nil {
+			return nil, err
+		}
+		return nil, h.doGenerate(ctx, uc.Cache, generators, uc.Cache.XListWorkspaceLocalURIs())
 	default:
 		panic("unknown command: " + uc.Command)
 	}
@@ -120,7 +129,20 @@ func (h *unknownHandler) Execute(ctx context.Context, uc lsp.UnknownCommand) (an
 
 var _ lsp.UnknownCommandHandler = (*unknownHandler)(nil)
 
-func (h *unknownHandler) doGenerate(ctx context.Context, cache *lsp.Cache, uris []protocol.DocumentURI) error {
+// generators returns the generators listed in the workspace's generation
+// config, or the handler's default generators if there is no config.
+func (h *unknownHandler) generators(cache *lsp.Cache) ([]codegen.Generator, error) {
+	config, err := cache.GenerateConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return h.Generators, nil
+	}
+	return codegen.NewConfiguredGenerators(config)
+}
+
+func (h *unknownHandler) doGenerate(ctx context.Context, cache *lsp.Cache, generators []codegen.Generator, uris []protocol.DocumentURI) error {
 	pathMappings := cache.XGetURIPathMappings()
 	roots := make(linker.Files, 0, len(uris))
 	outputDirs := map[string]string{}
@@ -156,37 +178,48 @@ func (h *unknownHandler) doGenerate(ctx context.Context, cache *lsp.Cache, uris
 	if err != nil {
 		return err
 	}
-	for _, g := range h.Generators {
-		if err := g.Generate(plugin); err != nil {
-			return err
-		}
-	}
-	response := plugin.Response()
-	if response.Error != nil {
-		return errors.New(response.GetError())
-	}
+	workdir := cache.XGetWorkdir()
 	var errs error
-	for _, rf := range response.GetFile() {
-		dir, ok := outputDirs[path.Dir(rf.GetName())]
-		if !ok {
-			errs = errors.Join(errs, fmt.Errorf("cannot write outside of workspace module: %s", rf.GetName()))
-			continue
+	for _, g := range generators {
+		files, err := codegen.Run(g, plugin.Request)
+		if err != nil {
+			return err
 		}
-		absPath := path.Join(dir, path.Base(rf.GetName()))
-		if info, err := os.Stat(absPath); err == nil {
-			original, err := os.ReadFile(absPath)
-			if err != nil {
-				return err
-			}
-			updated := rf.GetContent()
-			if err := util.OverwriteFile(absPath, original, []byte(updated), info.Mode().Perm(), info.Size()); err != nil {
-				return err
+		outputDir := codegen.OutputDir(g)
+		for _, rf := range files {
+			var absPath string
+			if outputDir != "" {
+				if !filepath.IsLocal(filepath.FromSlash(rf.GetName())) {
+					errs = errors.Join(errs, fmt.Errorf("cannot write outside of output directory: %s", rf.GetName()))
+					continue
+				}
+				absPath = filepath.Join(workdir, outputDir, filepath.FromSlash(rf.GetName()))
+				if err := os.MkdirAll(filepath.Dir(absPath), 0o755); err != nil {
+					return err
+				}
+			} else {
+				dir, ok := outputDirs[path.Dir(rf.GetName())]
+				if !ok {
+					errs = errors.Join(errs, fmt.Errorf("cannot write outside of workspace module: %s", rf.GetName()))
+					continue
+				}
+				absPath = path.Join(dir, path.Base(rf.GetName()))
 			}
-		} else {
-			if err := os.WriteFile(absPath, []byte(rf.GetContent()), 0o644); err != nil {
+			if err := writeGeneratedFile(absPath, rf.GetContent()); err != nil {
 				return err
 			}
 		}
 	}
 	return errs
 }
+
+func writeGeneratedFile(absPath string, content string) error {
+	if info, err := os.Stat(absPath); err == nil {
+		original, err := os.ReadFile(absPath)
+		if err != nil {
+			return err
+		}
+		return util.OverwriteFile(absPath, original, []byte(content), info.Mode().Perm(), info.Size())
+	}
+	return os.WriteFile(absPath, []byte(content), 0o644)
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/kralicky/protols/sdk/breaking"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/spf13/cobra"
+)
+
+// workspace config files copied from the old revision along with the proto
+// sources, so that imports are resolved in the same way.
+var breakingConfigFiles = map[string]bool{
+	"go.mod":        true,
+	"go.sum":        true,
+	"buf.yaml":      true,
+	"buf.work.yaml": true,
+	"buf.lock":      true,
+}
+
+// BreakingCmd represents the breaking command
+func BuildBreakingCmd() *cobra.Command {
+	var against string
+	var protoPaths []string
+	cmd := &cobra.Command{
+		Use:   "breaking --against=<git-ref>",
+		Short: "Report breaking changes to proto source files since a git revision",
+		Long: `
+Compiles the proto source files in the current directory as they are now, and
+as they were at the given git revision, and reports changes which are not
+wire- or source-compatible:
+
+  - files, messages, enums, services, and rpcs which were removed
+  - fields and enum values which were removed without reserving their number
+  - fields which changed number, type, or label (optional, repeated, etc.)
+  - fields and enum values which were renamed
+  - rpcs which changed request or response types, or streaming
+
+The old revision is read from the git repository containing the current
+directory. Elements are matched by their fully qualified names, so moving a
+message to another file is not reported unless the old file was removed.
+
+The command exits with a non-zero status if any breaking changes were found.
+`[1:],
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			if _, err := git(wd, "rev-parse", "--verify", "--quiet", against+"^{commit}"); err != nil {
+				return fmt.Errorf("unknown revision %q", against)
+			}
+			oldDir, err := os.MkdirTemp("", "protols-breaking-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(oldDir)
+			if err := checkoutProtoSources(wd, against, oldDir); err != nil {
+				return err
+			}
+
+			includePaths := splitProtoPaths(protoPaths)
+			oldIncludePaths := make([]string, len(includePaths))
+			for i, p := range includePaths {
+				// include paths within the working directory refer to directories in
+				// the old tree; anything outside of it, such as ../common, was not
+				// checked out and is shared by both.
+				switch {
+				case filepath.IsAbs(p):
+					oldIncludePaths[i] = p
+				case filepath.IsLocal(p):
+					oldIncludePaths[i] = filepath.Join(oldDir, p)
+				default:
+					oldIncludePaths[i] = filepath.Join(wd, p)
+				}
+			}
+			oldResults, err := loadLocalResults(oldDir, oldIncludePaths)
+			if err != nil {
+				return fmt.Errorf("failed to compile sources at %s:\n%w", against, err)
+			}
+			newResults, err := loadLocalResults(wd, includePaths)
+			if err != nil {
+				return fmt.Errorf("failed to compile sources:\n%w", err)
+			}
+
+			changes := breaking.Compare(oldResults, newResults)
+			for _, change := range changes {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s [%s]\n", change, change.Kind)
+			}
+			if len(changes) > 0 {
+				return fmt.Errorf("found %d breaking change(s) since %s", len(changes), against)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&against, "against", "", "git revision to compare against (e.g. main, HEAD~1, or a tag)")
+	cmd.MarkFlagRequired("against")
+	addProtoPathFlag(cmd, &protoPaths)
+	return cmd
+}
+
+// loadLocalResults compiles the proto sources in the workspace directory and
+// returns the results for the files within it. If any errors are reported
+// while compiling, they are returned instead, since the results would be
+// incomplete.
+func loadLocalResults(workdir string, includePaths []string) ([]linker.Result, error) {
+	cache := lsp.NewCache(protocol.WorkspaceFolder{
+		URI: string(protocol.URIFromPath(workdir)),
+	}, lsp.WithSettings(lsp.Settings{IncludePaths: includePaths}))
+	cache.LoadFiles(searchProtoPaths(workdir, includePaths))
+
+	diagnostics, err := cache.XGetAllDiagnostics()
+	if err != nil {
+		return nil, err
+	}
+	pathsByURI := cache.XGetURIPathMappings().FilePathsByURI
+	var errs []string
+	for uri, diags := range diagnostics {
+		for _, diag := range diags {
+			if diag.Severity != protocol.SeverityError {
+				continue
+			}
+			filename := pathsByURI[uri]
+			if filename == "" {
+				filename = uri.Path()
+			}
+			errs = append(errs, fmt.Sprintf("%s:%d:%d: %s", filename, diag.Range.Start.Line+1, diag.Range.Start.Character+1, diag.Message))
+		}
+	}
+	if len(errs) > 0 {
+		slices.Sort(errs)
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+
+	local := map[string]bool{}
+	for _, uri := range cache.XListWorkspaceLocalURIs() {
+		local[pathsByURI[uri]] = true
+	}
+	var results []linker.Result
+	for _, res := range cache.XGetLinkerResults() {
+		if local[res.Path()] {
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// checkoutProtoSources writes the proto sources and workspace config files
+// under dir, as they were at the given revision, to outDir.
+func checkoutProtoSources(dir, rev, outDir string) error {
+	out, err := git(dir, "ls-tree", "-r", "-z", "--name-only", rev)
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00") {
+		if name == "" || (filepath.Ext(name) != ".proto" && !breakingConfigFiles[filepath.Base(name)]) {
+			continue
+		}
+		contents, err := git(dir, "show", rev+":./"+name)
+		if err != nil {
+			return err
+		}
+		filename := filepath.Join(outDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filename, contents, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return nil, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
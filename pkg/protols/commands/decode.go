@@ -1,17 +1,13 @@
 package commands
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/kralicky/protols/pkg/lsp"
-	"github.com/kralicky/protols/pkg/sources"
 	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
 	"github.com/mattn/go-tty"
 	"github.com/spf13/cobra"
@@ -27,76 +23,175 @@ import (
 func BuildDecodeCmd() *cobra.Command {
 	var output string
 	var msgType string
+	var infer bool
+	var framing string
+	var encoding string
+	var protoPaths []string
 	cmd := &cobra.Command{
 		Use:   "decode [--type=pkg.Message]",
 		Short: "Decodes a protobuf message from stdin and prints it in text format",
 		Long: `
 If a message type is given with --type, protols will attempt to look up the message
 and use it to provide type information when decoding. If the message could not be
-found or if no message name is given, a textual representation of the wire format
-will be printed instead.
+found, a textual representation of the wire format will be printed instead.
+
+If no message type is given, every message in the workspace is scored against
+the structure of the input, and the best candidates are listed along with a
+confidence score. If one candidate clearly matches better than the others, it
+is used to decode the message. Otherwise, or with --infer=false, a textual
+representation of the wire format is printed. If there are no proto files in
+the current directory or the proto paths, the type is not inferred.
+
+google.protobuf.Any messages and extensions are expanded using the types
+defined in the workspace, as well as the well-known types.
+
+The input can be given as raw bytes, base64, or hex (including the output of
+xxd); by default, the encoding is detected automatically. With --framing, the
+input is read as a stream of messages, each of which is decoded and printed in
+sequence along with its offset in the stream:
+  none       a single message (default)
+  delimited  messages prefixed with their length as a varint
+  grpc       gRPC frames, each prefixed with a compressed flag and a 4-byte
+             length; compressed frames are decompressed with gzip
 `[1:],
 		RunE: func(cmd *cobra.Command, args []string) error {
-			in := cmd.InOrStdin()
-			if len(msgType) == 0 {
-				text, err := decodeWithNoType(cmd.Context(), in)
+			switch framingKind(framing) {
+			case framingNone, framingDelimited, framingGRPC:
+			default:
+				return fmt.Errorf("unknown framing %q (must be one of none, delimited, grpc)", framing)
+			}
+			switch inputEncoding(encoding) {
+			case encodingAuto, encodingRaw, encodingBase64, encodingHex:
+			default:
+				return fmt.Errorf("unknown encoding %q (must be one of raw, base64, hex, auto)", encoding)
+			}
+			cmd.SilenceUsage = true
+			input, err := readInput(cmd.InOrStdin(), inputEncoding(encoding))
+			if err != nil {
+				return err
+			}
+			frames, err := splitFrames(input, framingKind(framing))
+			if err != nil {
+				return err
+			}
+			var desc protoreflect.MessageDescriptor
+			var msgs []protoreflect.MessageDescriptor
+			var resolver *lsp.TypeResolver
+			if len(msgType) > 0 {
+				cache, err := loadWorkspace(splitProtoPaths(protoPaths))
+				if err != nil {
+					return err
+				}
+				resolver = cache.XGetTypeResolver()
+				desc, err = findMessageType(cache, msgType)
 				if err != nil {
 					return err
 				}
-				cmd.Println(text)
-				return nil
-			} else {
-				msg, err := decodeWithType(cmd.Context(), in, msgType)
+			} else if infer {
+				cwd, files, err := workspaceProtoFiles(splitProtoPaths(protoPaths))
 				if err != nil {
 					return err
 				}
-				switch output {
-				case "text":
-					cmd.Println(prototext.MarshalOptions{
-						Multiline:    true,
-						Indent:       "  ",
-						AllowPartial: true,
-						EmitUnknown:  true,
-					}.Format(msg))
-				case "json":
-					cmd.Println(protojson.MarshalOptions{
-						Multiline:     true,
-						Indent:        "  ",
-						AllowPartial:  true,
-						UseProtoNames: true,
-					}.Format(msg))
+				// outside of a workspace there are no types to infer from, so
+				// don't bother compiling anything
+				if len(files) > 0 {
+					cache := loadWorkspaceFiles(cwd, files, splitProtoPaths(protoPaths))
+					resolver = cache.XGetTypeResolver()
+					msgs = cache.XGetAllMessages()
+				}
+			}
+			for i, frame := range frames {
+				if framingKind(framing) != framingNone {
+					header := fmt.Sprintf("# message %d at offset %d (%d bytes", i, frame.offset, frame.length)
+					if frame.compressed {
+						header += ", compressed"
+					}
+					cmd.Println(header + ")")
+				}
+				if err := decodeFrame(cmd, frame.data, desc, msgs, resolver, output); err != nil {
+					if len(frames) > 1 {
+						return fmt.Errorf("message %d at offset %d: %w", i, frame.offset, err)
+					}
+					return err
 				}
-				return nil
 			}
+			return nil
 		},
 	}
 	cmd.Flags().StringVarP(&msgType, "type", "t", "", "The message type to use when decoding")
 	cmd.Flags().StringVarP(&output, "output", "o", "text", "Output format (text|json)")
+	cmd.Flags().BoolVar(&infer, "infer", true, "If no type is given, infer it from the messages in the workspace")
+	cmd.Flags().StringVar(&framing, "framing", string(framingNone), "How messages are framed in the input (none|delimited|grpc)")
+	cmd.Flags().StringVar(&encoding, "encoding", string(encodingAuto), "Encoding of the input (raw|base64|hex|auto)")
+	addProtoPathFlag(cmd, &protoPaths)
 	return cmd
 }
 
-func decodeWithNoType(ctx context.Context, in io.Reader) (string, error) {
-	input, err := readMaybeBase64AndTrim(in)
+// decodeFrame decodes and prints a single message. If desc is nil, the type
+// is inferred from the candidate messages, if any. The resolver is used to
+// expand Any messages and extensions, and must be set if desc or candidates
+// are given.
+func decodeFrame(cmd *cobra.Command, input []byte, desc protoreflect.MessageDescriptor, candidates []protoreflect.MessageDescriptor, resolver *lsp.TypeResolver, output string) error {
+	if desc == nil && len(candidates) > 0 {
+		inferred := inferMessageTypes(input, candidates)
+		if best, ok := clearWinner(inferred); ok {
+			cmd.PrintErrf("inferred type %s (%.0f%% confidence)\n", best.desc.FullName(), best.score*100)
+			desc = best.desc
+		} else if len(inferred) > 0 {
+			cmd.PrintErrln("could not infer the message type; the best candidates are:")
+			for _, c := range inferred[:min(len(inferred), maxInferCandidates)] {
+				cmd.PrintErrf("  %3.0f%%  %s\n", c.score*100, c.desc.FullName())
+			}
+		}
+	}
+	if desc == nil {
+		text, err := decodeWithNoType(cmd.Context(), input)
+		if err != nil {
+			return err
+		}
+		cmd.Println(text)
+		return nil
+	}
+	msg, err := unmarshalWithDescriptor(input, desc, resolver)
 	if err != nil {
-		return "", err
+		return err
+	}
+	printMessage(cmd, msg, resolver, output)
+	return nil
+}
+
+func printMessage(cmd *cobra.Command, msg proto.Message, resolver *lsp.TypeResolver, output string) {
+	switch output {
+	case "text":
+		cmd.Println(prototext.MarshalOptions{
+			Multiline:    true,
+			Indent:       "  ",
+			AllowPartial: true,
+			EmitUnknown:  true,
+			Resolver:     resolver,
+		}.Format(msg))
+	case "json":
+		cmd.Println(protojson.MarshalOptions{
+			Multiline:     true,
+			Indent:        "  ",
+			AllowPartial:  true,
+			UseProtoNames: true,
+			Resolver:      resolver,
+		}.Format(msg))
 	}
+}
+
+func decodeWithNoType(ctx context.Context, input []byte) (string, error) {
 	msg := protopack.Message{}
 	msg.UnmarshalAbductive(input, nil)
-	if len(msg) == 0 {
-		return "", fmt.Errorf("no input")
-	}
 	return strings.ReplaceAll(fmt.Sprintf("%+v\n", msg), "\t", "  "), nil
 }
 
-func decodeWithType(ctx context.Context, in io.Reader, msgType string) (proto.Message, error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-	cache := lsp.NewCache(protocol.WorkspaceFolder{
-		URI: string(protocol.URIFromPath(cwd)),
-	})
-	cache.LoadFiles(sources.SearchDirs(cwd))
+// findMessageType looks up the message type with the given name among the
+// messages in the cache. The name is matched against the full
+// name of each message, then its short name, then any part of its full name.
+// If there are multiple matches, the user is prompted to choose one.
+func findMessageType(cache *lsp.Cache, msgType string) (protoreflect.MessageDescriptor, error) {
 	allMsgs := cache.XGetAllMessages()
 	var exact protoreflect.MessageDescriptor
 	var exactNameOnly []protoreflect.MessageDescriptor
@@ -116,27 +211,55 @@ func decodeWithType(ctx context.Context, in io.Reader, msgType string) (proto.Me
 	}
 	if exact != nil {
 		// found an exact match, use it
-		return decodeWithDescriptor(ctx, in, exact)
+		return exact, nil
 	}
 	if len(exactNameOnly) == 1 {
 		// found a single name match, use it
-		return decodeWithDescriptor(ctx, in, exactNameOnly[0])
+		return exactNameOnly[0], nil
 	} else if len(exactNameOnly) > 1 {
 		// found multiple name matches, prompt the user to choose one
-		return chooseAndDecode(ctx, in, exactNameOnly)
+		return chooseMessageType(exactNameOnly)
 	}
 	if len(partialMatch) == 1 {
 		// found a single partial match, use it
-		return decodeWithDescriptor(ctx, in, partialMatch[0])
+		return partialMatch[0], nil
 	} else if len(partialMatch) > 1 {
 		// found multiple partial matches, prompt the user to choose one
-		return chooseAndDecode(ctx, in, partialMatch)
+		return chooseMessageType(partialMatch)
 	}
 
 	return nil, fmt.Errorf("could not find a matching type for %q", msgType)
 }
 
-func chooseAndDecode(ctx context.Context, in io.Reader, choices []protoreflect.MessageDescriptor) (proto.Message, error) {
+// loadWorkspace loads the proto files in the current directory and in each
+// of the proto paths.
+func loadWorkspace(protoPaths []string) (*lsp.Cache, error) {
+	cwd, files, err := workspaceProtoFiles(protoPaths)
+	if err != nil {
+		return nil, err
+	}
+	return loadWorkspaceFiles(cwd, files, protoPaths), nil
+}
+
+// workspaceProtoFiles returns the current directory, and the proto files in
+// it and in each of the proto paths.
+func workspaceProtoFiles(protoPaths []string) (string, []string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", nil, err
+	}
+	return cwd, searchProtoPaths(cwd, protoPaths), nil
+}
+
+func loadWorkspaceFiles(cwd string, files []string, protoPaths []string) *lsp.Cache {
+	cache := lsp.NewCache(protocol.WorkspaceFolder{
+		URI: string(protocol.URIFromPath(cwd)),
+	}, lsp.WithSettings(lsp.Settings{IncludePaths: protoPaths}))
+	cache.LoadFiles(files)
+	return cache
+}
+
+func chooseMessageType(choices []protoreflect.MessageDescriptor) (protoreflect.MessageDescriptor, error) {
 	var selected string
 	tty, err := tty.Open()
 	if err != nil {
@@ -158,59 +281,18 @@ func chooseAndDecode(ctx context.Context, in io.Reader, choices []protoreflect.M
 	}
 	for _, d := range choices {
 		if string(d.FullName()) == selected {
-			return decodeWithDescriptor(ctx, in, d)
+			return d, nil
 		}
 	}
 	return nil, fmt.Errorf("no type selected")
 }
 
-func decodeWithDescriptor(ctx context.Context, in io.Reader, desc protoreflect.MessageDescriptor) (proto.Message, error) {
-	input, err := readMaybeBase64AndTrim(in)
-	if err != nil {
-		return nil, err
-	}
+func unmarshalWithDescriptor(input []byte, desc protoreflect.MessageDescriptor, resolver *lsp.TypeResolver) (proto.Message, error) {
 	// try to decode as wire format
 	newMsg := dynamicpb.NewMessage(desc)
-	if err := proto.Unmarshal(input, newMsg); err != nil {
+	if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(input, newMsg); err != nil {
 		return nil, fmt.Errorf("could not decode input (wrong type?): %w", err)
 	}
 
 	return newMsg, nil
 }
-
-func looksLikeBase64(input []byte) bool {
-	return len(bytes.Trim(input, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/-_=")) == 0
-}
-
-func readMaybeBase64AndTrim(in io.Reader) ([]byte, error) {
-	input, err := io.ReadAll(in)
-	if err != nil {
-		return nil, err
-	}
-	input = bytes.TrimSpace(input)
-	if len(input) == 0 {
-		return nil, fmt.Errorf("no input")
-	}
-
-	// figure out what kind of input we have
-	// 1. check if it's base64 encoded
-	if looksLikeBase64(input) {
-		encodings := []*base64.Encoding{
-			base64.StdEncoding,
-			base64.URLEncoding,
-			base64.RawStdEncoding,
-			base64.RawURLEncoding,
-		}
-		if bytes.HasSuffix(input, []byte{'='}) {
-			encodings = encodings[0:2]
-		}
-		for _, codec := range encodings {
-			decoded, err := codec.DecodeString(string(input))
-			if err == nil {
-				input = decoded
-				break
-			}
-		}
-	}
-	return input, nil
-}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// EncodeCmd represents the encode command
+func BuildEncodeCmd() *cobra.Command {
+	var output string
+	var input string
+	var msgType string
+	var protoPaths []string
+	cmd := &cobra.Command{
+		Use:   "encode --type=pkg.Message [file]",
+		Short: "Encodes a protobuf message from text or JSON format into the wire format",
+		Long: `
+Reads a message in text or JSON format from the given file, or from stdin, and
+writes it in the wire format. This is the inverse of 'protols decode'.
+
+The message type given with --type is looked up in the same way as for
+'protols decode'. The input format is detected automatically unless given with
+--input: input starting with '{' is read as JSON, and anything else as text.
+google.protobuf.Any messages and extensions may refer to any type defined in
+the workspace, as well as the well-known types.
+
+The encoded message can be written as raw binary (the default), or as base64
+or hex.
+`[1:],
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch input {
+			case "auto", "text", "json":
+			default:
+				return fmt.Errorf("unknown input format %q (must be one of auto, text, json)", input)
+			}
+			switch output {
+			case "binary", "base64", "hex":
+			default:
+				return fmt.Errorf("unknown output format %q (must be one of binary, base64, hex)", output)
+			}
+			label := "<stdin>"
+			var data []byte
+			var err error
+			if len(args) == 1 && args[0] != "-" {
+				label = args[0]
+				data, err = os.ReadFile(args[0])
+			} else {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			}
+			if err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			cache, err := loadWorkspace(splitProtoPaths(protoPaths))
+			if err != nil {
+				return err
+			}
+			desc, err := findMessageType(cache, msgType)
+			if err != nil {
+				return err
+			}
+			wire, err := encodeWithDescriptor(label, data, input, desc, cache.XGetTypeResolver())
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			switch output {
+			case "binary":
+				_, err = out.Write(wire)
+			case "base64":
+				_, err = fmt.Fprintln(out, base64.StdEncoding.EncodeToString(wire))
+			case "hex":
+				_, err = fmt.Fprintln(out, hex.EncodeToString(wire))
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&msgType, "type", "t", "", "The message type to use when encoding")
+	cmd.Flags().StringVarP(&output, "output", "o", "binary", "Output format (binary|base64|hex)")
+	cmd.Flags().StringVar(&input, "input", "auto", "Input format (auto|text|json)")
+	cmd.MarkFlagRequired("type")
+	addProtoPathFlag(cmd, &protoPaths)
+	return cmd
+}
+
+// encodeWithDescriptor parses the message from its text or JSON form and
+// returns its wire format encoding. Parse errors are reported relative to the
+// given filename. The resolver is used to look up the types of Any messages
+// and extensions.
+func encodeWithDescriptor(filename string, data []byte, format string, desc protoreflect.MessageDescriptor, resolver *lsp.TypeResolver) ([]byte, error) {
+	if format == "auto" {
+		format = "text"
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			format = "json"
+		}
+	}
+	msg := dynamicpb.NewMessage(desc)
+	var err error
+	switch format {
+	case "json":
+		err = protojson.UnmarshalOptions{Resolver: resolver}.Unmarshal(data, msg)
+	default:
+		err = prototext.UnmarshalOptions{Resolver: resolver}.Unmarshal(data, msg)
+	}
+	if err != nil {
+		return nil, positionedParseError(filename, err)
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
+
+var (
+	parseErrorPosition = regexp.MustCompile(`\s*\(line (\d+):(\d+)\):?\s*`)
+	parseErrorPrefix   = regexp.MustCompile(`^proto:[ \x{00a0}]*`)
+)
+
+// positionedParseError rewrites an error from the prototext or protojson
+// parsers, which contain positions in the form "(line 1:2)", as
+// "filename:1:2: message".
+func positionedParseError(filename string, err error) error {
+	msg := parseErrorPrefix.ReplaceAllString(err.Error(), "")
+	loc := parseErrorPosition.FindStringSubmatchIndex(msg)
+	if loc == nil {
+		return fmt.Errorf("%s: %s", filename, msg)
+	}
+	line, col := msg[loc[2]:loc[3]], msg[loc[4]:loc[5]]
+	before, after := msg[:loc[0]], msg[loc[1]:]
+	switch {
+	case before == "":
+		msg = after
+	case after == "":
+		msg = before
+	default:
+		msg = before + ": " + after
+	}
+	return fmt.Errorf("%s:%s:%s: %s", filename, line, col, msg)
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeWithDescriptor(t *testing.T) {
+	person := inferTestMessages(t)["Person"]
+	dir := t.TempDir()
+	resolver := lsp.NewCache(protocol.WorkspaceFolder{URI: string(protocol.URIFromPath(dir))}).XGetTypeResolver()
+	alice := appendVarint(appendString(nil, 1, "alice"), 2, 7)
+
+	for _, tc := range []struct {
+		name    string
+		format  string
+		input   string
+		want    []byte
+		wantErr string
+	}{
+		{name: "auto text", format: "auto", input: `name: "alice" id: 7`, want: alice},
+		{name: "auto json", format: "auto", input: `{"name": "alice", "id": 7}`, want: alice},
+		{name: "auto json with leading whitespace", format: "auto", input: "\n  {\"id\": 7, \"name\": \"alice\"}", want: alice},
+		{name: "auto empty", format: "auto", input: "", want: []byte{}},
+		{name: "text", format: "text", input: "id: 7\nname: \"alice\"\n", want: alice},
+		{name: "json", format: "json", input: `{"name": "alice", "id": 7}`, want: alice},
+		{
+			name:    "json as text",
+			format:  "text",
+			input:   `{"name": "alice"}`,
+			wantErr: "in.txtpb:1:1: ",
+		},
+		{
+			name:    "text error",
+			format:  "auto",
+			input:   "name: \"alice\"\nid: \"x\"",
+			wantErr: `in.txtpb:2:5: invalid value for int32 type: "x"`,
+		},
+		{
+			name:    "json error",
+			format:  "auto",
+			input:   "{\n  \"name\": \"alice\",\n  \"bogus\": 1\n}",
+			wantErr: `in.txtpb:3:3: unknown field "bogus"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := encodeWithDescriptor("in.txtpb", []byte(tc.input), tc.format, person, resolver)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPositionedParseError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  string
+		want string
+	}{
+		{
+			name: "position at start",
+			err:  "proto: (line 2:5): invalid value for int32 type: \"x\"",
+			want: "in.txtpb:2:5: invalid value for int32 type: \"x\"",
+		},
+		{
+			// the protobuf module randomly uses a non-breaking space after the prefix
+			name: "non-breaking space",
+			err:  "proto:\u00a0(line 1:11): unknown field: bogus",
+			want: "in.txtpb:1:11: unknown field: bogus",
+		},
+		{
+			name: "position in the middle",
+			err:  "proto: syntax error (line 1:7): invalid scalar value: }",
+			want: "in.txtpb:1:7: syntax error: invalid scalar value: }",
+		},
+		{
+			name: "position at end",
+			err:  "proto: unexpected token (line 10:12)",
+			want: "in.txtpb:10:12: unexpected token",
+		},
+		{
+			name: "no position",
+			err:  "proto: unexpected EOF",
+			want: "in.txtpb: unexpected EOF",
+		},
+		{
+			name: "no prefix",
+			err:  "required field name not set",
+			want: "in.txtpb: required field name not set",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualError(t, positionedParseError("in.txtpb", errors.New(tc.err)), tc.want)
+		})
+	}
+}
@@ -1,7 +1,18 @@
 package commands
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
 	"github.com/kralicky/protols/pkg/format"
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/kralicky/protols/pkg/sources"
+	"github.com/kralicky/protols/pkg/util"
+	"github.com/kralicky/tools-lite/pkg/diff"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 )
@@ -9,20 +20,144 @@ import (
 // FmtCmd represents the fmt command
 func BuildFmtCmd() *cobra.Command {
 	var write bool
+	var showDiff bool
+	var check bool
+	var protoPaths []string
 	cmd := &cobra.Command{
-		Use:   "fmt [filenames...]",
+		Use:   "fmt [flags] <path|-> ...",
 		Short: "Format proto source files",
+		Long: `
+Formats the given proto source files. Directories are searched recursively
+for .proto files, and a path of "-" reads a single file from stdin.
+
+By default, the formatted sources are written to stdout. With --write, files
+are formatted in place instead, and with --diff, a unified diff of the changes
+is printed. With --check, the names of files which are not formatted are
+printed, and the command exits with a non-zero status if there are any.
+
+Files containing the "protols:nofmt" pragma are not formatted.
+`[1:],
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			var filenames []string
+			for _, arg := range args {
+				if arg == "-" {
+					if write {
+						return errors.New("cannot use --write with stdin")
+					}
+					filenames = append(filenames, arg)
+					continue
+				}
+				filename := resolveInputFile(arg, splitProtoPaths(protoPaths))
+				if info, err := os.Stat(filename); err == nil && info.IsDir() {
+					filenames = append(filenames, sources.SearchDirs(filename)...)
+				} else {
+					filenames = append(filenames, filename)
+				}
+			}
+
+			cmd.SilenceUsage = true
+			results := make([]fmtResult, len(filenames))
 			var eg errgroup.Group
-			for _, filename := range args {
-				filename := filename
+			for i, filename := range filenames {
 				eg.Go(func() error {
-					return format.FileInPlace(filename)
+					results[i] = formatFile(cmd.InOrStdin(), filename)
+					return nil
 				})
 			}
-			return eg.Wait()
+			eg.Wait()
+
+			var errs []error
+			var unformatted int
+			for _, res := range results {
+				switch {
+				case res.err != nil:
+					errs = append(errs, res.err)
+					continue
+				case res.skipped:
+					res.formatted = res.original
+				}
+				changed := !bytes.Equal(res.original, res.formatted)
+				if changed {
+					unformatted++
+				}
+				if check && changed {
+					fmt.Fprintln(cmd.OutOrStdout(), res.label)
+				}
+				if showDiff && changed {
+					fmt.Fprint(cmd.OutOrStdout(), diff.Unified("a/"+res.label, "b/"+res.label, string(res.original), string(res.formatted)))
+				}
+				if write && changed {
+					if err := util.OverwriteFile(res.filename, res.original, res.formatted, res.perm, int64(len(res.original))); err != nil {
+						errs = append(errs, err)
+					}
+				}
+				if !write && !showDiff && !check {
+					cmd.OutOrStdout().Write(res.formatted)
+				}
+			}
+			if len(errs) > 0 {
+				return errors.Join(errs...)
+			}
+			if check && unformatted > 0 {
+				return fmt.Errorf("%d file(s) not formatted", unformatted)
+			}
+			return nil
 		},
 	}
 	cmd.Flags().BoolVarP(&write, "write", "w", false, "write result to (source) file instead of stdout")
+	cmd.Flags().BoolVarP(&showDiff, "diff", "d", false, "print a unified diff of the changes instead of the formatted source")
+	cmd.Flags().BoolVar(&check, "check", false, "list files which are not formatted, and exit with a non-zero status if there are any")
+	addProtoPathFlag(cmd, &protoPaths)
 	return cmd
 }
+
+type fmtResult struct {
+	filename  string
+	label     string
+	perm      os.FileMode
+	original  []byte
+	formatted []byte
+	skipped   bool
+	err       error
+}
+
+// formatFile formats the named file, or reads from stdin if filename is "-".
+func formatFile(stdin io.Reader, filename string) (res fmtResult) {
+	res.filename = filename
+	res.label = filepath.ToSlash(filename)
+	if cwd, err := os.Getwd(); err == nil && filepath.IsAbs(filename) {
+		if rel, err := filepath.Rel(cwd, filename); err == nil && filepath.IsLocal(rel) {
+			res.label = filepath.ToSlash(rel)
+		}
+	}
+	if filename == "-" {
+		res.label = "<stdin>"
+		res.original, res.err = io.ReadAll(stdin)
+	} else {
+		var info os.FileInfo
+		if info, res.err = os.Stat(filename); res.err == nil {
+			res.perm = info.Mode().Perm()
+			res.original, res.err = os.ReadFile(filename)
+		}
+	}
+	if res.err != nil {
+		return
+	}
+	fileNode, err := format.Parse(res.label, bytes.NewReader(res.original))
+	if err != nil {
+		res.err = err
+		return
+	}
+	if _, ok := fileNode.Pragma(lsp.PragmaNoFormat); ok {
+		res.skipped = true
+		return
+	}
+	var formatted bytes.Buffer
+	if err := format.NewFormatter(&formatted, fileNode).Run(); err != nil {
+		res.err = err
+		return
+	}
+	res.formatted = formatted.Bytes()
+	return
+}
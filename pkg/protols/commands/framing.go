@@ -0,0 +1,222 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type inputEncoding string
+
+const (
+	encodingAuto   inputEncoding = "auto"
+	encodingRaw    inputEncoding = "raw"
+	encodingBase64 inputEncoding = "base64"
+	encodingHex    inputEncoding = "hex"
+)
+
+type framingKind string
+
+const (
+	framingNone      framingKind = "none"
+	framingDelimited framingKind = "delimited"
+	framingGRPC      framingKind = "grpc"
+)
+
+// readInput reads all of the input and decodes it according to the given
+// encoding. With encodingAuto, input consisting only of hex digits, or in the
+// format written by xxd, is decoded as hex; input consisting only of base64
+// characters is decoded as base64; anything else is read as raw bytes.
+func readInput(in io.Reader, encoding inputEncoding) ([]byte, error) {
+	input, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(input)
+	if len(trimmed) == 0 {
+		return nil, errors.New("no input")
+	}
+
+	switch encoding {
+	case encodingRaw:
+		return input, nil
+	case encodingBase64:
+		return decodeBase64(trimmed)
+	case encodingHex:
+		return decodeHex(trimmed)
+	case encodingAuto:
+		if looksLikeXxd(trimmed) || looksLikeHex(trimmed) {
+			if decoded, err := decodeHex(trimmed); err == nil {
+				return decoded, nil
+			}
+		}
+		if looksLikeBase64(trimmed) {
+			if decoded, err := decodeBase64(trimmed); err == nil {
+				return decoded, nil
+			}
+		}
+		// otherwise, it's binary; whitespace bytes are significant
+		return input, nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q (must be one of raw, base64, hex, auto)", encoding)
+	}
+}
+
+func looksLikeBase64(input []byte) bool {
+	return len(bytes.Trim(input, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/-_=")) == 0
+}
+
+func decodeBase64(input []byte) ([]byte, error) {
+	encodings := []*base64.Encoding{
+		base64.StdEncoding,
+		base64.URLEncoding,
+		base64.RawStdEncoding,
+		base64.RawURLEncoding,
+	}
+	if bytes.HasSuffix(input, []byte{'='}) {
+		encodings = encodings[0:2]
+	}
+	var errs []error
+	for _, codec := range encodings {
+		decoded, err := codec.DecodeString(string(input))
+		if err == nil {
+			return decoded, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("invalid base64 input: %w", errs[0])
+}
+
+// looksLikeHex reports whether the input consists of an even number of hex
+// digits, optionally separated by whitespace.
+func looksLikeHex(input []byte) bool {
+	digits := 0
+	for _, c := range input {
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'f', c >= 'A' && c <= 'F':
+			digits++
+		case c == ' ', c == '\t', c == '\n', c == '\r':
+		default:
+			return false
+		}
+	}
+	return digits%2 == 0
+}
+
+// xxdLine matches a line written by xxd in its default format, e.g.
+// "00000000: 0a03 416e 6e10  ..Ann.", capturing the hex column.
+var xxdLine = regexp.MustCompile(`^[0-9a-fA-F]+:((?: [0-9a-fA-F]{2,})+)(?:  .*)?$`)
+
+func looksLikeXxd(input []byte) bool {
+	line, _, _ := bytes.Cut(input, []byte("\n"))
+	return xxdLine.Match(bytes.TrimRight(line, "\r"))
+}
+
+// decodeHex decodes plain hex, ignoring whitespace, or the output of xxd.
+func decodeHex(input []byte) ([]byte, error) {
+	var digits strings.Builder
+	if looksLikeXxd(input) {
+		scanner := bufio.NewScanner(bytes.NewReader(input))
+		for lineNum := 1; scanner.Scan(); lineNum++ {
+			line := strings.TrimRight(scanner.Text(), "\r")
+			if line == "" {
+				continue
+			}
+			m := xxdLine.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid xxd input on line %d: %q", lineNum, line)
+			}
+			digits.WriteString(strings.ReplaceAll(m[1], " ", ""))
+		}
+	} else {
+		for _, field := range strings.Fields(string(input)) {
+			digits.WriteString(field)
+		}
+	}
+	decoded, err := hex.DecodeString(digits.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex input: %w", err)
+	}
+	return decoded, nil
+}
+
+// frame is a single message read from the input.
+type frame struct {
+	offset     int // offset of the frame, including its prefix, in the input
+	length     int // length of the message as it appears in the input
+	compressed bool
+	data       []byte
+}
+
+// splitFrames splits the input into messages according to the framing.
+func splitFrames(input []byte, framing framingKind) ([]frame, error) {
+	switch framing {
+	case framingNone:
+		return []frame{{length: len(input), data: input}}, nil
+	case framingDelimited:
+		var frames []frame
+		for offset := 0; offset < len(input); {
+			size, n := protowire.ConsumeVarint(input[offset:])
+			if n < 0 {
+				return nil, fmt.Errorf("invalid length prefix at offset %d: %w", offset, protowire.ParseError(n))
+			}
+			start := offset + n
+			if size > uint64(len(input)-start) {
+				return nil, fmt.Errorf("message at offset %d is truncated: expected %d bytes, have %d", offset, size, len(input)-start)
+			}
+			end := start + int(size)
+			frames = append(frames, frame{offset: offset, length: int(size), data: input[start:end]})
+			offset = end
+		}
+		return frames, nil
+	case framingGRPC:
+		var frames []frame
+		for offset := 0; offset < len(input); {
+			if len(input)-offset < 5 {
+				return nil, fmt.Errorf("truncated gRPC frame header at offset %d", offset)
+			}
+			flag := input[offset]
+			if flag > 1 {
+				return nil, fmt.Errorf("invalid gRPC compressed flag %d at offset %d", flag, offset)
+			}
+			size := binary.BigEndian.Uint32(input[offset+1 : offset+5])
+			start := offset + 5
+			if uint64(size) > uint64(len(input)-start) {
+				return nil, fmt.Errorf("gRPC frame at offset %d is truncated: expected %d bytes, have %d", offset, size, len(input)-start)
+			}
+			end := start + int(size)
+			f := frame{offset: offset, length: int(size), compressed: flag == 1, data: input[start:end]}
+			if f.compressed {
+				data, err := gunzip(f.data)
+				if err != nil {
+					return nil, fmt.Errorf("could not decompress gRPC frame at offset %d: %w", offset, err)
+				}
+				f.data = data
+			}
+			frames = append(frames, f)
+			offset = end
+		}
+		return frames, nil
+	default:
+		return nil, fmt.Errorf("unknown framing %q (must be one of none, delimited, grpc)", framing)
+	}
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,215 @@
+package commands
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// xxdTestInput is the output of xxd for the bytes 0x00 to 0x11; the final line
+// is shorter than the others and padded with spaces.
+const xxdTestInput = "00000000: 0001 0203 0405 0607 0809 0a0b 0c0d 0e0f  ................\n" +
+	"00000010: 1011                                     ..\n"
+
+func xxdTestBytes() []byte {
+	b := make([]byte, 0x12)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+func TestReadInput(t *testing.T) {
+	deadbeefBase64, err := base64.StdEncoding.DecodeString("deadbeef")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		encoding inputEncoding
+		input    string
+		want     []byte
+		wantErr  string
+	}{
+		{name: "auto hex", encoding: encodingAuto, input: "0a 05 68 65 6c 6c 6f\n", want: []byte("\x0a\x05hello")},
+		{name: "auto xxd", encoding: encodingAuto, input: xxdTestInput, want: xxdTestBytes()},
+		{name: "auto base64", encoding: encodingAuto, input: "CgVoZWxsbw==\n", want: []byte("\x0a\x05hello")},
+		{name: "auto unpadded base64", encoding: encodingAuto, input: "CgVoZWxsbw", want: []byte("\x0a\x05hello")},
+		{name: "auto url base64", encoding: encodingAuto, input: "_-8=", want: []byte{0xff, 0xef}},
+		{
+			// "deadbeef" is also valid base64, but hex is checked first
+			name:     "auto prefers hex over base64",
+			encoding: encodingAuto,
+			input:    "deadbeef",
+			want:     []byte{0xde, 0xad, 0xbe, 0xef},
+		},
+		{
+			// whitespace is significant in binary input
+			name:     "auto raw",
+			encoding: encodingAuto,
+			input:    "\x0a\x02hi\n",
+			want:     []byte("\x0a\x02hi\n"),
+		},
+		{name: "raw", encoding: encodingRaw, input: " deadbeef\n", want: []byte(" deadbeef\n")},
+		{name: "explicit base64", encoding: encodingBase64, input: "deadbeef", want: deadbeefBase64},
+		{name: "explicit hex", encoding: encodingHex, input: "DE AD\nBE EF", want: []byte{0xde, 0xad, 0xbe, 0xef}},
+		{name: "invalid hex", encoding: encodingHex, input: "abc", wantErr: "invalid hex input"},
+		{name: "invalid base64", encoding: encodingBase64, input: "a!", wantErr: "invalid base64 input"},
+		{name: "empty", encoding: encodingAuto, input: " \n", wantErr: "no input"},
+		{name: "unknown encoding", encoding: "utf8", input: "abc", wantErr: `unknown encoding "utf8"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readInput(strings.NewReader(tc.input), tc.encoding)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestDecodeHex(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		input   string
+		xxd     bool
+		want    []byte
+		wantErr string
+	}{
+		{name: "plain", input: "0a05", want: []byte{0x0a, 0x05}},
+		{name: "whitespace", input: "0a 05\r\n0A\t05", want: []byte{0x0a, 0x05, 0x0a, 0x05}},
+		{name: "xxd", input: xxdTestInput, xxd: true, want: xxdTestBytes()},
+		{name: "xxd crlf", input: strings.ReplaceAll(xxdTestInput, "\n", "\r\n"), xxd: true, want: xxdTestBytes()},
+		{
+			name:  "xxd single short line",
+			input: "00000000: 0a05 6869                                ..hi",
+			xxd:   true,
+			want:  []byte("\x0a\x05hi"),
+		},
+		{
+			name:    "xxd invalid line",
+			input:   "00000000: 0a05  ..\nnot xxd\n",
+			xxd:     true,
+			wantErr: `invalid xxd input on line 2: "not xxd"`,
+		},
+		{name: "odd length", input: "0a0", wantErr: "invalid hex input"},
+		{name: "not hex", input: "hello: world", wantErr: "invalid hex input"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.xxd, looksLikeXxd([]byte(tc.input)))
+			got, err := decodeHex([]byte(tc.input))
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSplitFrames(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	gzipFrame := append([]byte{1, 0, 0, 0, byte(compressed.Len())}, compressed.Bytes()...)
+
+	for _, tc := range []struct {
+		name    string
+		framing framingKind
+		input   []byte
+		want    []frame
+		wantErr string
+	}{
+		{
+			name:    "none",
+			framing: framingNone,
+			input:   []byte("\x02ab"),
+			want:    []frame{{length: 3, data: []byte("\x02ab")}},
+		},
+		{
+			name:    "delimited",
+			framing: framingDelimited,
+			input:   []byte("\x02ab\x00\x01c"),
+			want: []frame{
+				{offset: 0, length: 2, data: []byte("ab")},
+				{offset: 3, length: 0, data: []byte{}},
+				{offset: 4, length: 1, data: []byte("c")},
+			},
+		},
+		{
+			name:    "delimited truncated",
+			framing: framingDelimited,
+			input:   []byte("\x01a\x05a"),
+			wantErr: "message at offset 2 is truncated: expected 5 bytes, have 1",
+		},
+		{
+			name:    "delimited invalid prefix",
+			framing: framingDelimited,
+			input:   []byte{0x80},
+			wantErr: "invalid length prefix at offset 0",
+		},
+		{
+			name:    "grpc",
+			framing: framingGRPC,
+			input:   []byte("\x00\x00\x00\x00\x02ab\x00\x00\x00\x00\x01c"),
+			want: []frame{
+				{offset: 0, length: 2, data: []byte("ab")},
+				{offset: 7, length: 1, data: []byte("c")},
+			},
+		},
+		{
+			name:    "grpc compressed",
+			framing: framingGRPC,
+			input:   gzipFrame,
+			want:    []frame{{offset: 0, length: compressed.Len(), compressed: true, data: []byte("hello")}},
+		},
+		{
+			name:    "grpc invalid compressed data",
+			framing: framingGRPC,
+			input:   []byte("\x01\x00\x00\x00\x02ab"),
+			wantErr: "could not decompress gRPC frame at offset 0",
+		},
+		{
+			name:    "grpc truncated header",
+			framing: framingGRPC,
+			input:   []byte("\x00\x00\x00\x00\x01a\x00\x00"),
+			wantErr: "truncated gRPC frame header at offset 6",
+		},
+		{
+			name:    "grpc truncated message",
+			framing: framingGRPC,
+			input:   []byte("\x00\x00\x00\x00\x05ab"),
+			wantErr: "gRPC frame at offset 0 is truncated: expected 5 bytes, have 2",
+		},
+		{
+			name:    "grpc invalid flag",
+			framing: framingGRPC,
+			input:   []byte("\x02\x00\x00\x00\x01a"),
+			wantErr: "invalid gRPC compressed flag 2 at offset 0",
+		},
+		{
+			name:    "unknown framing",
+			framing: "http",
+			input:   []byte("a"),
+			wantErr: `unknown framing "http"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := splitFrames(tc.input, tc.framing)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
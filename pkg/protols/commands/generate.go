@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/kralicky/protols/sdk/codegen"
+	"github.com/spf13/cobra"
+)
+
+// GenerateCmd represents the generate command
+func BuildGenerateCmd() *cobra.Command {
+	var protoPaths []string
+	cmd := &cobra.Command{
+		Use:   "generate [flags] [path ...]",
+		Short: "Generate code for proto source files in the workspace",
+		Long: `
+Generates code for the proto source files in the given directories, or in the
+current directory if none are given. The current directory is the workspace
+root.
+
+The generators to run are read from the "generate" section of a protols.yaml
+file in the workspace root. Each entry names either a built-in generator, or
+an external protoc plugin to run:
+
+  generate:
+    - name: go
+    - name: go-grpc
+      opt: paths=source_relative
+    - plugin: protoc-gen-validate
+      opt: [lang=go, paths=source_relative]
+      out: gen/go
+
+Built-in generators are go, go-grpc, pathbuilder, and cli. If "out" is set,
+generated files are written to that directory, relative to the workspace root;
+otherwise, they are written alongside the sources they were generated from.
+Without a protols.yaml file, the go and go-grpc generators are run.
+
+The same configuration determines which generators are run by the code lenses
+in the editor.
+`[1:],
+		RunE: func(cmd *cobra.Command, args []string) error {
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			generators, err := codegen.LoadWorkspaceGenerators(wd)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				args = []string{wd}
+			}
+			cmd.SilenceUsage = true
+			files, err := codegen.GenerateCode(generators, args,
+				codegen.WithGenerateStrategy(codegen.WorkspaceLocalDescriptorsOnly),
+				codegen.WithIncludePaths(splitProtoPaths(protoPaths)),
+			)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				if err := file.WriteToDisk(); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	addProtoPathFlag(cmd, &protoPaths)
+	return cmd
+}
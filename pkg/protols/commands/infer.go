@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"cmp"
+	"slices"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// maximum number of candidates listed when the type can't be inferred
+	maxInferCandidates = 5
+	// minimum difference in score between the best and second-best candidates
+	// for the best candidate to be considered a clear winner
+	inferScoreMargin = 0.1
+	maxInferDepth    = 32
+)
+
+type typeCandidate struct {
+	desc protoreflect.MessageDescriptor
+	// fraction of fields in the input, including those of nested messages,
+	// which are consistent with the message type
+	match float64
+	// fraction of the message's fields which are present in the input
+	coverage float64
+	// overall score in [0, 1]
+	score float64
+}
+
+// inferMessageTypes scores each of the message types against the wire
+// structure of the input, and returns the types which could plausibly
+// describe it, best first.
+func inferMessageTypes(input []byte, msgs []protoreflect.MessageDescriptor) []typeCandidate {
+	var candidates []typeCandidate
+	seen := map[protoreflect.FullName]bool{}
+	for _, desc := range msgs {
+		if seen[desc.FullName()] {
+			continue
+		}
+		seen[desc.FullName()] = true
+		var s wireScore
+		present, ok := s.scoreMessage(input, desc, 0)
+		if !ok || s.total == 0 || s.matched == 0 {
+			continue
+		}
+		c := typeCandidate{
+			desc:  desc,
+			match: float64(s.matched) / float64(s.total),
+		}
+		if n := desc.Fields().Len(); n > 0 {
+			c.coverage = float64(present) / float64(n)
+		}
+		// matching fields matter most; coverage breaks ties between types
+		// which are equally consistent with the input
+		c.score = c.match * (0.75 + 0.25*c.coverage)
+		candidates = append(candidates, c)
+	}
+	slices.SortFunc(candidates, func(a, b typeCandidate) int {
+		return cmp.Or(
+			cmp.Compare(b.score, a.score),
+			cmp.Compare(a.desc.FullName(), b.desc.FullName()),
+		)
+	})
+	return candidates
+}
+
+// clearWinner returns the best candidate if every field in the input is
+// consistent with it, and it scores clearly better than the next candidate.
+func clearWinner(candidates []typeCandidate) (typeCandidate, bool) {
+	if len(candidates) == 0 || candidates[0].match < 1 {
+		return typeCandidate{}, false
+	}
+	if len(candidates) > 1 && candidates[0].score-candidates[1].score < inferScoreMargin {
+		return typeCandidate{}, false
+	}
+	return candidates[0], true
+}
+
+// wireScore counts the fields in an encoded message, including those of
+// nested messages, and how many of them match the message type.
+type wireScore struct {
+	matched, total int
+}
+
+// scoreMessage scores the encoded message against desc, returning the number
+// of distinct fields of desc present in the input. If the input is not a
+// well-formed message, ok is false.
+func (s *wireScore) scoreMessage(b []byte, desc protoreflect.MessageDescriptor, depth int) (present int, ok bool) {
+	if depth > maxInferDepth {
+		return 0, false
+	}
+	seen := map[protowire.Number]bool{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return 0, false
+		}
+		b = b[n:]
+		var value []byte
+		if typ == protowire.StartGroupType {
+			value, n = protowire.ConsumeGroup(num, b)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			value = b[:max(n, 0)]
+		}
+		if n < 0 {
+			return 0, false
+		}
+		b = b[n:]
+
+		s.total++
+		fd := desc.Fields().ByNumber(num)
+		if fd == nil {
+			continue
+		}
+		if s.scoreField(fd, typ, value, depth) {
+			s.matched++
+			if !seen[num] {
+				seen[num] = true
+				present++
+			}
+		}
+	}
+	return present, true
+}
+
+// scoreField reports whether a field value with the given wire type is
+// consistent with the field descriptor. The fields of nested messages are
+// added to the score.
+func (s *wireScore) scoreField(fd protoreflect.FieldDescriptor, typ protowire.Type, value []byte, depth int) bool {
+	switch typ {
+	case protowire.VarintType:
+		return isVarintKind(fd.Kind())
+	case protowire.Fixed32Type:
+		return isFixed32Kind(fd.Kind())
+	case protowire.Fixed64Type:
+		return isFixed64Kind(fd.Kind())
+	case protowire.StartGroupType:
+		if fd.Kind() != protoreflect.GroupKind {
+			return false
+		}
+		_, ok := s.scoreMessage(value, fd.Message(), depth+1)
+		return ok
+	case protowire.BytesType:
+		v, _ := protowire.ConsumeBytes(value)
+		switch fd.Kind() {
+		case protoreflect.BytesKind:
+			return true
+		case protoreflect.StringKind:
+			return utf8.Valid(v)
+		case protoreflect.MessageKind:
+			var nested wireScore
+			if _, ok := nested.scoreMessage(v, fd.Message(), depth+1); !ok || nested.matched < nested.total {
+				// count the mismatched nested fields against the outer message as well
+				s.total += nested.total
+				s.matched += nested.matched
+				return false
+			}
+			s.total += nested.total
+			s.matched += nested.matched
+			return true
+		default:
+			return fd.IsList() && isPackedValue(fd.Kind(), v)
+		}
+	}
+	return false
+}
+
+func isVarintKind(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.BoolKind, protoreflect.EnumKind,
+		protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind:
+		return true
+	}
+	return false
+}
+
+func isFixed32Kind(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return true
+	}
+	return false
+}
+
+func isFixed64Kind(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return true
+	}
+	return false
+}
+
+// isPackedValue reports whether v is a valid packed encoding of scalars of
+// the given kind.
+func isPackedValue(kind protoreflect.Kind, v []byte) bool {
+	switch {
+	case isVarintKind(kind):
+		for len(v) > 0 {
+			_, n := protowire.ConsumeVarint(v)
+			if n < 0 {
+				return false
+			}
+			v = v[n:]
+		}
+		return true
+	case isFixed32Kind(kind):
+		return len(v)%4 == 0
+	case isFixed64Kind(kind):
+		return len(v)%8 == 0
+	}
+	return false
+}
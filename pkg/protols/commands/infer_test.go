@@ -0,0 +1,228 @@
+package commands
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const inferTestFile = `
+name: "infer.proto"
+package: "infer"
+message_type {
+  name: "Person"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+  field { name: "id" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 }
+  field { name: "scores" number: 3 label: LABEL_REPEATED type: TYPE_INT32 options { packed: true } }
+  field { name: "address" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".infer.Address" }
+  field { name: "meta" number: 5 label: LABEL_OPTIONAL type: TYPE_GROUP type_name: ".infer.Person.Meta" }
+  field { name: "weights" number: 7 label: LABEL_REPEATED type: TYPE_FIXED32 options { packed: true } }
+  nested_type {
+    name: "Meta"
+    field { name: "key" number: 6 label: LABEL_OPTIONAL type: TYPE_STRING }
+  }
+}
+message_type {
+  name: "Address"
+  field { name: "street" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+  field { name: "city" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING }
+}
+message_type {
+  name: "Point"
+  field { name: "x" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 }
+  field { name: "y" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 }
+}
+message_type {
+  name: "Pair"
+  field { name: "a" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 }
+  field { name: "b" number: 2 label: LABEL_OPTIONAL type: TYPE_INT64 }
+}
+`
+
+func inferTestMessages(t *testing.T) map[protoreflect.Name]protoreflect.MessageDescriptor {
+	t.Helper()
+	fdp := &descriptorpb.FileDescriptorProto{}
+	require.NoError(t, prototext.Unmarshal([]byte(inferTestFile), fdp))
+	fd, err := protodesc.NewFile(fdp, nil)
+	require.NoError(t, err)
+	msgs := map[protoreflect.Name]protoreflect.MessageDescriptor{}
+	for i := 0; i < fd.Messages().Len(); i++ {
+		msgs[fd.Messages().Get(i).Name()] = fd.Messages().Get(i)
+	}
+	return msgs
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func TestInferMessageTypes(t *testing.T) {
+	msgs := inferTestMessages(t)
+	all := []protoreflect.MessageDescriptor{msgs["Person"], msgs["Address"], msgs["Point"], msgs["Pair"]}
+
+	address := appendString(appendString(nil, 1, "Main St"), 2, "Springfield")
+	var packedScores []byte
+	for _, v := range []uint64{1, 300, 70000} {
+		packedScores = protowire.AppendVarint(packedScores, v)
+	}
+	group := protowire.AppendTag(nil, 5, protowire.StartGroupType)
+	group = appendString(group, 6, "k")
+	group = protowire.AppendTag(group, 5, protowire.EndGroupType)
+
+	for _, tc := range []struct {
+		name  string
+		input []byte
+		// expected best candidate, and whether it should be a clear winner
+		best   protoreflect.Name
+		winner bool
+		// expected match fraction of individual candidates
+		matches map[protoreflect.Name]float64
+		// candidates which should not be considered at all
+		excluded []protoreflect.Name
+	}{
+		{
+			name:    "nested message",
+			input:   appendBytes(appendVarint(appendString(nil, 1, "alice"), 2, 7), 4, address),
+			best:    "Person",
+			winner:  true,
+			matches: map[protoreflect.Name]float64{"Person": 1},
+		},
+		{
+			name:    "packed repeated field",
+			input:   appendBytes(appendString(nil, 1, "bob"), 3, packedScores),
+			best:    "Person",
+			winner:  true,
+			matches: map[protoreflect.Name]float64{"Person": 1},
+		},
+		{
+			name:    "group",
+			input:   append(appendString(nil, 1, "carol"), group...),
+			best:    "Person",
+			winner:  true,
+			matches: map[protoreflect.Name]float64{"Person": 1},
+		},
+		{
+			// Point and Pair have the same wire structure
+			name:    "ambiguous",
+			input:   appendVarint(appendVarint(nil, 1, 3), 2, 4),
+			best:    "Pair",
+			winner:  false,
+			matches: map[protoreflect.Name]float64{"Pair": 1, "Point": 1},
+		},
+		{
+			// the nested message has a varint where a string is expected, which
+			// counts against Person along with the field containing it
+			name:    "nested mismatch",
+			input:   appendBytes(appendString(nil, 1, "dave"), 4, appendVarint(nil, 1, 1)),
+			best:    "Address",
+			winner:  false,
+			matches: map[protoreflect.Name]float64{"Person": 1.0 / 3, "Address": 0.5},
+		},
+		{
+			// 5 bytes is not a valid packed encoding of fixed32 values
+			name:    "invalid packed fixed32",
+			input:   appendBytes(appendString(nil, 1, "erin"), 7, []byte{1, 2, 3, 4, 5}),
+			best:    "Address",
+			winner:  false,
+			matches: map[protoreflect.Name]float64{"Person": 0.5, "Address": 0.5},
+		},
+		{
+			name:     "malformed input",
+			input:    []byte{0x0a, 0x05, 'a'},
+			excluded: []protoreflect.Name{"Person", "Address", "Point", "Pair"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			candidates := inferMessageTypes(tc.input, all)
+			for _, c := range candidates {
+				require.NotContains(t, tc.excluded, c.desc.Name())
+				require.True(t, c.score > 0 && c.score <= 1, "score out of range: %v", c.score)
+			}
+			if tc.best == "" {
+				require.Empty(t, candidates)
+				_, ok := clearWinner(candidates)
+				require.False(t, ok)
+				return
+			}
+			require.NotEmpty(t, candidates)
+			require.Equal(t, tc.best, candidates[0].desc.Name())
+			for name, want := range tc.matches {
+				idx := slices.IndexFunc(candidates, func(c typeCandidate) bool { return c.desc.Name() == name })
+				require.GreaterOrEqual(t, idx, 0, "missing candidate %s", name)
+				require.InDelta(t, want, candidates[idx].match, 1e-9, name)
+			}
+			best, ok := clearWinner(candidates)
+			require.Equal(t, tc.winner, ok)
+			if ok {
+				require.Equal(t, tc.best, best.desc.Name())
+			}
+		})
+	}
+}
+
+func TestInferMessageTypesDuplicates(t *testing.T) {
+	msgs := inferTestMessages(t)
+	input := appendVarint(nil, 1, 3)
+	candidates := inferMessageTypes(input, []protoreflect.MessageDescriptor{msgs["Point"], msgs["Point"]})
+	require.Len(t, candidates, 1)
+}
+
+func TestClearWinner(t *testing.T) {
+	msgs := inferTestMessages(t)
+	a, b := msgs["Point"], msgs["Pair"]
+	for _, tc := range []struct {
+		name       string
+		candidates []typeCandidate
+		want       bool
+	}{
+		{"none", nil, false},
+		{"single full match", []typeCandidate{{desc: a, match: 1, score: 0.8}}, true},
+		{"single partial match", []typeCandidate{{desc: a, match: 0.9, score: 0.9}}, false},
+		{"clear margin", []typeCandidate{{desc: a, match: 1, score: 0.95}, {desc: b, match: 1, score: 0.8}}, true},
+		{"within margin", []typeCandidate{{desc: a, match: 1, score: 0.95}, {desc: b, match: 1, score: 0.9}}, false},
+		{"tie", []typeCandidate{{desc: a, match: 1, score: 0.9}, {desc: b, match: 1, score: 0.9}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			best, ok := clearWinner(tc.candidates)
+			require.Equal(t, tc.want, ok)
+			if ok {
+				require.Equal(t, tc.candidates[0].desc, best.desc)
+			}
+		})
+	}
+}
+
+func TestIsPackedValue(t *testing.T) {
+	for _, tc := range []struct {
+		kind protoreflect.Kind
+		v    []byte
+		want bool
+	}{
+		{protoreflect.Int32Kind, protowire.AppendVarint(protowire.AppendVarint(nil, 1), 300), true},
+		{protoreflect.Int32Kind, []byte{0x80}, false},
+		{protoreflect.Fixed32Kind, make([]byte, 8), true},
+		{protoreflect.Fixed32Kind, make([]byte, 6), false},
+		{protoreflect.DoubleKind, make([]byte, 16), true},
+		{protoreflect.DoubleKind, make([]byte, 12), false},
+		{protoreflect.StringKind, []byte("abc"), false},
+	} {
+		require.Equal(t, tc.want, isPackedValue(tc.kind, tc.v), "%v %x", tc.kind, tc.v)
+	}
+}
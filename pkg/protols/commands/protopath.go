@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/kralicky/protols/pkg/sources"
+	"github.com/spf13/cobra"
+)
+
+// addProtoPathFlag adds the protoc-compatible --proto_path/-I flag to the
+// command. The flag can be given multiple times, and each value may contain
+// a list of directories separated by the OS path list separator.
+func addProtoPathFlag(cmd *cobra.Command, protoPaths *[]string) {
+	cmd.Flags().StringArrayVarP(protoPaths, "proto_path", "I", nil,
+		"directory in which to search for imports (can be given multiple times; searched in order)")
+}
+
+// splitProtoPaths expands the values given with --proto_path into a list of
+// directories.
+func splitProtoPaths(protoPaths []string) []string {
+	var dirs []string
+	for _, p := range protoPaths {
+		dirs = append(dirs, filepath.SplitList(p)...)
+	}
+	return dirs
+}
+
+// resolveInputFile returns the filename of the given input file. As with
+// protoc, a relative filename that does not exist in the current directory
+// is looked up within each of the proto paths in order.
+func resolveInputFile(filename string, protoPaths []string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	if _, err := os.Stat(filename); err == nil {
+		return filename
+	}
+	for _, dir := range protoPaths {
+		candidate := filepath.Join(dir, filename)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return filename
+}
+
+// searchProtoPaths returns the proto source files within the workspace and
+// each of the proto paths, without duplicates.
+func searchProtoPaths(workdir string, protoPaths []string) []string {
+	files := sources.SearchDirs(append([]string{workdir}, protoPaths...)...)
+	slices.Sort(files)
+	return slices.Compact(files)
+}
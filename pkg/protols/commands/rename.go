@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/kralicky/protols/pkg/sources"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/pkg/diff"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// RenameCmd represents the rename command
+func BuildRenameCmd() *cobra.Command {
+	var dryRun bool
+	var includeGoCode bool
+	cmd := &cobra.Command{
+		Use:   "rename <pkg.Symbol | file:line:col> <newName>",
+		Short: "Rename a symbol and update all references to it",
+		Long: `
+Renames a message, enum, enum value, field, oneof, service, or method, and
+updates all references to it in the workspace.
+
+The symbol to rename can be given either by its fully-qualified name, or by
+a position in a source file (with 1-based line and column numbers). Only the
+last component of the name is changed; newName should be a short name.
+
+With --dry-run, the changes are printed as a unified diff instead of being
+written to disk.
+`[1:],
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			cache := lsp.NewCache(protocol.WorkspaceFolder{
+				URI: string(protocol.URIFromPath(cwd)),
+			})
+			if includeGoCode {
+				cache.DidChangeConfiguration(cmd.Context(), lsp.Settings{
+					Rename: lsp.RenameSettings{IncludeGoCode: &includeGoCode},
+				})
+			}
+			cache.LoadFiles(sources.SearchDirs(cwd))
+
+			loc, err := findRenameTarget(cache, cwd, args[0])
+			if err != nil {
+				return err
+			}
+			params := protocol.LocationTextDocumentPositionParams(loc)
+			if _, err := cache.PrepareRename(params); err != nil {
+				return err
+			}
+			edit, err := cache.Rename(&protocol.RenameParams{
+				TextDocument: params.TextDocument,
+				Position:     params.Position,
+				NewName:      args[1],
+			})
+			if err != nil {
+				return err
+			}
+			return applyWorkspaceEdit(cmd, cwd, edit, dryRun)
+		},
+	}
+	cmd.Flags().BoolVarP(&dryRun, "dry-run", "n", false, "print a unified diff of the changes instead of writing them to disk")
+	cmd.Flags().BoolVar(&includeGoCode, "go", false, "also rename references to generated code in the local Go module")
+	return cmd
+}
+
+var filePositionRegex = regexp.MustCompile(`^(.+\.proto):(\d+):(\d+)$`)
+
+// findRenameTarget returns the location of the symbol identified by target,
+// which is either a file:line:col position or a fully-qualified name.
+func findRenameTarget(cache *lsp.Cache, cwd string, target string) (protocol.Location, error) {
+	if m := filePositionRegex.FindStringSubmatch(target); m != nil {
+		filename := m[1]
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(cwd, filename)
+		}
+		line, _ := strconv.Atoi(m[2])
+		col, _ := strconv.Atoi(m[3])
+		uri := protocol.URIFromPath(filename)
+		mapper, err := cache.XGetMapper(uri)
+		if err != nil {
+			return protocol.Location{}, fmt.Errorf("could not open %s: %w", m[1], err)
+		}
+		pos, err := mapper.LineCol8Position(line, col)
+		if err != nil {
+			return protocol.Location{}, fmt.Errorf("invalid position %s: %w", target, err)
+		}
+		return protocol.Location{
+			URI:   uri,
+			Range: protocol.Range{Start: pos, End: pos},
+		}, nil
+	}
+
+	name := protoreflect.FullName(strings.TrimPrefix(target, "."))
+	if !name.IsValid() {
+		return protocol.Location{}, fmt.Errorf("invalid symbol %q (expected pkg.Symbol or file:line:col)", target)
+	}
+	desc, err := cache.FindDescriptorByName(name)
+	if err != nil {
+		return protocol.Location{}, fmt.Errorf("could not find symbol %q: %w", name, err)
+	}
+	loc, err := cache.FindDefinitionForTypeDescriptor(desc)
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	loc.Range.End = loc.Range.Start
+	return loc, nil
+}
+
+// applyWorkspaceEdit writes the changes in the given workspace edit to disk,
+// or prints them as a unified diff if dryRun is set. The changes to every file
+// are computed before any are written, so that an error does not leave the
+// workspace partially renamed.
+func applyWorkspaceEdit(cmd *cobra.Command, cwd string, edit *protocol.WorkspaceEdit, dryRun bool) error {
+	uris := make([]protocol.DocumentURI, 0, len(edit.Changes))
+	for uri := range edit.Changes {
+		uris = append(uris, uri)
+	}
+	slices.Sort(uris)
+
+	type fileEdit struct {
+		filename string
+		content  string
+		edits    []diff.Edit
+		updated  string
+		perm     os.FileMode
+	}
+	files := make([]fileEdit, 0, len(uris))
+	for _, uri := range uris {
+		filename := uri.Path()
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		mapper := protocol.NewMapper(uri, content)
+		edits, err := protocol.EditsToDiffEdits(mapper, dedupeTextEdits(edit.Changes[uri]))
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		updated, err := diff.Apply(string(content), edits)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		files = append(files, fileEdit{
+			filename: filename,
+			content:  string(content),
+			edits:    edits,
+			updated:  updated,
+			perm:     info.Mode().Perm(),
+		})
+	}
+
+	if dryRun {
+		for _, f := range files {
+			label := f.filename
+			if rel, err := filepath.Rel(cwd, f.filename); err == nil && !strings.HasPrefix(rel, "..") {
+				label = filepath.ToSlash(rel)
+			}
+			unified, err := diff.ToUnified("a/"+label, "b/"+label, f.content, f.edits, diff.DefaultContextLines)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.filename, err)
+			}
+			fmt.Fprint(cmd.OutOrStdout(), unified)
+		}
+		return nil
+	}
+	for _, f := range files {
+		if err := os.WriteFile(f.filename, []byte(f.updated), f.perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dedupeTextEdits removes identical edits, which can occur when a symbol is
+// found both as a reference and as its own definition.
+func dedupeTextEdits(edits []protocol.TextEdit) []protocol.TextEdit {
+	edits = slices.Clone(edits)
+	slices.SortFunc(edits, func(a, b protocol.TextEdit) int {
+		return protocol.CompareRange(a.Range, b.Range)
+	})
+	return slices.CompactFunc(edits, func(a, b protocol.TextEdit) bool {
+		return a.Range == b.Range && a.NewText == b.NewText
+	})
+}
@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/kralicky/protols/pkg/sources"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+const renameTestProto = `syntax = "proto3";
+package foo;
+
+message Foo {
+  string name = 1;
+}
+
+message Bar {
+  Foo foo = 1;
+}
+`
+
+// writeRenameTestWorkspace writes the test proto file to a new temporary
+// directory, and changes the working directory to it for the duration of the
+// test.
+func writeRenameTestWorkspace(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "foo"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo", "foo.proto"), []byte(renameTestProto), 0o644))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestFindRenameTarget(t *testing.T) {
+	dir := writeRenameTestWorkspace(t)
+	cache := lsp.NewCache(protocol.WorkspaceFolder{URI: string(protocol.URIFromPath(dir))})
+	cache.LoadFiles(sources.SearchDirs(dir))
+
+	uri := protocol.URIFromPath(filepath.Join(dir, "foo", "foo.proto"))
+	at := func(line, char uint32) protocol.Location {
+		pos := protocol.Position{Line: line, Character: char}
+		return protocol.Location{URI: uri, Range: protocol.Range{Start: pos, End: pos}}
+	}
+	for _, tc := range []struct {
+		target  string
+		want    protocol.Location
+		wantErr string
+	}{
+		{target: "foo/foo.proto:4:9", want: at(3, 8)},
+		{target: filepath.Join(dir, "foo", "foo.proto") + ":5:10", want: at(4, 9)},
+		{target: "foo.Foo", want: at(3, 8)},
+		{target: ".foo.Foo", want: at(3, 8)},
+		{target: "foo.Foo.name", want: at(4, 9)},
+		{target: "foo.Missing", wantErr: `could not find symbol "foo.Missing"`},
+		{target: "foo..Foo", wantErr: `invalid symbol "foo..Foo"`},
+		{target: "foo/missing.proto:1:1", wantErr: "could not open foo/missing.proto"},
+	} {
+		t.Run(tc.target, func(t *testing.T) {
+			loc, err := findRenameTarget(cache, dir, tc.target)
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, loc)
+		})
+	}
+}
+
+func TestRenameCmd(t *testing.T) {
+	dir := writeRenameTestWorkspace(t)
+	filename := filepath.Join(dir, "foo", "foo.proto")
+
+	run := func(args ...string) string {
+		t.Helper()
+		cmd := BuildRenameCmd()
+		var stdout bytes.Buffer
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		require.NoError(t, cmd.Execute())
+		return stdout.String()
+	}
+
+	out := run("--dry-run", "foo.Foo", "Baz")
+	require.Equal(t, strings.Join([]string{
+		`--- a/foo/foo.proto`,
+		`+++ b/foo/foo.proto`,
+		`@@ -1,10 +1,10 @@`,
+		` syntax = "proto3";`,
+		` package foo;`,
+		` `,
+		`-message Foo {`,
+		`+message Baz {`,
+		`   string name = 1;`,
+		` }`,
+		` `,
+		` message Bar {`,
+		`-  Foo foo = 1;`,
+		`+  Baz foo = 1;`,
+		` }`,
+		"",
+	}, "\n"), out)
+	content, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Equal(t, renameTestProto, string(content), "--dry-run should not modify files")
+
+	require.Empty(t, run("foo.Foo", "Baz"))
+	content, err = os.ReadFile(filename)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "message Baz {")
+	require.Contains(t, string(content), "  Baz foo = 1;")
+}
+
+func TestDedupeTextEdits(t *testing.T) {
+	edit := func(line, start, end uint32, text string) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line, Character: start},
+				End:   protocol.Position{Line: line, Character: end},
+			},
+			NewText: text,
+		}
+	}
+	for _, tc := range []struct {
+		name  string
+		edits []protocol.TextEdit
+		want  []protocol.TextEdit
+	}{
+		{
+			name:  "empty",
+			edits: nil,
+			want:  nil,
+		},
+		{
+			name:  "duplicates",
+			edits: []protocol.TextEdit{edit(3, 8, 11, "Baz"), edit(8, 2, 5, "Baz"), edit(3, 8, 11, "Baz")},
+			want:  []protocol.TextEdit{edit(3, 8, 11, "Baz"), edit(8, 2, 5, "Baz")},
+		},
+		{
+			name:  "sorted by range",
+			edits: []protocol.TextEdit{edit(8, 2, 5, "Baz"), edit(3, 8, 11, "Baz"), edit(3, 0, 2, "x")},
+			want:  []protocol.TextEdit{edit(3, 0, 2, "x"), edit(3, 8, 11, "Baz"), edit(8, 2, 5, "Baz")},
+		},
+		{
+			// conflicting edits are kept, and rejected when they are applied
+			name:  "same range, different text",
+			edits: []protocol.TextEdit{edit(3, 8, 11, "Baz"), edit(3, 8, 11, "Qux")},
+			want:  []protocol.TextEdit{edit(3, 8, 11, "Baz"), edit(3, 8, 11, "Qux")},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			edits := slices.Clone(tc.edits)
+			require.Equal(t, tc.want, dedupeTextEdits(tc.edits))
+			require.Equal(t, edits, tc.edits, "input should not be modified")
+		})
+	}
+}
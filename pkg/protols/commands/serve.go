@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/kralicky/protols/pkg/lsprpc"
 	"github.com/kralicky/protols/pkg/version"
@@ -20,13 +24,27 @@ import (
 
 // ServeCmd represents the serve command
 func BuildServeCmd() *cobra.Command {
+	var stdio bool
 	var pipe string
+	var listen string
 	cmd := &cobra.Command{
-		Use:   "serve",
+		Use:   "serve [--stdio | --pipe=<socket> | --listen=tcp:host:port]",
 		Short: "Start the language server",
+		Long: `
+Starts the language server. By default, the server communicates with a single
+client over stdin and stdout.
+
+With --pipe, the server connects to an existing unix socket (this is the
+transport used by the VSCode extension).
+
+With --listen, the server accepts any number of client connections on the
+given address. Each connection is served by an independent session. The address
+is of the form 'tcp:host:port' or 'unix:/path/to/socket'.
+`[1:],
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.Printf("Starting protols %s\n", version.FriendlyVersion())
-			slog.SetDefault(slog.New(slog.NewTextHandler(cmd.OutOrStderr(), &slog.HandlerOptions{
+			slog.SetDefault(slog.New(slog.NewTextHandler(cmd.ErrOrStderr(), &slog.HandlerOptions{
 				AddSource: true,
 				Level:     slog.LevelDebug,
 			})))
@@ -54,19 +72,105 @@ func BuildServeCmd() *cobra.Command {
 				return ctx
 			})
 
-			cc, err := net.Dial("unix", pipe)
-			if err != nil {
-				return err
-			}
-			stream := jsonrpc2.NewHeaderStream(cc)
-			conn := jsonrpc2.NewConn(stream)
 			ss := lsprpc.NewStreamServer()
-			return ss.ServeStream(cmd.Context(), conn)
+			switch {
+			case listen != "":
+				network, addr, err := parseListenAddr(listen)
+				if err != nil {
+					return err
+				}
+				slog.Info("listening for connections", "network", network, "address", addr)
+				return jsonrpc2.ListenAndServe(cmd.Context(), network, addr, ss, 0)
+			case pipe != "":
+				cc, err := net.Dial("unix", pipe)
+				if err != nil {
+					return err
+				}
+				return serveConn(cmd.Context(), ss, cc)
+			default:
+				return serveConn(cmd.Context(), ss, newStdioConn(cmd.InOrStdin(), cmd.OutOrStdout()))
+			}
 		},
 	}
 
-	cmd.Flags().StringVar(&pipe, "pipe", "", "socket name to listen on")
-	cmd.MarkFlagRequired("pipe")
+	cmd.Flags().BoolVar(&stdio, "stdio", false, "communicate over stdin/stdout (default)")
+	cmd.Flags().StringVar(&pipe, "pipe", "", "socket name to connect to")
+	cmd.Flags().StringVar(&listen, "listen", "", "address to listen on for client connections (tcp:host:port or unix:path)")
+	cmd.MarkFlagsMutuallyExclusive("stdio", "pipe", "listen")
 
 	return cmd
 }
+
+// serveConn runs a single language server session over the given connection.
+func serveConn(ctx context.Context, ss jsonrpc2.StreamServer, nc net.Conn) error {
+	stream := jsonrpc2.NewHeaderStream(nc)
+	defer stream.Close()
+	return ss.ServeStream(ctx, jsonrpc2.NewConn(stream))
+}
+
+func parseListenAddr(listen string) (network string, addr string, _ error) {
+	network, addr, ok := strings.Cut(listen, ":")
+	if !ok {
+		return "", "", fmt.Errorf("invalid listen address %q (expected tcp:host:port or unix:path)", listen)
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return "", "", fmt.Errorf("unsupported network %q in listen address %q", network, listen)
+	}
+	if addr == "" {
+		return "", "", fmt.Errorf("missing address in listen address %q", listen)
+	}
+	return network, addr, nil
+}
+
+// stdioConn adapts the process's stdin and stdout to a net.Conn so that it
+// can be framed the same way as the socket transports.
+type stdioConn struct {
+	io.Reader
+	io.Writer
+	closeOnce sync.Once
+}
+
+func newStdioConn(in io.Reader, out io.Writer) *stdioConn {
+	return &stdioConn{
+		Reader: in,
+		Writer: out,
+	}
+}
+
+// Close implements net.Conn.
+func (c *stdioConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		if rc, ok := c.Reader.(io.Closer); ok {
+			err = errors.Join(err, rc.Close())
+		}
+		if wc, ok := c.Writer.(io.Closer); ok {
+			err = errors.Join(err, wc.Close())
+		}
+	})
+	return err
+}
+
+// LocalAddr implements net.Conn.
+func (c *stdioConn) LocalAddr() net.Addr { return stdioAddr{} }
+
+// RemoteAddr implements net.Conn.
+func (c *stdioConn) RemoteAddr() net.Addr { return stdioAddr{} }
+
+// SetDeadline implements net.Conn.
+func (c *stdioConn) SetDeadline(time.Time) error { return nil }
+
+// SetReadDeadline implements net.Conn.
+func (c *stdioConn) SetReadDeadline(time.Time) error { return nil }
+
+// SetWriteDeadline implements net.Conn.
+func (c *stdioConn) SetWriteDeadline(time.Time) error { return nil }
+
+var _ net.Conn = (*stdioConn)(nil)
+
+type stdioAddr struct{}
+
+func (stdioAddr) Network() string { return "stdio" }
+func (stdioAddr) String() string  { return "stdio" }
@@ -2,7 +2,9 @@ package commands
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"slices"
 
 	"github.com/kralicky/protols/pkg/sources"
 	"github.com/kralicky/protols/sdk/driver"
@@ -11,21 +13,55 @@ import (
 
 // VetCmd represents the vet command
 func BuildVetCmd() *cobra.Command {
+	var protoPaths []string
+	var format string
 	cmd := &cobra.Command{
-		Use:   "vet",
-		Short: "A brief description of your command",
+		Use:   "vet [flags] [path ...]",
+		Short: "Report errors and warnings in proto source files",
+		Long: `
+Compiles the proto source files in the given paths, and reports any errors or
+warnings. Directories are searched recursively for .proto files. If no paths
+are given, the current directory is searched.
+
+The --format flag selects how diagnostics are reported:
+  text    human-readable messages (default)
+  json    a JSON array of diagnostics
+  sarif   a SARIF 2.1.0 log, for use with code scanning tools
+  github  GitHub Actions workflow commands, shown as annotations
+
+The command exits with a non-zero status if any errors were reported.
+`[1:],
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if !slices.Contains(driver.ReportFormats, driver.ReportFormat(format)) {
+				return fmt.Errorf("unknown format %q (must be one of %v)", format, driver.ReportFormats)
+			}
 			wd, err := os.Getwd()
 			if err != nil {
 				return err
 			}
-			driver := driver.NewDriver(wd)
-			results, err := driver.Compile(sources.SearchDirs(wd))
+			if len(args) == 0 {
+				args = []string{wd}
+			}
+			paths := make([]string, len(args))
+			for i, arg := range args {
+				paths[i] = resolveInputFile(arg, splitProtoPaths(protoPaths))
+				if _, err := os.Stat(paths[i]); err != nil {
+					return err
+				}
+			}
+			cmd.SilenceUsage = true
+			drv := driver.NewDriver(wd, driver.WithIncludePaths(splitProtoPaths(protoPaths)))
+			results, err := drv.Compile(sources.SearchDirs(paths...))
 			if err != nil {
 				return err
 			}
-			for _, msg := range results.Messages {
-				cmd.Println(msg)
+			// human-readable messages are written to stderr, as before
+			out := cmd.OutOrStdout()
+			if format == string(driver.ReportFormatText) {
+				out = cmd.ErrOrStderr()
+			}
+			if err := results.Report(out, driver.ReportFormat(format)); err != nil {
+				return err
 			}
 			if results.Error {
 				return errors.New("one or more errors occurred")
@@ -33,5 +69,7 @@ func BuildVetCmd() *cobra.Command {
 			return nil
 		},
 	}
+	cmd.Flags().StringVar(&format, "format", string(driver.ReportFormatText), "output format (text, json, sarif, or github)")
+	addProtoPathFlag(cmd, &protoPaths)
 	return cmd
 }
@@ -20,6 +20,10 @@ func BuildRootCmd() *cobra.Command {
 	rootCmd.AddCommand(commands.BuildServeCmd())
 	rootCmd.AddCommand(commands.BuildVetCmd())
 	rootCmd.AddCommand(commands.BuildDecodeCmd())
+	rootCmd.AddCommand(commands.BuildEncodeCmd())
+	rootCmd.AddCommand(commands.BuildRenameCmd())
+	rootCmd.AddCommand(commands.BuildGenerateCmd())
+	rootCmd.AddCommand(commands.BuildBreakingCmd())
 	//+cobra:subcommands
 
 	return rootCmd
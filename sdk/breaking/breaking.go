@@ -0,0 +1,327 @@
+// Package breaking detects wire- and source-incompatible changes between two
+// versions of a set of proto files.
+package breaking
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kralicky/protocompile/ast"
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protocompile/protoutil"
+	"github.com/kralicky/protocompile/walk"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Kind identifies the type of a breaking change.
+type Kind string
+
+const (
+	FileRemoved        Kind = "file-removed"
+	MessageRemoved     Kind = "message-removed"
+	FieldRemoved       Kind = "field-removed"
+	FieldRenamed       Kind = "field-renamed"
+	FieldNumberChanged Kind = "field-number-changed"
+	FieldTypeChanged   Kind = "field-type-changed"
+	FieldLabelChanged  Kind = "field-label-changed"
+	EnumRemoved        Kind = "enum-removed"
+	EnumValueRemoved   Kind = "enum-value-removed"
+	EnumValueRenamed   Kind = "enum-value-renamed"
+	ServiceRemoved     Kind = "service-removed"
+	RPCRemoved         Kind = "rpc-removed"
+	RPCTypeChanged     Kind = "rpc-type-changed"
+)
+
+// Change is a breaking change found between the old and new versions.
+type Change struct {
+	Kind    Kind
+	Message string
+	// The path of the file containing the change. This is a file in the new
+	// version, unless the whole file was removed.
+	Filename string
+	// The location of the change in the new version of the file, or nil if
+	// the file or its parent element was removed.
+	Span ast.SourceSpan
+}
+
+func (c Change) String() string {
+	if c.Span == nil {
+		return fmt.Sprintf("%s: %s", c.Filename, c.Message)
+	}
+	start := c.Span.Start()
+	return fmt.Sprintf("%s:%d:%d: %s", c.Filename, start.Line, start.Col, c.Message)
+}
+
+type index struct {
+	files       map[string]linker.Result
+	descriptors map[protoreflect.FullName]protoreflect.Descriptor
+}
+
+func newIndex(results []linker.Result) *index {
+	idx := &index{
+		files:       make(map[string]linker.Result, len(results)),
+		descriptors: map[protoreflect.FullName]protoreflect.Descriptor{},
+	}
+	for _, res := range results {
+		idx.files[res.Path()] = res
+		walk.Descriptors(res, func(d protoreflect.Descriptor) error {
+			idx.descriptors[d.FullName()] = d
+			return nil
+		})
+	}
+	return idx
+}
+
+type comparer struct {
+	old, new *index
+	changes  []Change
+}
+
+// Compare returns the breaking changes between the old and new versions of
+// the given files, sorted by location. Elements are matched by their fully
+// qualified names, so moving an element between files is not reported unless
+// the old file was removed.
+func Compare(old, new []linker.Result) []Change {
+	c := &comparer{old: newIndex(old), new: newIndex(new)}
+	for _, res := range old {
+		if _, ok := c.new.files[res.Path()]; !ok {
+			c.changes = append(c.changes, Change{
+				Kind:     FileRemoved,
+				Message:  fmt.Sprintf("file %s was removed", res.Path()),
+				Filename: res.Path(),
+			})
+		}
+		walk.Descriptors(res, func(d protoreflect.Descriptor) error {
+			switch d := d.(type) {
+			case protoreflect.MessageDescriptor:
+				if !d.IsMapEntry() {
+					c.compareMessage(d)
+				}
+			case protoreflect.EnumDescriptor:
+				c.compareEnum(d)
+			case protoreflect.ServiceDescriptor:
+				c.compareService(d)
+			}
+			return nil
+		})
+	}
+	slices.SortStableFunc(c.changes, func(a, b Change) int {
+		var aLine, aCol, bLine, bCol int
+		if a.Span != nil {
+			aLine, aCol = a.Span.Start().Line, a.Span.Start().Col
+		}
+		if b.Span != nil {
+			bLine, bCol = b.Span.Start().Line, b.Span.Start().Col
+		}
+		return cmp.Or(
+			strings.Compare(a.Filename, b.Filename),
+			cmp.Compare(aLine, bLine),
+			cmp.Compare(aCol, bCol),
+		)
+	})
+	return c.changes
+}
+
+// report adds a change located at the given descriptor in the new version.
+func (c *comparer) report(kind Kind, at protoreflect.Descriptor, format string, args ...any) {
+	change := Change{
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, args...),
+		Filename: at.ParentFile().Path(),
+	}
+	if res, ok := c.new.files[at.ParentFile().Path()]; ok {
+		if node := nameNode(res, at); node != nil {
+			change.Span = res.AST().NodeInfo(node)
+		}
+	}
+	c.changes = append(c.changes, change)
+}
+
+// reportRemoved adds a change for an element which no longer exists, located
+// at its parent in the new version. If the parent was removed as well, the
+// change is not reported, since the parent will have been.
+func (c *comparer) reportRemoved(kind Kind, old protoreflect.Descriptor, format string, args ...any) {
+	parent := old.Parent()
+	if _, ok := parent.(protoreflect.FileDescriptor); ok {
+		if res, ok := c.new.files[parent.ParentFile().Path()]; ok {
+			c.report(kind, res, format, args...)
+		} else {
+			c.changes = append(c.changes, Change{
+				Kind:     kind,
+				Message:  fmt.Sprintf(format, args...),
+				Filename: parent.ParentFile().Path(),
+			})
+		}
+		return
+	}
+	if newParent, ok := c.new.descriptors[parent.FullName()]; ok {
+		c.report(kind, newParent, format, args...)
+	}
+}
+
+func (c *comparer) compareMessage(old protoreflect.MessageDescriptor) {
+	d, ok := c.new.descriptors[old.FullName()]
+	newMsg, isMsg := d.(protoreflect.MessageDescriptor)
+	if !ok || !isMsg {
+		c.reportRemoved(MessageRemoved, old, "message %s was removed", old.FullName())
+		return
+	}
+	fields := old.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		oldField := fields.Get(i)
+		if byName := newMsg.Fields().ByName(oldField.Name()); byName != nil && byName.Number() != oldField.Number() {
+			c.report(FieldNumberChanged, byName, "field %s changed number from %d to %d", oldField.FullName(), oldField.Number(), byName.Number())
+			continue
+		}
+		newField := newMsg.Fields().ByNumber(oldField.Number())
+		if newField == nil {
+			if !newMsg.ReservedRanges().Has(oldField.Number()) {
+				c.report(FieldRemoved, newMsg, "field %s (number %d) was removed without reserving its number", oldField.FullName(), oldField.Number())
+			}
+			continue
+		}
+		if newField.Name() != oldField.Name() {
+			c.report(FieldRenamed, newField, "field %d of %s was renamed from %q to %q", oldField.Number(), old.FullName(), oldField.Name(), newField.Name())
+		}
+		if oldLabel, newLabel := fieldLabel(oldField), fieldLabel(newField); oldLabel != newLabel {
+			c.report(FieldLabelChanged, newField, "field %s changed label from %s to %s", newField.FullName(), oldLabel, newLabel)
+		} else if oldType, newType := fieldType(oldField), fieldType(newField); oldType != newType {
+			c.report(FieldTypeChanged, newField, "field %s changed type from %s to %s", newField.FullName(), oldType, newType)
+		}
+	}
+}
+
+func (c *comparer) compareEnum(old protoreflect.EnumDescriptor) {
+	d, ok := c.new.descriptors[old.FullName()]
+	newEnum, isEnum := d.(protoreflect.EnumDescriptor)
+	if !ok || !isEnum {
+		c.reportRemoved(EnumRemoved, old, "enum %s was removed", old.FullName())
+		return
+	}
+	values := old.Values()
+	for i := 0; i < values.Len(); i++ {
+		oldValue := values.Get(i)
+		newValue := newEnum.Values().ByNumber(oldValue.Number())
+		if newValue == nil {
+			if !newEnum.ReservedRanges().Has(oldValue.Number()) {
+				c.report(EnumValueRemoved, newEnum, "enum value %s (number %d) was removed without reserving its number", oldValue.Name(), oldValue.Number())
+			}
+			continue
+		}
+		if newValue.Name() != oldValue.Name() && newEnum.Values().ByName(oldValue.Name()) == nil {
+			c.report(EnumValueRenamed, newValue, "enum value %d of %s was renamed from %s to %s", oldValue.Number(), old.FullName(), oldValue.Name(), newValue.Name())
+		}
+	}
+}
+
+func (c *comparer) compareService(old protoreflect.ServiceDescriptor) {
+	d, ok := c.new.descriptors[old.FullName()]
+	newSvc, isSvc := d.(protoreflect.ServiceDescriptor)
+	if !ok || !isSvc {
+		c.reportRemoved(ServiceRemoved, old, "service %s was removed", old.FullName())
+		return
+	}
+	methods := old.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		oldMethod := methods.Get(i)
+		newMethod := newSvc.Methods().ByName(oldMethod.Name())
+		if newMethod == nil {
+			c.report(RPCRemoved, newSvc, "rpc %s was removed", oldMethod.FullName())
+			continue
+		}
+		if oldSig, newSig := methodSignature(oldMethod), methodSignature(newMethod); oldSig != newSig {
+			c.report(RPCTypeChanged, newMethod, "rpc %s changed from %s to %s", newMethod.FullName(), oldSig, newSig)
+		}
+	}
+}
+
+func fieldLabel(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsMap():
+		return "map"
+	case fd.IsList():
+		return "repeated"
+	case fd.Cardinality() == protoreflect.Required:
+		return "required"
+	case fd.HasOptionalKeyword():
+		return "optional"
+	default:
+		return "no label"
+	}
+}
+
+func fieldType(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsMap():
+		return fmt.Sprintf("map<%s, %s>", fieldType(fd.MapKey()), fieldType(fd.MapValue()))
+	case fd.Message() != nil:
+		return string(fd.Message().FullName())
+	case fd.Enum() != nil:
+		return string(fd.Enum().FullName())
+	default:
+		return fd.Kind().String()
+	}
+}
+
+func methodSignature(md protoreflect.MethodDescriptor) string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	if md.IsStreamingClient() {
+		sb.WriteString("stream ")
+	}
+	sb.WriteString(string(md.Input().FullName()))
+	sb.WriteString(") returns (")
+	if md.IsStreamingServer() {
+		sb.WriteString("stream ")
+	}
+	sb.WriteString(string(md.Output().FullName()))
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// nameNode returns the node for the name of the descriptor, or the package
+// declaration for a file.
+func nameNode(res linker.Result, desc protoreflect.Descriptor) ast.Node {
+	if _, ok := desc.(protoreflect.FileDescriptor); ok {
+		for _, decl := range res.AST().Decls {
+			if pkg := decl.GetPackage(); pkg != nil {
+				return pkg
+			}
+		}
+		return nil
+	}
+	wrapper, ok := desc.(protoutil.DescriptorProtoWrapper)
+	if !ok {
+		return nil
+	}
+	switch d := wrapper.AsProto().(type) {
+	case *descriptorpb.DescriptorProto:
+		if name := res.MessageNode(d).GetName(); name != nil {
+			return name
+		}
+	case *descriptorpb.FieldDescriptorProto:
+		if name := res.FieldNode(d).GetName(); name != nil {
+			return name
+		}
+	case *descriptorpb.EnumDescriptorProto:
+		if node := res.EnumNode(d); node != nil && node.Name != nil {
+			return node.Name
+		}
+	case *descriptorpb.EnumValueDescriptorProto:
+		if node := res.EnumValueNode(d); node != nil && node.Name != nil {
+			return node.Name
+		}
+	case *descriptorpb.ServiceDescriptorProto:
+		if node := res.ServiceNode(d); node != nil && node.Name != nil {
+			return node.Name
+		}
+	case *descriptorpb.MethodDescriptorProto:
+		if node := res.MethodNode(d); node != nil && node.Name != nil {
+			return node.Name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package breaking_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kralicky/protocompile/linker"
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/kralicky/protols/sdk/breaking"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func compile(t *testing.T, files map[string]string) []linker.Result {
+	t.Helper()
+	dir := t.TempDir()
+	var filenames []string
+	for name, contents := range files {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0o755))
+		require.NoError(t, os.WriteFile(filename, []byte(contents), 0o644))
+		filenames = append(filenames, filename)
+	}
+	cache := lsp.NewCache(protocol.WorkspaceFolder{URI: string(protocol.URIFromPath(dir))})
+	cache.LoadFiles(filenames)
+	var results []linker.Result
+	for _, res := range cache.XGetLinkerResults() {
+		if _, ok := files[res.Path()]; ok {
+			results = append(results, res)
+		}
+	}
+	require.Len(t, results, len(files))
+	return results
+}
+
+func TestCompare(t *testing.T) {
+	old := compile(t, map[string]string{
+		"a.proto": `
+syntax = "proto3";
+package a;
+message A {
+  string name = 1;
+  int32 count = 2;
+  repeated string tags = 3;
+  string removed = 4;
+  string reserved_field = 5;
+  map<string, int32> labels = 6;
+  message Nested {
+    string x = 1;
+  }
+}
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_ONE = 1;
+  KIND_TWO = 2;
+}
+service Svc {
+  rpc Get(A) returns (A);
+  rpc Watch(A) returns (stream A);
+}
+`,
+		"b.proto": `
+syntax = "proto3";
+package a;
+message B {}
+`,
+	})
+	new := compile(t, map[string]string{
+		"a.proto": `
+syntax = "proto3";
+package a;
+message A {
+  string name = 10;
+  int64 count = 2;
+  string tags = 3;
+  reserved 5;
+  map<string, string> labels = 6;
+}
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_UNO = 1;
+}
+service Svc {
+  rpc Get(A) returns (stream A);
+}
+`,
+	})
+	var got []string
+	var kinds []breaking.Kind
+	for _, change := range breaking.Compare(old, new) {
+		got = append(got, change.String())
+		kinds = append(kinds, change.Kind)
+	}
+	require.Equal(t, []string{
+		"a.proto:4:9: field a.A.removed (number 4) was removed without reserving its number",
+		"a.proto:4:9: message a.A.Nested was removed",
+		"a.proto:5:10: field a.A.name changed number from 1 to 10",
+		"a.proto:6:9: field a.A.count changed type from int32 to int64",
+		"a.proto:7:10: field a.A.tags changed label from repeated to no label",
+		"a.proto:9:23: field a.A.labels changed type from map<string, int32> to map<string, string>",
+		"a.proto:11:6: enum value KIND_TWO (number 2) was removed without reserving its number",
+		"a.proto:13:3: enum value 1 of a.Kind was renamed from KIND_ONE to KIND_UNO",
+		"a.proto:15:9: rpc a.Svc.Watch was removed",
+		"a.proto:16:7: rpc a.Svc.Get changed from (a.A) returns (a.A) to (a.A) returns (stream a.A)",
+		"b.proto: file b.proto was removed",
+		"b.proto: message a.B was removed",
+	}, got)
+	require.Equal(t, []breaking.Kind{
+		breaking.FieldRemoved,
+		breaking.MessageRemoved,
+		breaking.FieldNumberChanged,
+		breaking.FieldTypeChanged,
+		breaking.FieldLabelChanged,
+		breaking.FieldTypeChanged,
+		breaking.EnumValueRemoved,
+		breaking.EnumValueRenamed,
+		breaking.RPCRemoved,
+		breaking.RPCTypeChanged,
+		breaking.FileRemoved,
+		breaking.MessageRemoved,
+	}, kinds)
+
+	require.Empty(t, breaking.Compare(old, old))
+}
+
+func TestCompareMovedMessage(t *testing.T) {
+	old := compile(t, map[string]string{
+		"a.proto": `
+syntax = "proto3";
+package a;
+message A {}
+message B {}
+`,
+	})
+	new := compile(t, map[string]string{
+		"a.proto": `
+syntax = "proto3";
+package a;
+message A {}
+`,
+		"b.proto": `
+syntax = "proto3";
+package a;
+message B {}
+`,
+	})
+	require.Empty(t, breaking.Compare(old, new))
+}
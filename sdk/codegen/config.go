@@ -0,0 +1,118 @@
+package codegen
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kralicky/codegen/cli"
+	"github.com/kralicky/codegen/pathbuilder"
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/kralicky/protols/sdk/codegen/generators/external"
+	"github.com/kralicky/protols/sdk/codegen/generators/golang"
+	"github.com/kralicky/protols/sdk/codegen/generators/golang/grpc"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/gofeaturespb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var builtinGenerators = map[string]Generator{
+	golang.Generator.Name():      golang.Generator,
+	grpc.Generator.Name():        grpc.Generator,
+	pathbuilder.Generator.Name(): pathbuilder.Generator,
+	cli.Generator.Name():         cli.Generator,
+}
+
+// BuiltinGenerators returns the names of the generators which can be named in
+// a generation config without an external plugin.
+func BuiltinGenerators() []string {
+	names := make([]string, 0, len(builtinGenerators))
+	for name := range builtinGenerators {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ConfiguredGenerator is a generator listed in a generation config.
+type ConfiguredGenerator struct {
+	Generator
+	// Output directory, relative to the workspace root. If empty, generated
+	// files are written alongside the sources they were generated from.
+	Out string
+
+	// protogen parameter for built-in generators; external plugins receive
+	// their options directly.
+	parameter string
+}
+
+// NewConfiguredGenerators returns the generators listed in the config.
+func NewConfiguredGenerators(config *lsp.GenerateConfig) ([]Generator, error) {
+	var generators []Generator
+	for _, gc := range config.Generators {
+		cg := &ConfiguredGenerator{Out: gc.Out}
+		if gc.Name != "" {
+			g, ok := builtinGenerators[gc.Name]
+			if !ok {
+				return nil, fmt.Errorf("unknown generator %q (available: %s)", gc.Name, strings.Join(BuiltinGenerators(), ", "))
+			}
+			cg.Generator = g
+			cg.parameter = gc.Parameter()
+		} else {
+			cg.Generator = external.NewGenerator([]string(gc.Plugin), external.GeneratorOptions{
+				Opt: gc.Parameter(),
+			})
+		}
+		generators = append(generators, cg)
+	}
+	return generators, nil
+}
+
+// LoadWorkspaceGenerators returns the generators listed in the generation
+// config in the given workspace directory, or DefaultGenerators if there is
+// no config.
+func LoadWorkspaceGenerators(dir string) ([]Generator, error) {
+	config, err := lsp.LoadGenerateConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return DefaultGenerators(), nil
+	}
+	return NewConfiguredGenerators(config)
+}
+
+// OutputDir returns the output directory configured for the generator, or an
+// empty string if its files should be written alongside their sources.
+func OutputDir(g Generator) string {
+	if cg, ok := g.(*ConfiguredGenerator); ok {
+		return cg.Out
+	}
+	return ""
+}
+
+// Run runs the generator against its own copy of the request, and returns the
+// files it generated.
+func Run(g Generator, req *pluginpb.CodeGeneratorRequest) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+	req = proto.Clone(req).(*pluginpb.CodeGeneratorRequest)
+	req.Parameter = nil
+	if cg, ok := g.(*ConfiguredGenerator); ok && cg.parameter != "" {
+		req.Parameter = &cg.parameter
+	}
+	plugin, err := (protogen.Options{
+		DefaultAPILevel: gofeaturespb.GoFeatures_API_OPEN,
+	}).New(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", g.Name(), err)
+	}
+	if err := g.Generate(plugin); err != nil {
+		return nil, fmt.Errorf("%s: %w", g.Name(), err)
+	}
+	response := plugin.Response()
+	if response.Error != nil {
+		return nil, fmt.Errorf("%s: %w", g.Name(), errors.New(response.GetError()))
+	}
+	return response.GetFile(), nil
+}
@@ -1,21 +1,17 @@
 package codegen
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
-	_ "github.com/kralicky/codegen/cli"
-	_ "github.com/kralicky/codegen/pathbuilder"
 	"github.com/kralicky/protols/pkg/sources"
 	"github.com/kralicky/protols/sdk/codegen/generators/golang"
 	"github.com/kralicky/protols/sdk/codegen/generators/golang/grpc"
 	"github.com/kralicky/protols/sdk/driver"
 	"google.golang.org/protobuf/compiler/protogen"
-	"google.golang.org/protobuf/types/gofeaturespb"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -43,6 +39,9 @@ func (g *GeneratedFile) Read(p []byte) (int, error) {
 }
 
 func (g *GeneratedFile) WriteToDisk() error {
+	if err := os.MkdirAll(filepath.Dir(g.SourceRelPath), 0o755); err != nil {
+		return err
+	}
 	return os.WriteFile(g.SourceRelPath, []byte(g.Content), 0o644)
 }
 
@@ -57,7 +56,8 @@ const (
 )
 
 type GenerateCodeOptions struct {
-	strategy GenerateStrategy
+	strategy     GenerateStrategy
+	includePaths []string
 }
 
 type GenerateCodeOption func(*GenerateCodeOptions)
@@ -74,6 +74,14 @@ func WithGenerateStrategy(strategy GenerateStrategy) GenerateCodeOption {
 	}
 }
 
+// WithIncludePaths sets directories to search for imported files, in order
+// of precedence. See driver.WithIncludePaths.
+func WithIncludePaths(includePaths []string) GenerateCodeOption {
+	return func(o *GenerateCodeOptions) {
+		o.includePaths = includePaths
+	}
+}
+
 // Generates code for each source file found in the given search directories,
 // using one or more code generators.
 func GenerateCode(generators []Generator, searchDirs []string, opts ...GenerateCodeOption) ([]*GeneratedFile, error) {
@@ -91,7 +99,10 @@ func GenerateCode(generators []Generator, searchDirs []string, opts ...GenerateC
 			searchDirs[i] = filepath.Join(wd, dir)
 		}
 	}
-	driver := driver.NewDriver(wd, driver.WithRenameStrategy(driver.RestoreExternalGoModuleDescriptorNames))
+	driver := driver.NewDriver(wd,
+		driver.WithRenameStrategy(driver.RestoreExternalGoModuleDescriptorNames),
+		driver.WithIncludePaths(options.includePaths),
+	)
 	results, err := driver.Compile(sources.SearchDirs(searchDirs...))
 	if err != nil {
 		return nil, err
@@ -133,46 +144,45 @@ func GenerateCode(generators []Generator, searchDirs []string, opts ...GenerateC
 		}
 	}
 
-	plugin, err := (protogen.Options{
-		DefaultAPILevel: gofeaturespb.GoFeatures_API_OPEN,
-	}).New(&pluginpb.CodeGeneratorRequest{
+	request := &pluginpb.CodeGeneratorRequest{
 		FileToGenerate: toGenerate,
 		ProtoFile:      results.AllDescriptorProtos,
-	})
-	if err != nil {
-		return nil, err
 	}
 
+	var outputs []*GeneratedFile
 	for _, g := range generators {
-		if err := g.Generate(plugin); err != nil {
+		files, err := Run(g, request)
+		if err != nil {
 			return nil, err
 		}
-	}
-
-	response := plugin.Response()
-	if response.Error != nil {
-		return nil, errors.New(response.GetError())
-	}
-
-	var outputs []*GeneratedFile
-	for _, f := range response.GetFile() {
-		pkg, name := filepath.Split(f.GetName())
-		pkg = strings.TrimSuffix(pkg, "/")
-		dir, ok := sourcePkgDirs[pkg]
-		if !ok {
-			if strings.Contains(pkg, "google/") {
-				dir = pkg[strings.Index(pkg, "google/"):]
+		outputDir := OutputDir(g)
+		for _, f := range files {
+			pkg, name := filepath.Split(f.GetName())
+			pkg = strings.TrimSuffix(pkg, "/")
+			var relPath string
+			if outputDir != "" {
+				if !filepath.IsLocal(filepath.FromSlash(f.GetName())) {
+					return nil, fmt.Errorf("%s: cannot write outside of output directory: %s", g.Name(), f.GetName())
+				}
+				relPath = filepath.Join(wd, outputDir, filepath.FromSlash(f.GetName()))
 			} else {
-				dir = pkg
+				dir, ok := sourcePkgDirs[pkg]
+				if !ok {
+					if strings.Contains(pkg, "google/") {
+						dir = pkg[strings.Index(pkg, "google/"):]
+					} else {
+						dir = pkg
+					}
+				}
+				relPath = path.Join(dir, name)
 			}
+			outputs = append(outputs, &GeneratedFile{
+				Name:          name,
+				Package:       pkg,
+				SourceRelPath: relPath,
+				Content:       f.GetContent(),
+			})
 		}
-		relPath := path.Join(dir, name)
-		outputs = append(outputs, &GeneratedFile{
-			Name:          name,
-			Package:       pkg,
-			SourceRelPath: relPath,
-			Content:       f.GetContent(),
-		})
 	}
 
 	return outputs, nil
@@ -185,9 +195,20 @@ func DefaultGenerators() []Generator {
 	}
 }
 
+// GenerateWorkspace generates code for the workspace in the current directory,
+// using the generators listed in its generation config, if any, or else the
+// default generators.
 func GenerateWorkspace() error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	generators, err := LoadWorkspaceGenerators(wd)
+	if err != nil {
+		return err
+	}
 	files, err := GenerateCode(
-		DefaultGenerators(),
+		generators,
 		[]string{"."},
 		WithGenerateStrategy(WorkspaceLocalDescriptorsOnly),
 	)
@@ -14,6 +14,9 @@ func (generator) Name() string {
 }
 
 func (generator) Generate(gen *protogen.Plugin) error {
+	gen.SupportedFeatures = gengo.SupportedFeatures
+	gen.SupportedEditionsMinimum = gengo.SupportedEditionsMinimum
+	gen.SupportedEditionsMaximum = gengo.SupportedEditionsMaximum
 	for _, f := range gen.Files {
 		if f.Generate {
 			gengo.GenerateFile(gen, f)
@@ -1,9 +1,12 @@
 package driver
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/kralicky/protocompile/linker"
@@ -35,6 +38,7 @@ const (
 
 type DriverOptions struct {
 	renameStrategy RenameStrategy
+	includePaths   []string
 }
 
 type DriverOption func(*DriverOptions)
@@ -51,6 +55,14 @@ func WithRenameStrategy(renameStrategy RenameStrategy) DriverOption {
 	}
 }
 
+// WithIncludePaths sets directories to search for imported files, in order
+// of precedence, similar to the --proto_path flags accepted by protoc.
+func WithIncludePaths(includePaths []string) DriverOption {
+	return func(o *DriverOptions) {
+		o.includePaths = includePaths
+	}
+}
+
 type Driver struct {
 	DriverOptions
 	workspace protocol.WorkspaceFolder
@@ -71,6 +83,7 @@ func NewDriver(workspaceFolder string, opts ...DriverOption) *Driver {
 type Results struct {
 	Error                          bool
 	Messages                       []string
+	Diagnostics                    []Diagnostic
 	AllDescriptors                 []protoreflect.FileDescriptor
 	AllDescriptorProtos            []*descriptorpb.FileDescriptorProto
 	WorkspaceLocalDescriptors      []protoreflect.FileDescriptor
@@ -79,6 +92,34 @@ type Results struct {
 	FilePathsByURI                 map[protocol.DocumentURI]string
 }
 
+// Diagnostic is an error, warning, or other message reported for a file
+// during compilation.
+type Diagnostic struct {
+	URI      protocol.DocumentURI        `json:"uri"`
+	Filename string                      `json:"filename"` // relative to the workspace, if possible
+	Range    protocol.Range              `json:"range"`
+	Severity protocol.DiagnosticSeverity `json:"-"`
+	// If this is a warning being treated as an error, Code is the category
+	// that can be named in a debug pragma to disable it. If it was reported by
+	// a lint rule, Code is the name of the rule.
+	Code               string                                  `json:"code,omitempty"`
+	Message            string                                  `json:"message"`
+	Tags               []protocol.DiagnosticTag                `json:"tags,omitempty"`
+	RelatedInformation []protocol.DiagnosticRelatedInformation `json:"relatedInformation,omitempty"`
+	CodeActions        []CodeAction                            `json:"codeActions,omitempty"`
+	// The full line of source containing the diagnostic, if it does not span
+	// multiple lines.
+	SourceLine string `json:"-"`
+}
+
+// CodeAction is a suggested fix for a diagnostic.
+type CodeAction struct {
+	Title       string               `json:"title"`
+	IsPreferred bool                 `json:"isPreferred,omitempty"`
+	URI         protocol.DocumentURI `json:"uri"`
+	Edits       []protocol.TextEdit  `json:"edits"`
+}
+
 var severityToColor = map[protocol.DiagnosticSeverity]string{
 	protocol.SeverityHint:        "\x1b[34m", // blue
 	protocol.SeverityInformation: "\x1b[32m", // green
@@ -94,101 +135,71 @@ var severityMsg = map[protocol.DiagnosticSeverity]string{
 }
 
 func (d *Driver) Compile(protos []string) (*Results, error) {
-	cache := lsp.NewCache(d.workspace)
+	cache := lsp.NewCache(d.workspace, lsp.WithSettings(lsp.Settings{
+		IncludePaths: d.includePaths,
+	}))
 	cache.LoadFiles(protos)
 
 	diagnostics, err := cache.XGetAllDiagnostics()
 	if err != nil {
 		return nil, err
 	}
+	pathMappings := cache.XGetURIPathMappings()
 	var results Results
 	for uri, diags := range diagnostics {
 		mapper, err := cache.XGetMapper(uri)
 		if err != nil {
 			return nil, err
 		}
+		filename := d.relativeFilename(uri)
 		for _, diag := range diags {
 			if diag.Severity == protocol.SeverityError {
 				results.Error = true
 			}
+			res := Diagnostic{
+				URI:                uri,
+				Filename:           filename,
+				Range:              diag.Range,
+				Severity:           diag.Severity,
+				Message:            diag.Message,
+				Tags:               diag.Tags,
+				RelatedInformation: diag.RelatedInformation,
+			}
+			if code, ok := diag.Code.(string); ok {
+				res.Code = code
+			}
+			if diag.Data != nil {
+				var data lsp.DiagnosticData
+				if err := json.Unmarshal(*diag.Data, &data); err == nil {
+					res.CodeActions = toCodeActions(uri, data.CodeActions, pathMappings.FileURIsByPath)
+				}
+			}
 			// obtain the whole line as context
 			if diag.Range.Start.Line == diag.Range.End.Line {
-				start := protocol.Position{
-					Line:      diag.Range.Start.Line,
-					Character: 0,
-				}
-				end := protocol.Position{
-					Line:      diag.Range.End.Line + 1,
-					Character: 0,
-				}
 				startPos, endPos, err := mapper.RangeOffsets(protocol.Range{
-					Start: start,
-					End:   end,
+					Start: protocol.Position{Line: diag.Range.Start.Line},
+					End:   protocol.Position{Line: diag.Range.End.Line + 1},
 				})
 				if err != nil {
 					return nil, err
 				}
-				showSourceContext := diag.Severity <= protocol.SeverityWarning
-				for _, tag := range diag.Tags {
-					if tag == protocol.Unnecessary {
-						showSourceContext = false
-						break
-					}
-				}
-				// show the source line with dimmed text before and after the error range,
-				// and highlight the error range
-				var uriFilename, relativePath string
-				if uri.IsFile() {
-					uriFilename = uri.Path()
-				} else {
-					uriFilename = strings.TrimPrefix(string(uri), "proto://")
-				}
-				if p, err := filepath.Rel(protocol.DocumentURI(d.workspace.URI).Path(), uriFilename); err == nil {
-					relativePath = p
-				} else {
-					relativePath = uriFilename
-				}
-				// dim path and line number
-				source := fmt.Sprintf("\x1b[2m%s:%d\x1b[0m", relativePath, diag.Range.Start.Line+1)
-
-				color := severityToColor[diag.Severity]
-				if !showSourceContext {
-					results.Messages = append(results.Messages,
-						fmt.Sprintf("%s: %s %s",
-							severityMsg[diag.Severity],
-							source,
-							diag.Message,
-						),
-					)
-					continue
-				}
-
-				fullLine := string(mapper.Content[startPos : endPos-1])
-				if diag.Range.Start.Character > uint32(len(fullLine)) ||
-					diag.Range.End.Character > uint32(len(fullLine)) ||
-					diag.Range.Start.Character > diag.Range.End.Character {
-					diag.Range.Start.Character = 0
-					diag.Range.End.Character = uint32(len(fullLine))
-				}
-				highlightedLine := fmt.Sprintf("%s%s%s",
-					"\x1b[2m"+fullLine[:diag.Range.Start.Character]+"\x1b[0m",
-					color+fullLine[diag.Range.Start.Character:diag.Range.End.Character]+"\x1b[0m",
-					"\x1b[2m"+fullLine[diag.Range.End.Character:]+"\x1b[0m",
-				)
-
-				results.Messages = append(results.Messages,
-					fmt.Sprintf("%s: %s %s\n\t%s", severityMsg[diag.Severity],
-						source,
-						diag.Message,
-						highlightedLine,
-					),
-				)
+				res.SourceLine = strings.TrimSuffix(string(mapper.Content[startPos:endPos]), "\n")
 			}
+			results.Diagnostics = append(results.Diagnostics, res)
 		}
 	}
+	slices.SortStableFunc(results.Diagnostics, func(a, b Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(a.Filename, b.Filename),
+			cmp.Compare(a.Range.Start.Line, b.Range.Start.Line),
+			cmp.Compare(a.Range.Start.Character, b.Range.Start.Character),
+		)
+	})
+	for _, diag := range results.Diagnostics {
+		results.Messages = append(results.Messages, formatMessage(diag))
+	}
 	if !results.Error {
 		unsorted := cache.XGetLinkerResults()
-		pathMappings := cache.XGetURIPathMappings()
 		results.FileURIsByPath = pathMappings.FileURIsByPath
 		results.FilePathsByURI = pathMappings.FilePathsByURI
 		results.AllDescriptors, results.WorkspaceLocalDescriptors = d.sortAndFilterResults(unsorted, results.FileURIsByPath)
@@ -210,6 +221,79 @@ func (d *Driver) Compile(protos []string) (*Results, error) {
 	return &results, nil
 }
 
+func (d *Driver) relativeFilename(uri protocol.DocumentURI) string {
+	var uriFilename string
+	if uri.IsFile() {
+		uriFilename = uri.Path()
+	} else {
+		uriFilename = strings.TrimPrefix(string(uri), "proto://")
+	}
+	if p, err := filepath.Rel(protocol.DocumentURI(d.workspace.URI).Path(), uriFilename); err == nil {
+		return p
+	}
+	return uriFilename
+}
+
+// toCodeActions returns the code actions which can be applied as plain text
+// edits. Actions which require running a command in the language server are
+// omitted.
+func toCodeActions(uri protocol.DocumentURI, actions []lsp.CodeAction, fileURIsByPath map[string]protocol.DocumentURI) []CodeAction {
+	var res []CodeAction
+	for _, action := range actions {
+		if len(action.Edits) == 0 {
+			continue
+		}
+		target := uri
+		if action.Path != "" {
+			u, ok := fileURIsByPath[action.Path]
+			if !ok {
+				continue
+			}
+			target = u
+		}
+		res = append(res, CodeAction{
+			Title:       action.Title,
+			IsPreferred: action.IsPreferred,
+			URI:         target,
+			Edits:       action.Edits,
+		})
+	}
+	return res
+}
+
+// formatMessage renders a diagnostic for display in a terminal. For errors
+// and warnings, the source line is shown with dimmed text before and after
+// the diagnostic range, and the range itself highlighted.
+func formatMessage(diag Diagnostic) string {
+	// dim path and line number
+	source := fmt.Sprintf("\x1b[2m%s:%d\x1b[0m", diag.Filename, diag.Range.Start.Line+1)
+
+	showSourceContext := diag.Severity <= protocol.SeverityWarning && diag.Range.Start.Line == diag.Range.End.Line
+	for _, tag := range diag.Tags {
+		if tag == protocol.Unnecessary {
+			showSourceContext = false
+			break
+		}
+	}
+	if !showSourceContext {
+		return fmt.Sprintf("%s: %s %s", severityMsg[diag.Severity], source, diag.Message)
+	}
+
+	fullLine := diag.SourceLine
+	start, end := diag.Range.Start.Character, diag.Range.End.Character
+	if start > uint32(len(fullLine)) || end > uint32(len(fullLine)) || start > end {
+		start = 0
+		end = uint32(len(fullLine))
+	}
+	color := severityToColor[diag.Severity]
+	highlightedLine := fmt.Sprintf("%s%s%s",
+		"\x1b[2m"+fullLine[:start]+"\x1b[0m",
+		color+fullLine[start:end]+"\x1b[0m",
+		"\x1b[2m"+fullLine[end:]+"\x1b[0m",
+	)
+	return fmt.Sprintf("%s: %s %s\n\t%s", severityMsg[diag.Severity], source, diag.Message, highlightedLine)
+}
+
 // Given a list of linker results, sorts them topologically and returns two lists:
 //  1. The sorted list of all descriptors
 //  2. A subset of the first list, containing only files that exist on disk
@@ -0,0 +1,298 @@
+package driver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kralicky/protols/pkg/version"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+)
+
+// ReportFormat is an output format for the diagnostics in Results.
+type ReportFormat string
+
+const (
+	// Human-readable messages, as in Results.Messages
+	ReportFormatText ReportFormat = "text"
+	// A JSON array of Diagnostic objects
+	ReportFormatJSON ReportFormat = "json"
+	// A SARIF 2.1.0 log, for use with code scanning tools
+	ReportFormatSARIF ReportFormat = "sarif"
+	// GitHub Actions workflow commands, which are shown as annotations
+	ReportFormatGitHub ReportFormat = "github"
+)
+
+var ReportFormats = []ReportFormat{
+	ReportFormatText,
+	ReportFormatJSON,
+	ReportFormatSARIF,
+	ReportFormatGitHub,
+}
+
+// Report writes the diagnostics in the given format.
+func (r *Results) Report(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportFormatText:
+		for _, msg := range r.Messages {
+			if _, err := fmt.Fprintln(w, msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ReportFormatJSON:
+		return writeJSONReport(w, r.Diagnostics)
+	case ReportFormatSARIF:
+		return writeSARIFReport(w, r.Diagnostics)
+	case ReportFormatGitHub:
+		return writeGitHubReport(w, r.Diagnostics)
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+func severityName(severity protocol.DiagnosticSeverity) string {
+	switch severity {
+	case protocol.SeverityError:
+		return "error"
+	case protocol.SeverityWarning:
+		return "warning"
+	case protocol.SeverityInformation:
+		return "info"
+	default:
+		return "hint"
+	}
+}
+
+type jsonDiagnostic struct {
+	Diagnostic
+	Severity string `json:"severity"`
+}
+
+func writeJSONReport(w io.Writer, diagnostics []Diagnostic) error {
+	items := make([]jsonDiagnostic, len(diagnostics))
+	for i, diag := range diagnostics {
+		items[i] = jsonDiagnostic{
+			Diagnostic: diag,
+			Severity:   severityName(diag.Severity),
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
+
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type (
+	sarifLog struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifToolComponent `json:"driver"`
+	}
+	sarifToolComponent struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri,omitempty"`
+		Rules          []sarifRule `json:"rules,omitempty"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID           string          `json:"ruleId,omitempty"`
+		Level            string          `json:"level"`
+		Message          sarifMessage    `json:"message"`
+		Locations        []sarifLocation `json:"locations"`
+		RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+		Fixes            []sarifFix      `json:"fixes,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+		Message          *sarifMessage         `json:"message,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           sarifRegion           `json:"region"`
+	}
+	sarifArtifactLocation struct {
+		URI       string `json:"uri"`
+		URIBaseID string `json:"uriBaseId,omitempty"`
+	}
+	// Columns are counted in UTF-16 code units by default, as in LSP.
+	sarifRegion struct {
+		StartLine   uint32 `json:"startLine"`
+		StartColumn uint32 `json:"startColumn"`
+		EndLine     uint32 `json:"endLine"`
+		EndColumn   uint32 `json:"endColumn"`
+	}
+	sarifFix struct {
+		Description     sarifMessage          `json:"description"`
+		ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+	}
+	sarifArtifactChange struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Replacements     []sarifReplacement    `json:"replacements"`
+	}
+	sarifReplacement struct {
+		DeletedRegion   sarifRegion   `json:"deletedRegion"`
+		InsertedContent *sarifContent `json:"insertedContent,omitempty"`
+	}
+	sarifContent struct {
+		Text string `json:"text"`
+	}
+)
+
+func writeSARIFReport(w io.Writer, diagnostics []Diagnostic) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifToolComponent{
+				Name:           "protols",
+				Version:        version.Version,
+				InformationURI: "https://github.com/kralicky/protols",
+			},
+		},
+		Results: []sarifResult{},
+	}
+	filenames := map[protocol.DocumentURI]string{}
+	for _, diag := range diagnostics {
+		filenames[diag.URI] = diag.Filename
+	}
+	artifactLocation := func(uri protocol.DocumentURI) sarifArtifactLocation {
+		if filename, ok := filenames[uri]; ok && uri.IsFile() && filepath.IsLocal(filename) {
+			return sarifArtifactLocation{URI: filepath.ToSlash(filename), URIBaseID: "%SRCROOT%"}
+		}
+		return sarifArtifactLocation{URI: string(uri)}
+	}
+	for _, diag := range diagnostics {
+		result := sarifResult{
+			RuleID:  diag.Code,
+			Level:   sarifLevel(diag.Severity),
+			Message: sarifMessage{Text: diag.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: artifactLocation(diag.URI),
+					Region:           toSARIFRegion(diag.Range),
+				},
+			}},
+		}
+		if diag.Code != "" && !slices.Contains(run.Tool.Driver.Rules, sarifRule{ID: diag.Code}) {
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: diag.Code})
+		}
+		for _, info := range diag.RelatedInformation {
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: artifactLocation(info.Location.URI),
+					Region:           toSARIFRegion(info.Location.Range),
+				},
+				Message: &sarifMessage{Text: info.Message},
+			})
+		}
+		for _, action := range diag.CodeActions {
+			change := sarifArtifactChange{
+				ArtifactLocation: artifactLocation(action.URI),
+			}
+			for _, edit := range action.Edits {
+				replacement := sarifReplacement{DeletedRegion: toSARIFRegion(edit.Range)}
+				if edit.NewText != "" {
+					replacement.InsertedContent = &sarifContent{Text: edit.NewText}
+				}
+				change.Replacements = append(change.Replacements, replacement)
+			}
+			result.Fixes = append(result.Fixes, sarifFix{
+				Description:     sarifMessage{Text: action.Title},
+				ArtifactChanges: []sarifArtifactChange{change},
+			})
+		}
+		run.Results = append(run.Results, result)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
+
+func sarifLevel(severity protocol.DiagnosticSeverity) string {
+	switch severity {
+	case protocol.SeverityError:
+		return "error"
+	case protocol.SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func toSARIFRegion(rng protocol.Range) sarifRegion {
+	return sarifRegion{
+		StartLine:   rng.Start.Line + 1,
+		StartColumn: rng.Start.Character + 1,
+		EndLine:     rng.End.Line + 1,
+		EndColumn:   rng.End.Character + 1,
+	}
+}
+
+// writeGitHubReport writes a workflow command for each diagnostic. See
+// https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+func writeGitHubReport(w io.Writer, diagnostics []Diagnostic) error {
+	for _, diag := range diagnostics {
+		var command string
+		switch diag.Severity {
+		case protocol.SeverityError:
+			command = "error"
+		case protocol.SeverityWarning:
+			command = "warning"
+		default:
+			command = "notice"
+		}
+		title := "protols"
+		if diag.Code != "" {
+			title += " (" + diag.Code + ")"
+		}
+		props := []string{
+			"file=" + escapeGitHubProperty(filepath.ToSlash(diag.Filename)),
+			fmt.Sprintf("line=%d", diag.Range.Start.Line+1),
+			fmt.Sprintf("endLine=%d", diag.Range.End.Line+1),
+		}
+		// columns are only allowed for annotations on a single line
+		if diag.Range.Start.Line == diag.Range.End.Line {
+			props = append(props,
+				fmt.Sprintf("col=%d", diag.Range.Start.Character+1),
+				fmt.Sprintf("endColumn=%d", diag.Range.End.Character+1),
+			)
+		}
+		props = append(props, "title="+escapeGitHubProperty(title))
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(props, ","), escapeGitHubData(diag.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	gitHubDataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	gitHubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+func escapeGitHubData(s string) string {
+	return gitHubDataEscaper.Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return gitHubPropertyEscaper.Replace(s)
+}
@@ -0,0 +1,156 @@
+package driver
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func compileTestFiles(t *testing.T, files map[string]string) *Results {
+	t.Helper()
+	dir := t.TempDir()
+	var filenames []string
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0o755))
+		require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+		filenames = append(filenames, filename)
+	}
+	results, err := NewDriver(dir).Compile(filenames)
+	require.NoError(t, err)
+	return results
+}
+
+func TestCompileDiagnostics(t *testing.T) {
+	results := compileTestFiles(t, map[string]string{
+		"b.proto": `syntax = "proto3";
+message B { Foo x = 1; }
+`,
+		"a.proto": `syntax = "proto3";
+import "b.proto";
+message A {}
+`,
+	})
+	require.True(t, results.Error)
+	require.Len(t, results.Diagnostics, 2)
+	require.Len(t, results.Messages, 2)
+
+	unused := results.Diagnostics[0]
+	require.Equal(t, "a.proto", unused.Filename)
+	require.Equal(t, protocol.SeverityWarning, unused.Severity)
+	require.Equal(t, `import "b.proto" not used`, unused.Message)
+	require.Equal(t, `import "b.proto";`, unused.SourceLine)
+	require.Len(t, unused.CodeActions, 1)
+	require.Equal(t, "Remove unused import", unused.CodeActions[0].Title)
+	require.Equal(t, unused.URI, unused.CodeActions[0].URI)
+
+	unknown := results.Diagnostics[1]
+	require.Equal(t, "b.proto", unknown.Filename)
+	require.Equal(t, protocol.SeverityError, unknown.Severity)
+	require.Equal(t, "field B.x: unknown type Foo", unknown.Message)
+	require.Equal(t, protocol.Range{
+		Start: protocol.Position{Line: 1, Character: 12},
+		End:   protocol.Position{Line: 1, Character: 15},
+	}, unknown.Range)
+}
+
+func TestReport(t *testing.T) {
+	results := &Results{
+		Error:    true,
+		Messages: []string{"first", "second"},
+		Diagnostics: []Diagnostic{
+			{
+				URI:      "file:///work/a,b.proto",
+				Filename: "a,b.proto",
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 1, Character: 2},
+					End:   protocol.Position{Line: 1, Character: 5},
+				},
+				Severity: protocol.SeverityWarning,
+				Code:     "unused",
+				Message:  "100% not used\nsecond line",
+				CodeActions: []CodeAction{{
+					Title: "Remove it",
+					URI:   "file:///work/a,b.proto",
+					Edits: []protocol.TextEdit{{
+						Range: protocol.Range{
+							Start: protocol.Position{Line: 1, Character: 0},
+							End:   protocol.Position{Line: 2, Character: 0},
+						},
+					}},
+				}},
+			},
+			{
+				URI:      "file:///work/c.proto",
+				Filename: "c.proto",
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 3, Character: 0},
+					End:   protocol.Position{Line: 5, Character: 1},
+				},
+				Severity: protocol.SeverityHint,
+				Message:  "hint",
+			},
+		},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, results.Report(&buf, ReportFormatText))
+		require.Equal(t, "first\nsecond\n", buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, results.Report(&buf, ReportFormatJSON))
+		var items []map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &items))
+		require.Len(t, items, 2)
+		require.Equal(t, "warning", items[0]["severity"])
+		require.Equal(t, "unused", items[0]["code"])
+		require.Equal(t, "a,b.proto", items[0]["filename"])
+		require.Len(t, items[0]["codeActions"], 1)
+		require.Equal(t, "hint", items[1]["severity"])
+		require.NotContains(t, items[1], "code")
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, results.Report(&buf, ReportFormatSARIF))
+		var log sarifLog
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+		require.Equal(t, "2.1.0", log.Version)
+		require.Len(t, log.Runs, 1)
+		require.Equal(t, []sarifRule{{ID: "unused"}}, log.Runs[0].Tool.Driver.Rules)
+		require.Len(t, log.Runs[0].Results, 2)
+
+		res := log.Runs[0].Results[0]
+		require.Equal(t, "unused", res.RuleID)
+		require.Equal(t, "warning", res.Level)
+		require.Equal(t, sarifArtifactLocation{URI: "a,b.proto", URIBaseID: "%SRCROOT%"}, res.Locations[0].PhysicalLocation.ArtifactLocation)
+		require.Equal(t, sarifRegion{StartLine: 2, StartColumn: 3, EndLine: 2, EndColumn: 6}, res.Locations[0].PhysicalLocation.Region)
+		require.Len(t, res.Fixes, 1)
+		require.Equal(t, "Remove it", res.Fixes[0].Description.Text)
+		require.Equal(t, sarifRegion{StartLine: 2, StartColumn: 1, EndLine: 3, EndColumn: 1}, res.Fixes[0].ArtifactChanges[0].Replacements[0].DeletedRegion)
+		require.Nil(t, res.Fixes[0].ArtifactChanges[0].Replacements[0].InsertedContent)
+
+		require.Equal(t, "note", log.Runs[0].Results[1].Level)
+	})
+
+	t.Run("github", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, results.Report(&buf, ReportFormatGitHub))
+		require.Equal(t, strings.Join([]string{
+			"::warning file=a%2Cb.proto,line=2,endLine=2,col=3,endColumn=6,title=protols (unused)::100%25 not used%0Asecond line",
+			"::notice file=c.proto,line=4,endLine=6,title=protols::hint",
+			"",
+		}, "\n"), buf.String())
+	})
+
+	require.Error(t, results.Report(&bytes.Buffer{}, "xml"))
+}
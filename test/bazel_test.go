@@ -0,0 +1,61 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBazelWorkspace(t *testing.T) {
+	const src = `
+-- MODULE.bazel --
+module(name = "example")
+-- proto/BUILD.bazel --
+load("@rules_proto//proto:defs.bzl", "proto_library")
+
+proto_library(
+    name = "foo_proto",
+    srcs = ["foo/v1/foo.proto"],
+    strip_import_prefix = "/proto",
+    deps = ["//third_party/bar:bar_proto"],
+)
+-- proto/foo/v1/foo.proto --
+syntax = "proto3";
+
+package foo.v1;
+
+import "example.com/bar.proto";
+
+message Foo {
+  bar.Bar bar = 1;
+}
+-- third_party/bar/BUILD --
+proto_library(
+    name = "bar_proto",
+    srcs = glob(["*.proto"]),
+    strip_import_prefix = "",
+    import_prefix = "example.com",
+)
+-- third_party/bar/bar.proto --
+syntax = "proto3";
+
+package bar;
+
+message Bar {}
+`
+	pullCapabilities := CapabilitiesJSON(`{"textDocument":{"diagnostic":{}}}`)
+
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("proto/foo/v1/foo.proto")
+		report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("proto/foo/v1/foo.proto")},
+		})
+		require.NoError(t, err)
+		require.Empty(t, report.Value.(protocol.RelatedFullDocumentDiagnosticReport).Items)
+
+		loc := env.GoToDefinition(env.RegexpSearch("proto/foo/v1/foo.proto", `bar\.(Bar)`))
+		require.Equal(t, env.Sandbox.Workdir.URI("third_party/bar/bar.proto"), loc.URI)
+	}, pullCapabilities)
+}
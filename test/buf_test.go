@@ -0,0 +1,74 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufWorkspace(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("BUF_CACHE_DIR", cacheDir)
+	validate := filepath.Join(cacheDir, "v3/modules/b5/buf.build/bufbuild/protovalidate/0123456789abcdef/files/buf/validate/validate.proto")
+	require.NoError(t, os.MkdirAll(filepath.Dir(validate), 0o755))
+	require.NoError(t, os.WriteFile(validate, []byte(`syntax = "proto3";
+
+package buf.validate;
+
+message Rules {}
+`), 0o644))
+
+	const src = `
+-- buf.yaml --
+version: v2
+modules:
+  - path: proto
+    excludes:
+      - proto/legacy
+deps:
+  - buf.build/bufbuild/protovalidate
+-- buf.lock --
+version: v2
+deps:
+  - name: buf.build/bufbuild/protovalidate
+    commit: 0123456789abcdef
+-- proto/foo/v1/foo.proto --
+syntax = "proto3";
+
+package foo.v1;
+
+import "bar/v1/bar.proto";
+import "buf/validate/validate.proto";
+
+message Foo {
+  bar.v1.Bar         bar   = 1;
+  buf.validate.Rules rules = 2;
+}
+-- proto/bar/v1/bar.proto --
+syntax = "proto3";
+
+package bar.v1;
+
+message Bar {}
+`
+	pullCapabilities := CapabilitiesJSON(`{"textDocument":{"diagnostic":{}}}`)
+
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("proto/foo/v1/foo.proto")
+		report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("proto/foo/v1/foo.proto")},
+		})
+		require.NoError(t, err)
+		require.Empty(t, report.Value.(protocol.RelatedFullDocumentDiagnosticReport).Items)
+
+		loc := env.GoToDefinition(env.RegexpSearch("proto/foo/v1/foo.proto", `bar\.v1\.(Bar)`))
+		require.Equal(t, env.Sandbox.Workdir.URI("proto/bar/v1/bar.proto"), loc.URI)
+
+		loc = env.GoToDefinition(env.RegexpSearch("proto/foo/v1/foo.proto", `buf\.validate\.(Rules)`))
+		require.Equal(t, protocol.URIFromPath(validate), loc.URI)
+	}, pullCapabilities)
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeLensGenerateConfig(t *testing.T) {
+	const src = `
+-- protols.yaml --
+generate:
+  - name: go
+  - plugin: [protoc-gen-validate, --verbose]
+    opt: lang=go
+    out: gen
+-- a.proto --
+syntax = "proto3";
+
+package a;
+
+message A {}
+`
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("a.proto")
+		var titles []string
+		for _, lens := range env.CodeLens("a.proto") {
+			titles = append(titles, lens.Command.Title)
+		}
+		require.Equal(t, []string{
+			"Generate File (go, protoc-gen-validate)",
+			"Generate Package (go, protoc-gen-validate)",
+			"Generate Workspace (go, protoc-gen-validate)",
+		}, titles)
+
+		env.WriteWorkspaceFile("protols.yaml", "generate: []\n")
+		require.Empty(t, env.CodeLens("a.proto"))
+
+		env.RemoveWorkspaceFile("protols.yaml")
+		titles = nil
+		for _, lens := range env.CodeLens("a.proto") {
+			titles = append(titles, lens.Command.Title)
+		}
+		require.Equal(t, []string{"Generate File", "Generate Package", "Generate Workspace"}, titles)
+	})
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullDiagnostics(t *testing.T) {
+	const src = `
+-- a.proto --
+package a;
+
+message A {}
+-- b.proto --
+syntax = "proto3";
+
+package b;
+
+message B {}
+`
+	pullCapabilities := CapabilitiesJSON(`{"workspace":{"diagnostics":{"refreshSupport":true}},"textDocument":{"diagnostic":{},"codeAction":{"resolveSupport":{"properties":["edit"]}}}}`)
+
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("a.proto")
+		uri := env.Sandbox.Workdir.URI("a.proto")
+
+		report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		})
+		require.NoError(t, err)
+		full := report.Value.(protocol.RelatedFullDocumentDiagnosticReport)
+		require.Equal(t, string(protocol.DiagnosticFull), full.Kind)
+		require.Len(t, full.Items, 1)
+		require.Equal(t, "no syntax specified; defaulting to proto2 syntax", full.Items[0].Message)
+		require.NotEmpty(t, full.ResultID)
+
+		report, err = env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument:     protocol.TextDocumentIdentifier{URI: uri},
+			PreviousResultID: full.ResultID,
+		})
+		require.NoError(t, err)
+		// the client-side unmarshaler can't distinguish between the report kinds
+		unchanged := report.Value.(protocol.RelatedFullDocumentDiagnosticReport)
+		require.Equal(t, string(protocol.DiagnosticUnchanged), unchanged.Kind)
+		require.Equal(t, full.ResultID, unchanged.ResultID)
+
+		workspaceReport, err := env.Editor.Server.DiagnosticWorkspace(env.Ctx, &protocol.WorkspaceDiagnosticParams{
+			PreviousResultIds: []protocol.PreviousResultID{
+				{URI: uri, Value: full.ResultID},
+			},
+		})
+		require.NoError(t, err)
+		var sawA bool
+		for _, item := range workspaceReport.Items {
+			item := item.Value.(protocol.WorkspaceFullDocumentDiagnosticReport)
+			if item.URI == uri {
+				require.Equal(t, string(protocol.DiagnosticUnchanged), item.Kind)
+				sawA = true
+			} else {
+				require.Equal(t, string(protocol.DiagnosticFull), item.Kind)
+				require.Empty(t, item.Items)
+			}
+		}
+		require.True(t, sawA, "expected an unchanged report for a.proto")
+	}, pullCapabilities)
+}
@@ -0,0 +1,80 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditionsCompletion(t *testing.T) {
+	const src = `
+-- editions.proto --
+edition = "2023";
+
+package test;
+
+message Foo {
+  string name = 1;
+}
+-- proto3.proto --
+syntax = "proto3";
+
+package test;
+
+message Bar {
+  string name = 1;
+}
+-- empty.proto --
+`
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("editions.proto")
+		env.OpenFile("proto3.proto")
+		env.OpenFile("empty.proto")
+
+		// completeLine inserts a new line containing text at the location matched
+		// by re, and returns the labels of the completion items at the end of the
+		// inserted text. The inserted line is removed afterwards.
+		completeLine := func(filename, re, text string) []string {
+			t.Helper()
+			loc := env.RegexpSearch(filename, re)
+			env.EditBuffer(filename, protocol.TextEdit{Range: loc.Range, NewText: text + "\n"})
+			defer env.EditBuffer(filename, protocol.TextEdit{
+				Range: protocol.Range{
+					Start: loc.Range.Start,
+					End:   protocol.Position{Line: loc.Range.Start.Line + 1},
+				},
+			})
+			loc.Range.Start.Character += uint32(len(text))
+			loc.Range.End = loc.Range.Start
+			var labels []string
+			for _, item := range env.Completion(loc).Items {
+				labels = append(labels, item.Label)
+			}
+			return labels
+		}
+
+		require.Equal(t, []string{
+			"field_presence",
+			"enum_type",
+			"repeated_field_encoding",
+			"utf8_validation",
+			"message_encoding",
+			"json_format",
+		}, completeLine("editions.proto", `\n()message Foo`, "option features."))
+
+		require.Equal(t, []string{
+			"json_format",
+		}, completeLine("editions.proto", `\n()  string name`, "  option features."))
+
+		require.Contains(t, completeLine("editions.proto", `\n()message Foo`, "option "), "features")
+		require.NotContains(t, completeLine("proto3.proto", `\n()message Bar`, "option "), "features")
+
+		require.NotContains(t, completeLine("editions.proto", `\n()  string name`, "  "), "optional")
+		require.Contains(t, completeLine("proto3.proto", `\n()  string name`, "  "), "optional")
+
+		require.Contains(t, completeLine("empty.proto", `()`, "ed"), "edition: 2023")
+		require.NotContains(t, completeLine("editions.proto", `\n()message Foo`, "ed"), "edition: 2023")
+	})
+}
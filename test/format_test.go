@@ -1 +1,236 @@
 package test
+
+import (
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeFormatting(t *testing.T) {
+	const src = `
+-- test.proto --
+syntax = "proto3";
+
+package   test;
+
+// Hand-tuned
+message   Legacy {
+  string   a=1;
+  int32 bb    = 2;
+}
+
+// Foo message
+message   Foo {   // Foo
+  string   a=1;   // a
+  int32 bb    = 2;
+  message   Bar {
+    int32   x=1;
+  }
+}  // end Foo
+
+enum   Baz { BAZ_UNSPECIFIED=0; }
+`
+	testCases := []struct {
+		name   string
+		ranges []string
+		want   string
+	}{
+		{
+			name:   "single message",
+			ranges: []string{`string   a=1;   // ()a`},
+			want: `
+syntax = "proto3";
+
+package   test;
+
+// Hand-tuned
+message   Legacy {
+  string   a=1;
+  int32 bb    = 2;
+}
+
+// Foo message
+message Foo {// Foo
+  string a  = 1; // a
+  int32  bb = 2;
+  message Bar {
+    int32 x = 1;
+  }
+} // end Foo
+
+enum   Baz { BAZ_UNSPECIFIED=0; }
+`,
+		},
+		{
+			name:   "nested message",
+			ranges: []string{`int32   ()x=1`},
+			want: `
+syntax = "proto3";
+
+package   test;
+
+// Hand-tuned
+message   Legacy {
+  string   a=1;
+  int32 bb    = 2;
+}
+
+// Foo message
+message   Foo {   // Foo
+  string   a=1;   // a
+  int32 bb    = 2;
+  message Bar {
+    int32 x = 1;
+  }
+}  // end Foo
+
+enum   Baz { BAZ_UNSPECIFIED=0; }
+`,
+		},
+		{
+			name:   "multiple ranges",
+			ranges: []string{`int32   ()x=1`, `enum   ()Baz`},
+			want: `
+syntax = "proto3";
+
+package   test;
+
+// Hand-tuned
+message   Legacy {
+  string   a=1;
+  int32 bb    = 2;
+}
+
+// Foo message
+message   Foo {   // Foo
+  string   a=1;   // a
+  int32 bb    = 2;
+  message Bar {
+    int32 x = 1;
+  }
+}  // end Foo
+
+enum Baz {
+  BAZ_UNSPECIFIED = 0;
+}
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Run(t, src, func(t *testing.T, env *integration.Env) {
+				env.OpenFile("test.proto")
+				var ranges []protocol.Range
+				for _, re := range tc.ranges {
+					ranges = append(ranges, env.RegexpSearch("test.proto", re).Range)
+				}
+				edits, err := env.Editor.Server.RangesFormatting(env.Ctx, &protocol.DocumentRangesFormattingParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("test.proto")},
+					Ranges:       ranges,
+				})
+				require.NoError(t, err)
+				env.EditBuffer("test.proto", edits...)
+				require.Equal(t, tc.want[1:], env.BufferText("test.proto"))
+			})
+		})
+	}
+}
+
+func TestOnTypeFormatting(t *testing.T) {
+	const src = `
+-- test.proto --
+syntax = "proto3";
+
+import   "google/protobuf/empty.proto";
+
+message Foo {
+  string a = 1;
+  int32 bb    = 2;
+}
+
+service   Svc {
+  rpc   Bar(google.protobuf.Empty) returns (google.protobuf.Empty);
+}
+`
+	testCases := []struct {
+		name string
+		at   string
+		ch   string
+		want string
+	}{
+		{
+			name: "field semicolon",
+			at:   `int32 bb    = 2;()`,
+			ch:   ";",
+			want: `
+syntax = "proto3";
+
+import   "google/protobuf/empty.proto";
+
+message Foo {
+  string a  = 1;
+  int32  bb = 2;
+}
+
+service   Svc {
+  rpc   Bar(google.protobuf.Empty) returns (google.protobuf.Empty);
+}
+`,
+		},
+		{
+			name: "closing brace",
+			at:   `Empty\);\n}()`,
+			ch:   "}",
+			want: `
+syntax = "proto3";
+
+import   "google/protobuf/empty.proto";
+
+message Foo {
+  string a = 1;
+  int32 bb    = 2;
+}
+
+service Svc {
+  rpc Bar(google.protobuf.Empty) returns (google.protobuf.Empty);
+}
+`,
+		},
+		{
+			name: "top-level statement",
+			at:   `empty.proto";()`,
+			ch:   ";",
+			want: `
+syntax = "proto3";
+
+import "google/protobuf/empty.proto";
+
+message Foo {
+  string a = 1;
+  int32 bb    = 2;
+}
+
+service   Svc {
+  rpc   Bar(google.protobuf.Empty) returns (google.protobuf.Empty);
+}
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			Run(t, src, func(t *testing.T, env *integration.Env) {
+				env.OpenFile("test.proto")
+				edits, err := env.Editor.Server.OnTypeFormatting(env.Ctx, &protocol.DocumentOnTypeFormattingParams{
+					TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("test.proto")},
+					Position:     env.RegexpSearch("test.proto", tc.at).Range.Start,
+					Ch:           tc.ch,
+				})
+				require.NoError(t, err)
+				env.EditBuffer("test.proto", edits...)
+				require.Equal(t, tc.want[1:], env.BufferText("test.proto"))
+			})
+		})
+	}
+}
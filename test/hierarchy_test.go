@@ -0,0 +1,240 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeHierarchy(t *testing.T) {
+	const src = `
+-- a.proto --
+syntax = "proto2";
+
+package a;
+
+message Base {
+  extensions 100 to 200;
+}
+
+message Info {
+  optional string name = 1;
+}
+
+message Leaf {}
+-- b.proto --
+syntax = "proto2";
+
+package b;
+
+import "a.proto";
+
+message Container {
+  optional a.Info info = 1;
+  map<string, a.Info> infos = 2;
+  optional a.Leaf leaf = 3;
+}
+
+extend a.Base {
+  optional a.Info base_info = 100;
+  optional int32 base_count = 101;
+}
+`
+	names := func(items []protocol.TypeHierarchyItem) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Detail)
+		}
+		return names
+	}
+
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("a.proto")
+		env.OpenFile("b.proto")
+
+		prepare := func(file, re string) protocol.TypeHierarchyItem {
+			items, err := env.Editor.Server.PrepareTypeHierarchy(env.Ctx, &protocol.TypeHierarchyPrepareParams{
+				TextDocumentPositionParams: protocol.LocationTextDocumentPositionParams(env.RegexpSearch(file, re)),
+			})
+			require.NoError(t, err)
+			require.Len(t, items, 1)
+			return items[0]
+		}
+
+		info := prepare("a.proto", `message (Info)`)
+		require.Equal(t, "Info", info.Name)
+		require.Equal(t, protocol.Class, info.Kind)
+
+		supertypes, err := env.Editor.Server.Supertypes(env.Ctx, &protocol.TypeHierarchySupertypesParams{Item: info})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.Base", "b.Container"}, names(supertypes))
+
+		// references to the type resolve to the same item
+		require.Equal(t, info, prepare("b.proto", `optional a\.(Info) info`))
+
+		container := prepare("b.proto", `message (Container)`)
+		subtypes, err := env.Editor.Server.Subtypes(env.Ctx, &protocol.TypeHierarchySubtypesParams{Item: container})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.Info", "a.Leaf"}, names(subtypes))
+
+		base := prepare("a.proto", `message (Base)`)
+		subtypes, err = env.Editor.Server.Subtypes(env.Ctx, &protocol.TypeHierarchySubtypesParams{Item: base})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.Info", "b.base_count"}, names(subtypes))
+
+		count := subtypes[1]
+		require.Equal(t, protocol.Field, count.Kind)
+		supertypes, err = env.Editor.Server.Supertypes(env.Ctx, &protocol.TypeHierarchySupertypesParams{Item: count})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a.Base"}, names(supertypes))
+	})
+}
+
+func TestCallHierarchy(t *testing.T) {
+	const src = `
+-- go.mod --
+module example.com/calls
+
+go 1.23
+-- foo/foo.proto --
+syntax = "proto3";
+
+package foo;
+
+option go_package = "example.com/calls/foo";
+
+message GetRequest {}
+message GetResponse {}
+
+service FooService {
+  rpc Get(GetRequest) returns (GetResponse);
+  rpc Unused(GetRequest) returns (GetResponse);
+}
+-- foo/foo_grpc.pb.go --
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// source: foo/foo.proto
+
+package foo
+
+import (
+	context "context"
+
+	grpc "google.golang.org/grpc"
+)
+
+type FooServiceClient interface {
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+	Unused(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
+}
+
+type FooServiceServer interface {
+	Get(context.Context, *GetRequest) (*GetResponse, error)
+	Unused(context.Context, *GetRequest) (*GetResponse, error)
+}
+
+func _FooService_Get_Handler(srv interface{}, ctx context.Context) (interface{}, error) {
+	return srv.(FooServiceServer).Get(ctx, new(GetRequest))
+}
+-- client/client.go --
+package client
+
+import (
+	"context"
+
+	"example.com/calls/foo"
+)
+
+func GetFoo(ctx context.Context, c foo.FooServiceClient) {
+	c.Get(ctx, &foo.GetRequest{})
+	c.Get(ctx, &foo.GetRequest{})
+}
+
+type runner struct {
+	client foo.FooServiceClient
+}
+
+func (r *runner) Run(ctx context.Context) {
+	r.client.Get(ctx, &foo.GetRequest{})
+}
+-- client/cache.go --
+package client
+
+import (
+	"context"
+
+	"example.com/calls/foo"
+)
+
+type cache struct{}
+
+func (cache) Get(ctx context.Context, in *foo.GetRequest) {}
+
+func Cached(ctx context.Context) {
+	cache{}.Get(ctx, &foo.GetRequest{})
+}
+-- client/client_test.go --
+package client
+
+import (
+	"context"
+	"testing"
+
+	"example.com/calls/foo"
+)
+
+func TestGet(t *testing.T) {
+	var c foo.FooServiceClient
+	c.Get(context.Background(), &foo.GetRequest{})
+}
+-- unrelated/unrelated.go --
+package unrelated
+
+type getter interface {
+	Get()
+}
+
+func get(g getter) {
+	g.Get()
+}
+`
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("foo/foo.proto")
+
+		prepare := func(re string) protocol.CallHierarchyItem {
+			items, err := env.Editor.Server.PrepareCallHierarchy(env.Ctx, &protocol.CallHierarchyPrepareParams{
+				TextDocumentPositionParams: protocol.LocationTextDocumentPositionParams(env.RegexpSearch("foo/foo.proto", re)),
+			})
+			require.NoError(t, err)
+			require.Len(t, items, 1)
+			return items[0]
+		}
+
+		get := prepare(`rpc (Get)`)
+		require.Equal(t, "Get", get.Name)
+		require.Equal(t, "foo.FooService", get.Detail)
+
+		calls, err := env.Editor.Server.IncomingCalls(env.Ctx, &protocol.CallHierarchyIncomingCallsParams{Item: get})
+		require.NoError(t, err)
+		// calls to methods with the same name on other types are not included
+		require.Len(t, calls, 3)
+
+		clientGo := env.Sandbox.Workdir.URI("client/client.go")
+		require.Equal(t, "GetFoo", calls[0].From.Name)
+		require.Equal(t, protocol.Function, calls[0].From.Kind)
+		require.Equal(t, clientGo, calls[0].From.URI)
+		require.Len(t, calls[0].FromRanges, 2)
+		require.Equal(t, "Run", calls[1].From.Name)
+		require.Equal(t, protocol.Method, calls[1].From.Kind)
+		require.Equal(t, clientGo, calls[1].From.URI)
+		require.Equal(t, []protocol.Range{env.RegexpSearch("client/client.go", `r\.client\.(Get)`).Range}, calls[1].FromRanges)
+		require.Equal(t, "TestGet", calls[2].From.Name)
+		require.Equal(t, env.Sandbox.Workdir.URI("client/client_test.go"), calls[2].From.URI)
+
+		unused := prepare(`rpc (Unused)`)
+		calls, err = env.Editor.Server.IncomingCalls(env.Ctx, &protocol.CallHierarchyIncomingCallsParams{Item: unused})
+		require.NoError(t, err)
+		require.Empty(t, calls)
+	})
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncludePaths(t *testing.T) {
+	const src = `
+-- main.proto --
+syntax = "proto3";
+
+package main;
+
+import "foo/foo.proto";
+import "bar/bar.proto";
+
+message Main {
+  foo.Foo foo = 1;
+  bar.Bar bar = 2;
+}
+-- a/foo/foo.proto --
+syntax = "proto3";
+
+package foo;
+
+message Foo {}
+-- b/foo/foo.proto --
+syntax = "proto3";
+
+package foo;
+
+message Foo {}
+-- b/bar/bar.proto --
+syntax = "proto3";
+
+package bar;
+
+message Bar {}
+`
+	pullCapabilities := CapabilitiesJSON(`{"textDocument":{"diagnostic":{}}}`)
+
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("main.proto")
+		report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("main.proto")},
+		})
+		require.NoError(t, err)
+		items := report.Value.(protocol.RelatedFullDocumentDiagnosticReport).Items
+		require.Len(t, items, 1)
+		require.Equal(t, protocol.SeverityWarning, items[0].Severity)
+		require.Equal(t, `import "foo/foo.proto" resolves to a/foo/foo.proto, which shadows b/foo/foo.proto`, items[0].Message)
+		require.Equal(t, env.RegexpSearch("main.proto", `import ("foo/foo.proto")`).Range, items[0].Range)
+
+		loc := env.GoToDefinition(env.RegexpSearch("main.proto", `foo\.(Foo)`))
+		require.Equal(t, env.Sandbox.Workdir.URI("a/foo/foo.proto"), loc.URI)
+
+		loc = env.GoToDefinition(env.RegexpSearch("main.proto", `bar\.(Bar)`))
+		require.Equal(t, env.Sandbox.Workdir.URI("b/bar/bar.proto"), loc.URI)
+	}, pullCapabilities, Settings(map[string]any{
+		"includePaths": []string{"a", "b"},
+	}))
+}
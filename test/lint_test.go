@@ -0,0 +1,184 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kralicky/protols/pkg/lsp"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
+	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintRules(t *testing.T) {
+	const src = `
+-- foo/v1/a.proto --
+//protols:debug lint=all nolint=message-case
+syntax = "proto3";
+
+package foo.v2;
+
+message my_message {
+  string fooBar = 1;
+}
+
+enum Kind {
+  UNKNOWN = 0;
+  kindOne = 1;
+}
+
+service Svc {}
+
+// Documented is documented.
+service Documented {}
+-- b.proto --
+syntax = "proto3";
+
+package b;
+
+message my_message {}
+`
+	pullCapabilities := CapabilitiesJSON(`{"textDocument":{"diagnostic":{},"codeAction":{"resolveSupport":{"properties":["edit"]}}}}`)
+
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("foo/v1/a.proto")
+		env.OpenFile("b.proto")
+
+		diagnostics := func(path string) []protocol.Diagnostic {
+			report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI(path)},
+			})
+			require.NoError(t, err)
+			return report.Value.(protocol.RelatedFullDocumentDiagnosticReport).Items
+		}
+
+		// lint rules are not enabled without the pragma
+		require.Empty(t, diagnostics("b.proto"))
+
+		byCode := map[string][]protocol.Diagnostic{}
+		for _, d := range diagnostics("foo/v1/a.proto") {
+			code, _ := d.Code.(string)
+			byCode[code] = append(byCode[code], d)
+		}
+		require.NotContains(t, byCode, "message-case")
+		require.Len(t, byCode["field-case"], 1)
+		require.Equal(t, `field name "fooBar" should be lower_snake_case, such as "foo_bar"`, byCode["field-case"][0].Message)
+		require.Len(t, byCode["enum-value-case"], 1)
+		require.Equal(t, `enum value name "kindOne" should be UPPER_SNAKE_CASE, such as "KIND_ONE"`, byCode["enum-value-case"][0].Message)
+		require.Len(t, byCode["enum-zero-unspecified"], 1)
+		require.Equal(t, `zero value of enum Kind should be named "KIND_UNSPECIFIED"`, byCode["enum-zero-unspecified"][0].Message)
+		require.Len(t, byCode["package-directory"], 1)
+		require.Equal(t, `package "foo.v2" should be in a directory named "foo/v2", but the file is in "foo/v1"`, byCode["package-directory"][0].Message)
+		require.Len(t, byCode["service-comments"], 1)
+		require.Equal(t, "service Svc should have a comment", byCode["service-comments"][0].Message)
+		for _, ds := range byCode {
+			require.Equal(t, protocol.SeverityWarning, ds[0].Severity)
+		}
+
+		quickFix := func(d protocol.Diagnostic) lsp.CodeAction {
+			var data lsp.DiagnosticData
+			require.NoError(t, json.Unmarshal(*d.Data, &data))
+			require.Len(t, data.CodeActions, 1)
+			return data.CodeActions[0]
+		}
+		fix := quickFix(byCode["field-case"][0])
+		require.Equal(t, "Rename fooBar to foo_bar", fix.Title)
+		require.Equal(t, protocol.QuickFix, fix.Kind)
+		require.Len(t, fix.Edits, 1)
+		require.Equal(t, "foo_bar", fix.Edits[0].NewText)
+		require.Equal(t, byCode["field-case"][0].Range, fix.Edits[0].Range)
+
+		fix = quickFix(byCode["service-comments"][0])
+		require.Equal(t, "// Svc\n", fix.Edits[0].NewText)
+		require.Equal(t, protocol.Position{Line: 14, Character: 0}, fix.Edits[0].Range.Start)
+
+		actions, err := env.Editor.Server.CodeAction(env.Ctx, &protocol.CodeActionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("foo/v1/a.proto")},
+			Range:        byCode["enum-zero-unspecified"][0].Range,
+			Context: protocol.CodeActionContext{
+				Diagnostics: byCode["enum-zero-unspecified"],
+				Only:        []protocol.CodeActionKind{protocol.QuickFix},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, actions, 1)
+		require.Equal(t, "Rename UNKNOWN to KIND_UNSPECIFIED", actions[0].Title)
+	}, pullCapabilities)
+}
+
+func TestLintRulesEnumWithoutZeroValue(t *testing.T) {
+	const src = `
+-- a.proto --
+//protols:debug lint=enum-zero-unspecified
+syntax = "proto2";
+
+package a;
+
+message M {
+  enum HTTPStatus {
+    OK = 200;
+  }
+}
+`
+	pullCapabilities := CapabilitiesJSON(`{"textDocument":{"diagnostic":{},"codeAction":{"resolveSupport":{"properties":["edit"]}}}}`)
+
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("a.proto")
+		report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("a.proto")},
+		})
+		require.NoError(t, err)
+		items := report.Value.(protocol.RelatedFullDocumentDiagnosticReport).Items
+		require.Len(t, items, 1)
+		require.Equal(t, `enum HTTPStatus should have a zero value named "HTTP_STATUS_UNSPECIFIED"`, items[0].Message)
+
+		var data lsp.DiagnosticData
+		require.NoError(t, json.Unmarshal(*items[0].Data, &data))
+		require.Len(t, data.CodeActions, 1)
+		require.Equal(t, "HTTP_STATUS_UNSPECIFIED = 0;\n    ", data.CodeActions[0].Edits[0].NewText)
+		require.Equal(t, protocol.Position{Line: 7, Character: 4}, data.CodeActions[0].Edits[0].Range.Start)
+	}, pullCapabilities)
+}
+
+func TestLintRulesNoRenameFixForImportedFiles(t *testing.T) {
+	const src = `
+-- a.proto --
+//protols:debug lint=enum-value-case
+syntax = "proto2";
+
+package a;
+
+enum Kind {
+  kindOne = 1;
+}
+-- b.proto --
+syntax = "proto2";
+
+package b;
+
+import "a.proto";
+
+message B {
+  optional a.Kind kind = 1 [default = kindOne];
+}
+`
+	pullCapabilities := CapabilitiesJSON(`{"textDocument":{"diagnostic":{},"codeAction":{"resolveSupport":{"properties":["edit"]}}}}`)
+
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("a.proto")
+		env.OpenFile("b.proto")
+		report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("a.proto")},
+		})
+		require.NoError(t, err)
+		items := report.Value.(protocol.RelatedFullDocumentDiagnosticReport).Items
+		require.Len(t, items, 1)
+		require.Equal(t, "enum-value-case", items[0].Code)
+
+		// kindOne is referenced from b.proto, so renaming it within a.proto
+		// would break b.proto
+		var data lsp.DiagnosticData
+		require.NoError(t, json.Unmarshal(*items[0].Data, &data))
+		require.Empty(t, data.CodeActions)
+	}, pullCapabilities)
+}
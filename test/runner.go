@@ -51,8 +51,8 @@ func Main(m *testing.M) {
 	code = m.Run()
 }
 
-func Run(t *testing.T, files string, f TestFunc) {
-	runner.Run(t, files, f)
+func Run(t *testing.T, files string, f TestFunc, opts ...RunOption) {
+	runner.Run(t, files, f, opts...)
 }
 
 type Runner struct {
@@ -63,12 +63,28 @@ type Runner struct {
 
 type (
 	TestFunc  func(t *testing.T, env *integration.Env)
+	RunOption func(*runConfig)
 	runConfig struct {
 		editor  fake.EditorConfig
 		sandbox fake.SandboxConfig
 	}
 )
 
+// CapabilitiesJSON overrides the client capabilities sent by the editor.
+func CapabilitiesJSON(capabilities string) RunOption {
+	return func(c *runConfig) {
+		c.editor.CapabilitiesJSON = []byte(capabilities)
+	}
+}
+
+// Settings sets the user-provided configuration sent by the editor in the
+// initialization options.
+func Settings(settings map[string]any) RunOption {
+	return func(c *runConfig) {
+		c.editor.Settings = settings
+	}
+}
+
 func defaultConfig() runConfig {
 	return runConfig{
 		editor: fake.EditorConfig{
@@ -84,12 +100,15 @@ func defaultConfig() runConfig {
 // Run executes the test function in the default configured gopls execution
 // modes. For each a test run, a new workspace is created containing the
 // un-txtared files specified by filedata.
-func (r *Runner) Run(t *testing.T, files string, test TestFunc, opts ...integration.RunOption) {
+func (r *Runner) Run(t *testing.T, files string, test TestFunc, opts ...RunOption) {
 	// TODO(rfindley): this function has gotten overly complicated, and warrants
 	// refactoring.
 	t.Helper()
 
 	config := defaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
 	t.Run("in-process", func(t *testing.T) {
 		// TODO: shutdown is broken in the upstream code; if it gets fixed, this
 		// should implement and verify correct shutdown behavior.
@@ -1,11 +1,16 @@
 package test
 
 import (
+	"bytes"
+	"encoding/json"
+	"slices"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/kralicky/tools-lite/gopls/pkg/protocol"
 	"github.com/kralicky/tools-lite/gopls/pkg/test/integration"
 	"github.com/kralicky/tools-lite/gopls/pkg/test/integration/fake"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSemanticTokens(t *testing.T) {
@@ -332,3 +337,72 @@ extend .google. /* comment */ protobuf.ExtensionRangeOptions {
 		}
 	})
 }
+
+func TestSemanticTokensDelta(t *testing.T) {
+	const src = `
+-- test.proto --
+syntax = "proto3";
+
+package test;
+
+message Foo {
+  string name = 1;
+}
+
+message Bar {
+  Foo foo = 1;
+}
+`
+	Run(t, src, func(t *testing.T, env *integration.Env) {
+		env.OpenFile("test.proto")
+		doc := protocol.TextDocumentIdentifier{URI: env.Sandbox.Workdir.URI("test.proto")}
+
+		full, err := env.Editor.Server.SemanticTokensFull(env.Ctx, &protocol.SemanticTokensParams{TextDocument: doc})
+		require.NoError(t, err)
+		require.NotEmpty(t, full.ResultID)
+
+		env.EditBuffer("test.proto", protocol.TextEdit{
+			Range:   env.RegexpSearch("test.proto", `\n}\n\nmessage Bar`).Range,
+			NewText: "\n  int32 id = 2;\n}\n\nmessage Bar",
+		})
+
+		result, err := env.Editor.Server.SemanticTokensFullDelta(env.Ctx, &protocol.SemanticTokensDeltaParams{
+			TextDocument:     doc,
+			PreviousResultID: full.ResultID,
+		})
+		require.NoError(t, err)
+		var delta protocol.SemanticTokensDelta
+		decodeResult(t, result, &delta)
+		require.NotEqual(t, full.ResultID, delta.ResultID)
+		require.Len(t, delta.Edits, 1)
+
+		data := slices.Clone(full.Data)
+		for _, edit := range delta.Edits {
+			data = slices.Replace(data, int(edit.Start), int(edit.Start+edit.DeleteCount), edit.Data...)
+		}
+		want, err := env.Editor.Server.SemanticTokensFull(env.Ctx, &protocol.SemanticTokensParams{TextDocument: doc})
+		require.NoError(t, err)
+		require.Equal(t, want.Data, data)
+
+		// an unknown result ID falls back to the full result
+		result, err = env.Editor.Server.SemanticTokensFullDelta(env.Ctx, &protocol.SemanticTokensDeltaParams{
+			TextDocument:     doc,
+			PreviousResultID: delta.ResultID,
+		})
+		require.NoError(t, err)
+		var tokens protocol.SemanticTokens
+		decodeResult(t, result, &tokens)
+		require.Equal(t, want.Data, tokens.Data)
+	})
+}
+
+// decodeResult decodes a result of type interface{}, which is received from
+// the server as a generic JSON object.
+func decodeResult(t *testing.T, result any, out any) {
+	t.Helper()
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	require.NoError(t, dec.Decode(out))
+}